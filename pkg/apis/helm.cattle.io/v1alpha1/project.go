@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,9 +30,86 @@ type ProjectHelmChartSpec struct {
 	// will be created in dedicated project namespaces with a pre-defined project namespace selector
 	ProjectNamespaceSelector *metav1.LabelSelector `json:"projectNamespaceSelector"`
 
+	// ReleaseNamespace is an existing namespace targeted by this ProjectHelmChart that the underlying Helm chart should be deployed into
+	// If not provided, the operator will deploy the Helm chart into the Project Release Namespace (or Project Registration Namespace)
+	// The operator will never create, label, or orphan this namespace
+	ReleaseNamespace string `json:"releaseNamespace,omitempty"`
+
 	// Values is a generic map (e.g. generic yaml) representing the values.yaml used to configure the underlying Helm chart that
 	// will be deployed for this
 	Values GenericMap `json:"values"`
+
+	// HelmOptions configures how the underlying Helm release is deployed
+	// Only options that have been allowed by the operator (and are supported by its deployment backend) can be provided
+	HelmOptions *HelmOptions `json:"helmOptions,omitempty"`
+
+	// RollbackOnFailure configures the operator to automatically re-apply the last values that were successfully deployed
+	// if the values provided in spec.values fail to deploy. The values that failed will not be retried until spec.values is modified.
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+
+	// ReleaseNamespaceResources overrides the ResourceQuota and LimitRange created by the operator in the Project Release Namespace
+	// Only values within the bounds configured by the operator can be provided
+	ReleaseNamespaceResources *ReleaseNamespaceResources `json:"releaseNamespaceResources,omitempty"`
+
+	// ReleaseNamespaceMetadata configures additional labels and annotations added to the Project Release Namespace created by the operator
+	// Labels and annotations managed by the operator cannot be overridden
+	ReleaseNamespaceMetadata *ReleaseNamespaceMetadata `json:"releaseNamespaceMetadata,omitempty"`
+}
+
+// ReleaseNamespaceMetadata configures additional metadata added to the Project Release Namespace of a ProjectHelmChart
+type ReleaseNamespaceMetadata struct {
+	// Labels are additional labels added to the Project Release Namespace
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are additional annotations added to the Project Release Namespace
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ReleaseNamespaceResources configures the resource governance applied to the Project Release Namespace of a ProjectHelmChart
+type ReleaseNamespaceResources struct {
+	// ResourceQuota overrides the hard limits of the ResourceQuota created in the Project Release Namespace
+	ResourceQuota corev1.ResourceList `json:"resourceQuota,omitempty"`
+
+	// LimitRange overrides the limits applied to each container in the Project Release Namespace
+	LimitRange *ContainerLimitRange `json:"limitRange,omitempty"`
+}
+
+// ContainerLimitRange configures the limits applied to each container by the LimitRange created in the Project Release Namespace
+type ContainerLimitRange struct {
+	// Default is the default resource limits of a container if none are specified
+	Default corev1.ResourceList `json:"default,omitempty"`
+
+	// DefaultRequest is the default resource requests of a container if none are specified
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+
+	// Max is the maximum resource limits of a container
+	Max corev1.ResourceList `json:"max,omitempty"`
+}
+
+// HelmOptions are options that configure how the underlying Helm release of a ProjectHelmChart is deployed
+type HelmOptions struct {
+	// Timeout is the time to wait for any individual Kubernetes operation (like Jobs for hooks) during a Helm operation
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Atomic rolls back changes made in case of a failed Helm operation, which also implies Wait
+	Atomic bool `json:"atomic,omitempty"`
+
+	// Wait waits until all resources deployed by the release are in a ready state before marking the Helm operation as successful
+	Wait bool `json:"wait,omitempty"`
+
+	// FailurePolicy determines how a failed Helm operation is handled. One of:
+	// - abort: leaves the failed release in place until it is manually resolved
+	// - reinstall: uninstalls and reinstalls the release
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+
+	// BackoffLimit is the number of times the Job that performs the Helm operation is retried before it is marked as failed
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// NodeSelector is added to the nodeSelector of the Job that performs the Helm operation
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to the tolerations of the Job that performs the Helm operation
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 type ProjectHelmChartStatus struct {
@@ -61,4 +139,72 @@ type ProjectHelmChartStatus struct {
 	// that this ProjectHelmChart was configured with. As noted above, this will correspond
 	// to the Project Registration Namespace's selector if project label is provided
 	TargetNamespaces []string `json:"targetNamespaces"`
+
+	// LastDeployedRelease is the last revision of the Helm release that was successfully deployed along with the spec.values that produced it
+	LastDeployedRelease *DeployedRelease `json:"lastDeployedRelease,omitempty"`
+
+	// FailedValuesDigest is the digest of the spec.values that failed to deploy and were rolled back to the values of the LastDeployedRelease
+	// This is only set if spec.rollbackOnFailure is enabled and will be cleared on modifying spec.values
+	FailedValuesDigest string `json:"failedValuesDigest,omitempty"`
+
+	// ValuesRevisions are the revisions of spec.values that have been applied, from oldest to newest
+	// To restore the spec.values of a revision, add the annotation 'helm.cattle.io/rollback-to-revision': '<revision>' to the ProjectHelmChart
+	ValuesRevisions []ValuesRevision `json:"valuesRevisions,omitempty"`
+}
+
+// ValuesRevision is a revision of the spec.values of a ProjectHelmChart that was applied
+type ValuesRevision struct {
+	// Revision is the number of this revision
+	Revision int `json:"revision"`
+
+	// ValuesDigest is the digest of the spec.values stored in this revision
+	ValuesDigest string `json:"valuesDigest"`
+
+	// AppliedAt is the time at which the values were first applied
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// DeployedRelease is a revision of a Helm release that was successfully deployed on behalf of a ProjectHelmChart
+type DeployedRelease struct {
+	// Revision is the revision of the Helm release
+	Revision int `json:"revision"`
+
+	// ValuesDigest is the digest of the spec.values that produced this revision
+	ValuesDigest string `json:"valuesDigest"`
+
+	// Values are the spec.values that produced this revision
+	Values GenericMap `json:"values"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Project explicitly defines a "Project" as a group of namespaces. It is only watched if the operator is configured to define projects
+// based on Project objects (see RuntimeOptions.ProjectCRD), in which case a Project Registration Namespace is created for each Project
+// without requiring any labels to be added to the member namespaces of the Project
+type Project struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ProjectSpec   `json:"spec"`
+	Status            ProjectStatus `json:"status"`
+}
+
+// ProjectSpec defines the spec of a Project
+type ProjectSpec struct {
+	// Namespaces are the names of the namespaces that are members of this Project
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector is a namespaceSelector that identifies additional namespaces that are members of this Project
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ProjectStatus defines the observed status of a Project
+type ProjectStatus struct {
+	// RegistrationNamespace is the Project Registration Namespace created for this Project, where ProjectHelmCharts can be created
+	RegistrationNamespace string `json:"registrationNamespace"`
+
+	// Namespaces are the current set of namespaces that are members of this Project
+	// Namespaces that do not exist, Project Registration Namespaces, and system namespaces are never members of a Project
+	Namespaces []string `json:"namespaces"`
 }
@@ -0,0 +1,185 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// HelmControllerBackend deploys releases by creating k3s-io/helm-controller HelmCharts that are picked up by a Helm Controller,
+	// which runs a Job in the system namespace to perform the Helm operation. A Helm Locker HelmRelease is created alongside it.
+	HelmControllerBackend = "helm-controller"
+
+	// HelmSDKBackend deploys releases in-process by using the Helm SDK to install and upgrade the release directly. Release
+	// information is stored in Secrets in the release namespace, so a Helm Locker HelmRelease is still created alongside it.
+	HelmSDKBackend = "helm-sdk"
+
+	// FleetBackend deploys releases by creating Fleet Bundles that are picked up by a Fleet controller.
+	// A Helm Locker HelmRelease is created alongside it.
+	FleetBackend = "fleet"
+)
+
+const (
+	// HelmOptionTimeout corresponds to spec.helmOptions.timeout on a ProjectHelmChart
+	HelmOptionTimeout = "timeout"
+
+	// HelmOptionAtomic corresponds to spec.helmOptions.atomic on a ProjectHelmChart
+	HelmOptionAtomic = "atomic"
+
+	// HelmOptionWait corresponds to spec.helmOptions.wait on a ProjectHelmChart
+	HelmOptionWait = "wait"
+
+	// HelmOptionFailurePolicy corresponds to spec.helmOptions.failurePolicy on a ProjectHelmChart
+	HelmOptionFailurePolicy = "failurePolicy"
+
+	// HelmOptionBackoffLimit corresponds to spec.helmOptions.backoffLimit on a ProjectHelmChart
+	HelmOptionBackoffLimit = "backoffLimit"
+
+	// HelmOptionNodeSelector corresponds to spec.helmOptions.nodeSelector on a ProjectHelmChart
+	HelmOptionNodeSelector = "nodeSelector"
+
+	// HelmOptionTolerations corresponds to spec.helmOptions.tolerations on a ProjectHelmChart
+	HelmOptionTolerations = "tolerations"
+
+	// FailurePolicyAbort leaves a failed release in place until it is manually resolved
+	FailurePolicyAbort = "abort"
+
+	// FailurePolicyReinstall uninstalls and reinstalls a failed release
+	FailurePolicyReinstall = "reinstall"
+)
+
+// SupportedHelmOptions returns the options in spec.helmOptions that are respected by the DeploymentBackend identified by the provided name
+func SupportedHelmOptions(name string) []string {
+	switch name {
+	case "", HelmControllerBackend:
+		// HelmCharts only allow configuring the timeout and failure policy of the Job that performs the Helm operation;
+		// the remaining options of the Job are only respected by the embedded Helm Controller (see NewHelmChartJobInjector)
+		return []string{HelmOptionTimeout, HelmOptionFailurePolicy, HelmOptionBackoffLimit, HelmOptionNodeSelector, HelmOptionTolerations}
+	case HelmSDKBackend:
+		return []string{HelmOptionTimeout, HelmOptionAtomic, HelmOptionWait, HelmOptionFailurePolicy}
+	case FleetBackend:
+		return []string{HelmOptionTimeout, HelmOptionAtomic}
+	default:
+		return nil
+	}
+}
+
+// IsJobHelmOption returns whether the option in spec.helmOptions configures the Job that performs the Helm operation,
+// which is only respected if the HelmChart is deployed by the embedded Helm Controller
+func IsJobHelmOption(option string) bool {
+	switch option {
+	case HelmOptionBackoffLimit, HelmOptionNodeSelector, HelmOptionTolerations:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release is a Helm release that needs to be deployed on behalf of a ProjectHelmChart
+type Release struct {
+	// Namespace is the namespace that the Helm release should be deployed into (e.g. the Project Release Namespace)
+	Namespace string
+
+	// Name is the name of the Helm release
+	Name string
+
+	// ValuesContent is the values.yaml that should be used to deploy the Helm release
+	ValuesContent string
+
+	// Labels are the labels that should be added to every object returned by a DeploymentBackend for this release
+	Labels map[string]string
+
+	// HelmOptions configures how the Helm release is deployed, if provided
+	//
+	// Note: only the options returned by SupportedHelmOptions for the backend will be respected
+	HelmOptions *v1alpha1.HelmOptions
+
+	// ServiceAccountName is the name of a ServiceAccount in the system namespace that Helm operations on the release
+	// should be performed as, if provided (only supported by the helm-sdk backend)
+	ServiceAccountName string
+}
+
+// DeploymentBackend deploys the Helm chart embedded in the operator on behalf of a ProjectHelmChart
+type DeploymentBackend interface {
+	// Deploy ensures the release will be deployed and returns the objects that need to be applied on its behalf
+	//
+	// Note: the returned objects are applied by the ProjectHelmChart generating handler, so removing a release
+	// from the apply set will cause any objects returned here to be deleted from the cluster
+	Deploy(release Release) ([]runtime.Object, error)
+
+	// Remove ensures a release that is no longer desired is removed
+	// Backends that only rely on the objects returned by Deploy can treat this as a no-op
+	Remove(release Release) error
+}
+
+// ReleaseStatus is the status of the last operation performed by a DeploymentBackend on a release
+type ReleaseStatus struct {
+	// State is the state of the release (e.g. Pending, Deploying, Deployed, or Failed)
+	State string
+
+	// Message is a human-readable message describing the state of the release
+	Message string
+}
+
+const (
+	// ReleasePendingState indicates that the release is waiting to be deployed
+	ReleasePendingState = "Pending"
+
+	// ReleaseDeployingState indicates that a Helm operation is currently being performed on the release
+	ReleaseDeployingState = "Deploying"
+
+	// ReleaseDeployedState indicates that the desired state of the release has been deployed
+	ReleaseDeployedState = "Deployed"
+
+	// ReleaseFailedState indicates that the last Helm operation performed on the release failed
+	ReleaseFailedState = "Failed"
+)
+
+// StatusReporter is implemented by DeploymentBackends that perform Helm operations themselves
+// and can therefore report on the outcome of those operations
+type StatusReporter interface {
+	// GetStatus returns the status of a release, if the backend has performed any operation on it
+	GetStatus(release Release) (ReleaseStatus, bool)
+
+	// OnStatusChange registers a handler that will be called whenever the status of a release changes
+	OnStatusChange(handler func(release Release))
+}
+
+// Options are options used to configure a DeploymentBackend
+type Options struct {
+	// SystemNamespace is the namespace where HelmCharts, HelmReleases, and other system resources should be created
+	SystemNamespace string
+
+	// ControllerName is the name of the controller that identifies this operator
+	ControllerName string
+
+	// ChartContent is the base64 tgz contents of the Helm chart that needs to be deployed
+	ChartContent string
+
+	// HelmJobImage is the job image to use to run HelmChart jobs (only used by the helm-controller backend)
+	HelmJobImage string
+
+	// FleetNamespace is the namespace to create Fleet Bundles in (only used by the fleet backend)
+	FleetNamespace string
+
+	// HelmSDKWorkers is the maximum number of Helm operations to perform concurrently (only used by the helm-sdk backend)
+	HelmSDKWorkers int
+}
+
+// New returns the DeploymentBackend identified by the provided name
+func New(ctx context.Context, name string, opts Options, configurationGetter ConfigurationGetter) (DeploymentBackend, error) {
+	switch name {
+	case "", HelmControllerBackend:
+		return NewHelmControllerBackend(opts), nil
+	case HelmSDKBackend:
+		return NewHelmSDKBackend(ctx, opts, configurationGetter)
+	case FleetBackend:
+		return NewFleetBackend(opts)
+	default:
+		return nil, fmt.Errorf("unknown deployment backend %s: must be one of %s, %s, or %s", name, HelmControllerBackend, HelmSDKBackend, FleetBackend)
+	}
+}
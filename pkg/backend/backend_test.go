@@ -0,0 +1,421 @@
+package backend
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	helmcontrollerv1 "github.com/k3s-io/helm-controller/pkg/apis/helm.cattle.io/v1"
+	"github.com/k3s-io/helm-controller/pkg/controllers/chart"
+	k3shelmcontroller "github.com/k3s-io/helm-controller/pkg/generated/controllers/helm.cattle.io/v1"
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	helmlockerv1alpha1 "github.com/rancher/helm-project-operator/pkg/helm-locker/apis/helm.cattle.io/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var testChartFiles = map[string]string{
+	"test-chart/Chart.yaml":               "apiVersion: v2\nname: test-chart\nversion: 0.1.0\n",
+	"test-chart/values.yaml":              "data: default\n",
+	"test-chart/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  data: {{ .Values.data }}\n",
+}
+
+func newTestChartContent(t *testing.T) string {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range testChartFiles {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// newMemoryConfigurationGetter returns a ConfigurationGetter backed by the Helm SDK memory driver
+func newMemoryConfigurationGetter() ConfigurationGetter {
+	memory := driver.NewMemory()
+	return func(namespace string, _ string) (*action.Configuration, error) {
+		memory.SetNamespace(namespace)
+		return &action.Configuration{
+			Releases:     storage.Init(memory),
+			KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+			Capabilities: chartutil.DefaultCapabilities,
+			Log:          func(_ string, _ ...interface{}) {},
+		}, nil
+	}
+}
+
+func newTestOptions(t *testing.T) Options {
+	return Options{
+		SystemNamespace: "cattle-helm-system",
+		ControllerName:  "helm-project-operator",
+		ChartContent:    newTestChartContent(t),
+		FleetNamespace:  "fleet-local",
+	}
+}
+
+func newTestRelease(values string) Release {
+	return Release{
+		Namespace:     "p-example-dummy",
+		Name:          "project-dummy",
+		ValuesContent: values,
+		Labels:        map[string]string{"helm.cattle.io/helm-project-operated": "true"},
+	}
+}
+
+func getLastRelease(t *testing.T, configurationGetter ConfigurationGetter, release Release) *rspb.Release {
+	cfg, err := configurationGetter(release.Namespace, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := cfg.Releases.Last(release.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return last
+}
+
+func TestHelmControllerBackend(t *testing.T) {
+	b, err := New(context.Background(), HelmControllerBackend, newTestOptions(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := newTestRelease("data: example\n")
+	backoffLimit := int32(3)
+	release.HelmOptions = &v1alpha1.HelmOptions{
+		Timeout:       &metav1.Duration{Duration: 10 * time.Minute},
+		FailurePolicy: FailurePolicyAbort,
+		BackoffLimit:  &backoffLimit,
+		NodeSelector:  map[string]string{"example.com/pool": "helm"},
+		Tolerations:   []corev1.Toleration{{Key: "example.com/dedicated", Operator: corev1.TolerationOpExists}},
+	}
+	objs, err := b.Deploy(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected a HelmChart and a HelmRelease, found %d objects", len(objs))
+	}
+	helmChart, ok := objs[0].(*helmcontrollerv1.HelmChart)
+	if !ok {
+		t.Fatalf("expected first object to be a HelmChart, found %T", objs[0])
+	}
+	if helmChart.Namespace != "cattle-helm-system" || helmChart.Spec.TargetNamespace != "p-example-dummy" {
+		t.Errorf("unexpected HelmChart %s/%s targeting %s", helmChart.Namespace, helmChart.Name, helmChart.Spec.TargetNamespace)
+	}
+	if helmChart.Spec.ValuesContent != "data: example\n" {
+		t.Errorf("unexpected valuesContent %q", helmChart.Spec.ValuesContent)
+	}
+	if helmChart.Spec.Timeout == nil || helmChart.Spec.Timeout.Duration != 10*time.Minute || helmChart.Spec.FailurePolicy != FailurePolicyAbort {
+		t.Errorf("expected helm options to be set on HelmChart, found timeout %v and failurePolicy %q", helmChart.Spec.Timeout, helmChart.Spec.FailurePolicy)
+	}
+	expectedAnnotations := map[string]string{
+		HelmChartJobBackoffLimitAnnotation: "3",
+		HelmChartJobNodeSelectorAnnotation: `{"example.com/pool":"helm"}`,
+		HelmChartJobTolerationsAnnotation:  `[{"key":"example.com/dedicated","operator":"Exists"}]`,
+	}
+	for k, v := range expectedAnnotations {
+		if helmChart.Annotations[k] != v {
+			t.Errorf("expected annotation %s to be %s, found %q", k, v, helmChart.Annotations[k])
+		}
+	}
+	if _, ok := objs[1].(*helmlockerv1alpha1.HelmRelease); !ok {
+		t.Fatalf("expected second object to be a HelmRelease, found %T", objs[1])
+	}
+}
+
+// fakeHelmChartCache is a HelmChartCache backed by the provided HelmCharts
+type fakeHelmChartCache struct {
+	k3shelmcontroller.HelmChartCache
+
+	helmCharts []*helmcontrollerv1.HelmChart
+}
+
+func (c *fakeHelmChartCache) Get(namespace, name string) (*helmcontrollerv1.HelmChart, error) {
+	for _, helmChart := range c.helmCharts {
+		if helmChart.Namespace == namespace && helmChart.Name == name {
+			return helmChart, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(helmcontrollerv1.Resource("helmcharts"), name)
+}
+
+func TestHelmChartJobInjector(t *testing.T) {
+	newJob := func(helmChartName string) *batchv1.Job {
+		backoffLimit := int32(1000)
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "helm-install-" + helmChartName,
+				Namespace: "cattle-helm-system",
+				Labels:    map[string]string{chart.Label: helmChartName},
+			},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: &backoffLimit,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						NodeSelector: map[string]string{corev1.LabelOSStable: "linux"},
+					},
+				},
+			},
+		}
+	}
+	helmChartCache := &fakeHelmChartCache{
+		helmCharts: []*helmcontrollerv1.HelmChart{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "configured",
+					Namespace: "cattle-helm-system",
+					Annotations: map[string]string{
+						HelmChartJobBackoffLimitAnnotation: "3",
+						HelmChartJobNodeSelectorAnnotation: `{"example.com/pool":"helm","kubernetes.io/os":"windows"}`,
+						HelmChartJobTolerationsAnnotation:  `[{"key":"example.com/dedicated","operator":"Exists"}]`,
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "invalid",
+					Namespace:   "cattle-helm-system",
+					Annotations: map[string]string{HelmChartJobBackoffLimitAnnotation: "many"},
+				},
+			},
+		},
+	}
+	inject := NewHelmChartJobInjector(helmChartCache)
+
+	configuredJob := newJob("configured")
+	unconfiguredJob := newJob("missing")
+	if _, err := inject([]runtime.Object{configuredJob, unconfiguredJob}); err != nil {
+		t.Fatal(err)
+	}
+	if *configuredJob.Spec.BackoffLimit != 3 {
+		t.Errorf("expected backoffLimit to be overridden, found %d", *configuredJob.Spec.BackoffLimit)
+	}
+	expectedNodeSelector := map[string]string{corev1.LabelOSStable: "linux", "example.com/pool": "helm"}
+	if !reflect.DeepEqual(configuredJob.Spec.Template.Spec.NodeSelector, expectedNodeSelector) {
+		t.Errorf("expected nodeSelector %v, found %v", expectedNodeSelector, configuredJob.Spec.Template.Spec.NodeSelector)
+	}
+	expectedTolerations := []corev1.Toleration{{Key: "example.com/dedicated", Operator: corev1.TolerationOpExists}}
+	if !reflect.DeepEqual(configuredJob.Spec.Template.Spec.Tolerations, expectedTolerations) {
+		t.Errorf("expected tolerations %v, found %v", expectedTolerations, configuredJob.Spec.Template.Spec.Tolerations)
+	}
+	if *unconfiguredJob.Spec.BackoffLimit != 1000 || len(unconfiguredJob.Spec.Template.Spec.Tolerations) != 0 {
+		t.Errorf("expected Job of an unknown HelmChart to be left unmodified, found %v", unconfiguredJob.Spec)
+	}
+
+	if _, err := inject([]runtime.Object{newJob("invalid")}); err == nil {
+		t.Error("expected an invalid backoffLimit annotation to be rejected")
+	}
+}
+
+// watchStatus returns a channel that is signalled whenever the backend reports a change in the status of a release
+func watchStatus(t *testing.T, b DeploymentBackend) <-chan struct{} {
+	statusReporter, ok := b.(StatusReporter)
+	if !ok {
+		t.Fatalf("expected backend to report release statuses")
+	}
+	statusChangeCh := make(chan struct{}, 1)
+	statusReporter.OnStatusChange(func(_ Release) {
+		select {
+		case statusChangeCh <- struct{}{}:
+		default:
+			// a signal is already pending
+		}
+	})
+	return statusChangeCh
+}
+
+// waitForStatus waits for the backend to report the provided state for a release, re-checking on every status change
+func waitForStatus(t *testing.T, b DeploymentBackend, statusChangeCh <-chan struct{}, release Release, state string) ReleaseStatus {
+	statusReporter := b.(StatusReporter)
+	timeout := time.After(10 * time.Second)
+	for {
+		status, _ := statusReporter.GetStatus(release)
+		if status.State == state {
+			return status
+		}
+		select {
+		case <-statusChangeCh:
+		case <-timeout:
+			t.Fatalf("timed out waiting for release to be %s, last status was %s: %s", state, status.State, status.Message)
+			return status
+		}
+	}
+}
+
+func TestHelmSDKBackend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	configurationGetter := newMemoryConfigurationGetter()
+	b, err := New(ctx, HelmSDKBackend, newTestOptions(t), configurationGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statusChanges int32
+	b.(StatusReporter).OnStatusChange(func(_ Release) {
+		atomic.AddInt32(&statusChanges, 1)
+	})
+	statusChangeCh := watchStatus(t, b)
+	release := newTestRelease("data: example\n")
+	objs, err := b.Deploy(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("expected only a HelmRelease, found %d objects", len(objs))
+	}
+	if _, ok := objs[0].(*helmlockerv1alpha1.HelmRelease); !ok {
+		t.Fatalf("expected object to be a HelmRelease, found %T", objs[0])
+	}
+	waitForStatus(t, b, statusChangeCh, release, ReleaseDeployedState)
+	last := getLastRelease(t, configurationGetter, release)
+	if last.Version != 1 || last.Info.Status != rspb.StatusDeployed {
+		t.Fatalf("expected revision 1 to be deployed, found revision %d with status %s", last.Version, last.Info.Status)
+	}
+	if atomic.LoadInt32(&statusChanges) == 0 {
+		t.Fatalf("expected status change handler to be called on deploying release")
+	}
+
+	// redeploying the same values should not upgrade the release; the Helm operation is run synchronously
+	// here since an up-to-date release does not produce a status change that could be waited on
+	if _, err := b.Deploy(release); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.(*helmSDKBackend).applyRelease(releaseKey(release)); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, b, statusChangeCh, release, ReleaseDeployedState)
+	if last := getLastRelease(t, configurationGetter, release); last.Version != 1 {
+		t.Fatalf("expected no upgrade on identical values, found revision %d", last.Version)
+	}
+
+	// changing values should upgrade the release
+	release.ValuesContent = "data: changed\n"
+	if _, err := b.Deploy(release); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, b, statusChangeCh, release, ReleaseDeployedState)
+	last = getLastRelease(t, configurationGetter, release)
+	if last.Version != 2 || last.Config["data"] != "changed" {
+		t.Fatalf("expected revision 2 with changed values, found revision %d with values %v", last.Version, last.Config)
+	}
+
+	// invalid values should be rejected before any Helm operation is performed
+	invalidRelease := release
+	invalidRelease.ValuesContent = "data: [\n"
+	if _, err := b.Deploy(invalidRelease); err == nil {
+		t.Fatalf("expected error on deploying release with invalid values")
+	}
+
+	if err := b.Remove(release); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := configurationGetter(release.Namespace, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Releases.Last(release.Name); err == nil {
+		t.Fatalf("expected release to be uninstalled")
+	}
+	if _, ok := b.(StatusReporter).GetStatus(release); ok {
+		t.Fatalf("expected status to be cleared on removing release")
+	}
+	// removing a release that does not exist is a no-op
+	if err := b.Remove(release); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHelmSDKBackendServiceAccount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	memoryConfigurationGetter := newMemoryConfigurationGetter()
+	impersonatedUsers := make(chan string, 10)
+	configurationGetter := func(namespace string, impersonateUser string) (*action.Configuration, error) {
+		impersonatedUsers <- impersonateUser
+		return memoryConfigurationGetter(namespace, impersonateUser)
+	}
+	b, err := New(ctx, HelmSDKBackend, newTestOptions(t), configurationGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statusChangeCh := watchStatus(t, b)
+	release := newTestRelease("data: example\n")
+	release.ServiceAccountName = "project-dummy-deployer"
+	if _, err := b.Deploy(release); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, b, statusChangeCh, release, ReleaseDeployedState)
+	if user := <-impersonatedUsers; user != "system:serviceaccount:cattle-helm-system:project-dummy-deployer" {
+		t.Fatalf("expected Helm operations to be performed as the release ServiceAccount, found user %q", user)
+	}
+}
+
+func TestFleetBackend(t *testing.T) {
+	b, err := New(context.Background(), FleetBackend, newTestOptions(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := b.Deploy(newTestRelease("data: example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected a Bundle and a HelmRelease, found %d objects", len(objs))
+	}
+	bundle, ok := objs[0].(*unstructured.Unstructured)
+	if !ok {
+		t.Fatalf("expected first object to be a Bundle, found %T", objs[0])
+	}
+	if bundle.GroupVersionKind() != FleetBundleGVK || bundle.GetNamespace() != "fleet-local" {
+		t.Errorf("unexpected Bundle %s %s/%s", bundle.GroupVersionKind(), bundle.GetNamespace(), bundle.GetName())
+	}
+	resources, _, _ := unstructured.NestedSlice(bundle.Object, "spec", "resources")
+	if len(resources) != len(testChartFiles) {
+		t.Errorf("expected %d resources in Bundle, found %d", len(testChartFiles), len(resources))
+	}
+	data, _, _ := unstructured.NestedString(bundle.Object, "spec", "helm", "values", "data")
+	if data != "example" {
+		t.Errorf("expected values to be set on Bundle, found data=%q", data)
+	}
+}
+
+func TestUnknownBackend(t *testing.T) {
+	if _, err := New(context.Background(), "unknown", newTestOptions(t), nil); err == nil {
+		t.Fatalf("expected error on unknown backend")
+	}
+}
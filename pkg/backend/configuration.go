@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/action"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ConfigurationGetter returns a Helm action configuration that manages releases in the provided namespace
+// If impersonateUser is provided, all requests made by the Helm action will be performed as that user
+type ConfigurationGetter func(namespace string, impersonateUser string) (*action.Configuration, error)
+
+// NewConfigurationGetter returns a ConfigurationGetter that stores releases in Secrets in the release namespace,
+// which is identical to how the Helm CLI (and therefore Helm Locker) expects releases to be stored
+func NewConfigurationGetter(clientConfig clientcmd.ClientConfig) ConfigurationGetter {
+	return func(namespace string, impersonateUser string) (*action.Configuration, error) {
+		cfg := &action.Configuration{}
+		getter := &restClientGetter{
+			clientConfig: &namespacedClientConfig{
+				delegate:        clientConfig,
+				namespace:       namespace,
+				impersonateUser: impersonateUser,
+			},
+		}
+		if err := cfg.Init(getter, namespace, "secrets", logrus.Debugf); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+}
+
+// restClientGetter implements genericclioptions.RESTClientGetter on top of a clientcmd.ClientConfig
+type restClientGetter struct {
+	clientConfig clientcmd.ClientConfig
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return g.clientConfig.ClientConfig()
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	restConfig, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(discoveryClient), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	discoveryClient, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+	return restmapper.NewShortcutExpander(mapper, discoveryClient), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
+
+// namespacedClientConfig overrides the namespace of a clientcmd.ClientConfig so that namespaced
+// resources rendered by a chart without an explicit namespace are deployed into the release namespace
+type namespacedClientConfig struct {
+	delegate clientcmd.ClientConfig
+
+	namespace       string
+	impersonateUser string
+}
+
+func (c *namespacedClientConfig) RawConfig() (clientcmdapi.Config, error) {
+	return c.delegate.RawConfig()
+}
+
+func (c *namespacedClientConfig) ClientConfig() (*rest.Config, error) {
+	restConfig, err := c.delegate.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(c.impersonateUser) == 0 {
+		return restConfig, nil
+	}
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: c.impersonateUser,
+	}
+	return restConfig, nil
+}
+
+func (c *namespacedClientConfig) Namespace() (string, bool, error) {
+	return c.namespace, true, nil
+}
+
+func (c *namespacedClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return c.delegate.ConfigAccess()
+}
@@ -0,0 +1,163 @@
+package backend
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// FleetBundleGVK is the GroupVersionKind of the Fleet Bundle created by the fleet backend
+	FleetBundleGVK = schema.GroupVersionKind{
+		Group:   "fleet.cattle.io",
+		Version: "v1alpha1",
+		Kind:    "Bundle",
+	}
+)
+
+// NewFleetBackend returns a DeploymentBackend that deploys releases by creating Fleet Bundles
+func NewFleetBackend(opts Options) (DeploymentBackend, error) {
+	if len(opts.FleetNamespace) == 0 {
+		return nil, errors.New("cannot create fleet deployment backend without a namespace to create Fleet Bundles in")
+	}
+	resources, err := getBundleResources(opts.ChartContent)
+	if err != nil {
+		return nil, err
+	}
+	return &fleetBackend{
+		opts:      opts,
+		resources: resources,
+	}, nil
+}
+
+type fleetBackend struct {
+	opts Options
+
+	// resources are the files of the embedded chart in the format expected by spec.resources of a Fleet Bundle
+	resources []interface{}
+}
+
+// Deploy returns the Fleet Bundle and HelmRelease that need to be created for this release
+func (b *fleetBackend) Deploy(release Release) ([]runtime.Object, error) {
+	bundle, err := b.getBundle(release)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{
+		bundle,
+		getHelmRelease(b.opts, release),
+	}, nil
+}
+
+// Remove does nothing since Fleet will uninstall the release when the Bundle is deleted
+func (b *fleetBackend) Remove(_ Release) error {
+	return nil
+}
+
+// getBundle returns the Fleet Bundle created on behalf of this release
+func (b *fleetBackend) getBundle(release Release) (*unstructured.Unstructured, error) {
+	values, err := toJSONMap(release.ValuesContent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse values for release %s/%s: %s", release.Namespace, release.Name, err)
+	}
+	bundle := &unstructured.Unstructured{}
+	bundle.SetGroupVersionKind(FleetBundleGVK)
+	bundle.SetNamespace(b.opts.FleetNamespace)
+	bundle.SetName(release.Name)
+	bundle.SetLabels(copyLabels(release.Labels))
+	helmOptions := map[string]interface{}{
+		"releaseName": release.Name,
+		"values":      values,
+	}
+	if release.HelmOptions != nil {
+		if release.HelmOptions.Timeout != nil {
+			helmOptions["timeoutSeconds"] = int64(release.HelmOptions.Timeout.Duration.Seconds())
+		}
+		if release.HelmOptions.Atomic {
+			helmOptions["atomic"] = true
+		}
+	}
+	bundle.Object["spec"] = map[string]interface{}{
+		"defaultNamespace": release.Namespace,
+		"helm":             helmOptions,
+		"resources":        runtime.DeepCopyJSONValue(b.resources),
+		"targets": []interface{}{
+			map[string]interface{}{
+				// all clusters within the Fleet workspace, which is only the local cluster for fleet-local
+				"clusterSelector": map[string]interface{}{},
+			},
+		},
+	}
+	return bundle, nil
+}
+
+// getBundleResources converts the base64 tgz contents of a Helm chart into Fleet Bundle resources
+func getBundleResources(chartContent string) ([]interface{}, error) {
+	tgzChartBytes, err := base64.StdEncoding.DecodeString(chartContent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode chart content: %s", err)
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(tgzChartBytes))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gzipReader to read from chart content: %s", err)
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	var resources []interface{}
+	for {
+		h, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Fleet expects paths relative to the root of the chart
+		splitName := strings.SplitN(h.Name, "/", 2)
+		nameWithoutRootDir := splitName[0]
+		if len(splitName) > 1 {
+			nameWithoutRootDir = splitName[1]
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, tarReader); err != nil {
+			return nil, err
+		}
+		resources = append(resources, map[string]interface{}{
+			"name":     nameWithoutRootDir,
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString(buf.Bytes()),
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].(map[string]interface{})["name"].(string) < resources[j].(map[string]interface{})["name"].(string)
+	})
+	return resources, nil
+}
+
+// toJSONMap converts YAML content into a map that only contains JSON-compatible types
+func toJSONMap(yamlContent string) (map[string]interface{}, error) {
+	jsonContent, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(jsonContent, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
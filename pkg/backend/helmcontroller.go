@@ -0,0 +1,203 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	helmcontrollerv1 "github.com/k3s-io/helm-controller/pkg/apis/helm.cattle.io/v1"
+	"github.com/k3s-io/helm-controller/pkg/controllers/chart"
+	k3shelmcontroller "github.com/k3s-io/helm-controller/pkg/generated/controllers/helm.cattle.io/v1"
+	helmlockerv1alpha1 "github.com/rancher/helm-project-operator/pkg/helm-locker/apis/helm.cattle.io/v1alpha1"
+	helmlockerrelease "github.com/rancher/helm-project-operator/pkg/helm-locker/controllers/release"
+	"github.com/rancher/wrangler/pkg/apply/injectors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	// DefaultJobImage is the job image used to run HelmChart jobs if HelmJobImage is not provided
+	DefaultJobImage = chart.DefaultJobImage
+)
+
+const (
+	// HelmChartJobBackoffLimitAnnotation is added to a HelmChart to override the backoffLimit of the Job that deploys it
+	HelmChartJobBackoffLimitAnnotation = "helm.cattle.io/job-backoff-limit"
+
+	// HelmChartJobNodeSelectorAnnotation is added to a HelmChart to add a JSON-encoded nodeSelector to the Job that deploys it
+	HelmChartJobNodeSelectorAnnotation = "helm.cattle.io/job-node-selector"
+
+	// HelmChartJobTolerationsAnnotation is added to a HelmChart to add JSON-encoded tolerations to the Job that deploys it
+	HelmChartJobTolerationsAnnotation = "helm.cattle.io/job-tolerations"
+)
+
+// NewHelmControllerBackend returns a DeploymentBackend that deploys releases via k3s-io/helm-controller HelmCharts
+func NewHelmControllerBackend(opts Options) DeploymentBackend {
+	return &helmControllerBackend{
+		opts: opts,
+	}
+}
+
+type helmControllerBackend struct {
+	opts Options
+}
+
+// Deploy returns the HelmChart and HelmRelease that need to be created for this release
+func (b *helmControllerBackend) Deploy(release Release) ([]runtime.Object, error) {
+	helmChart, err := b.getHelmChart(release)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{
+		helmChart,
+		getHelmRelease(b.opts, release),
+	}, nil
+}
+
+// Remove does nothing since the Helm Controller will uninstall the release when the HelmChart is deleted
+func (b *helmControllerBackend) Remove(_ Release) error {
+	return nil
+}
+
+// getHelmChart returns the HelmChart created on behalf of this release
+func (b *helmControllerBackend) getHelmChart(release Release) (*helmcontrollerv1.HelmChart, error) {
+	// must be in system namespace since helm controllers are configured to only watch one namespace
+	jobImage := DefaultJobImage
+	if len(b.opts.HelmJobImage) > 0 {
+		jobImage = b.opts.HelmJobImage
+	}
+	helmChart := helmcontrollerv1.NewHelmChart(b.opts.SystemNamespace, release.Name, helmcontrollerv1.HelmChart{
+		Spec: helmcontrollerv1.HelmChartSpec{
+			TargetNamespace: release.Namespace,
+			Chart:           release.Name,
+			JobImage:        jobImage,
+			ChartContent:    b.opts.ChartContent,
+			ValuesContent:   release.ValuesContent,
+		},
+	})
+	annotations := map[string]string{
+		chart.ManagedBy: b.opts.ControllerName,
+	}
+	if release.HelmOptions != nil {
+		helmChart.Spec.Timeout = release.HelmOptions.Timeout
+		helmChart.Spec.FailurePolicy = release.HelmOptions.FailurePolicy
+		// the HelmChart spec has no fields for the remaining options of the Job, so they are recorded as annotations
+		// that are picked up by the injector registered with the embedded Helm Controller
+		if release.HelmOptions.BackoffLimit != nil {
+			annotations[HelmChartJobBackoffLimitAnnotation] = strconv.Itoa(int(*release.HelmOptions.BackoffLimit))
+		}
+		if len(release.HelmOptions.NodeSelector) > 0 {
+			nodeSelectorBytes, err := json.Marshal(release.HelmOptions.NodeSelector)
+			if err != nil {
+				return nil, fmt.Errorf("unable to marshall nodeSelector: %s", err)
+			}
+			annotations[HelmChartJobNodeSelectorAnnotation] = string(nodeSelectorBytes)
+		}
+		if len(release.HelmOptions.Tolerations) > 0 {
+			tolerationsBytes, err := json.Marshal(release.HelmOptions.Tolerations)
+			if err != nil {
+				return nil, fmt.Errorf("unable to marshall tolerations: %s", err)
+			}
+			annotations[HelmChartJobTolerationsAnnotation] = string(tolerationsBytes)
+		}
+	}
+	helmChart.SetLabels(copyLabels(release.Labels))
+	helmChart.SetAnnotations(annotations)
+	return helmChart, nil
+}
+
+// NewHelmChartJobInjector returns an injector for the apply of the embedded Helm Controller that overrides the backoffLimit and adds the
+// nodeSelector and tolerations recorded in the annotations of a HelmChart to the Job that deploys it
+//
+// Note: this is required since k3s-io/helm-controller hardcodes these fields of the Job and neither HelmChart nor HelmChartConfig allows
+// them to be overridden. Therefore, these options are only respected if the embedded Helm Controller is enabled.
+func NewHelmChartJobInjector(helmChartCache k3shelmcontroller.HelmChartCache) injectors.ConfigInjector {
+	return func(objs []runtime.Object) ([]runtime.Object, error) {
+		for _, obj := range objs {
+			job, ok := obj.(*batchv1.Job)
+			if !ok {
+				continue
+			}
+			helmChartName, ok := job.Labels[chart.Label]
+			if !ok {
+				continue
+			}
+			helmChart, err := helmChartCache.Get(job.Namespace, helmChartName)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			if err := setJobOptions(job, helmChart.Annotations); err != nil {
+				return nil, fmt.Errorf("unable to set job options of HelmChart %s/%s: %s", helmChart.Namespace, helmChart.Name, err)
+			}
+		}
+		return objs, nil
+	}
+}
+
+// setJobOptions modifies the Job based on the job options recorded in the annotations of the HelmChart that it deploys
+func setJobOptions(job *batchv1.Job, annotations map[string]string) error {
+	if backoffLimit, ok := annotations[HelmChartJobBackoffLimitAnnotation]; ok {
+		parsedBackoffLimit, err := strconv.ParseInt(backoffLimit, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid backoff limit %s: %s", backoffLimit, err)
+		}
+		backoffLimit := int32(parsedBackoffLimit)
+		job.Spec.BackoffLimit = &backoffLimit
+	}
+	if nodeSelectorJSON, ok := annotations[HelmChartJobNodeSelectorAnnotation]; ok {
+		var nodeSelector map[string]string
+		if err := json.Unmarshal([]byte(nodeSelectorJSON), &nodeSelector); err != nil {
+			return fmt.Errorf("invalid nodeSelector %s: %s", nodeSelectorJSON, err)
+		}
+		if job.Spec.Template.Spec.NodeSelector == nil {
+			job.Spec.Template.Spec.NodeSelector = make(map[string]string, len(nodeSelector))
+		}
+		for k, v := range nodeSelector {
+			if _, exists := job.Spec.Template.Spec.NodeSelector[k]; exists {
+				// never override the nodeSelector set by the Helm Controller (e.g. to schedule onto Linux nodes)
+				continue
+			}
+			job.Spec.Template.Spec.NodeSelector[k] = v
+		}
+	}
+	if tolerationsJSON, ok := annotations[HelmChartJobTolerationsAnnotation]; ok {
+		var tolerations []corev1.Toleration
+		if err := json.Unmarshal([]byte(tolerationsJSON), &tolerations); err != nil {
+			return fmt.Errorf("invalid tolerations %s: %s", tolerationsJSON, err)
+		}
+		job.Spec.Template.Spec.Tolerations = append(job.Spec.Template.Spec.Tolerations, tolerations...)
+	}
+	return nil
+}
+
+// getHelmRelease returns the HelmRelease created on behalf of this release, which allows Helm Locker to lock the release
+// regardless of which backend performed the Helm operation
+func getHelmRelease(opts Options, release Release) *helmlockerv1alpha1.HelmRelease {
+	// must be in system namespace since helmlocker controllers are configured to only watch one namespace
+	helmRelease := helmlockerv1alpha1.NewHelmRelease(opts.SystemNamespace, release.Name, helmlockerv1alpha1.HelmRelease{
+		Spec: helmlockerv1alpha1.HelmReleaseSpec{
+			Release: helmlockerv1alpha1.ReleaseKey{
+				Namespace: release.Namespace,
+				Name:      release.Name,
+			},
+		},
+	})
+	helmRelease.SetLabels(copyLabels(release.Labels))
+	helmRelease.SetAnnotations(map[string]string{
+		helmlockerrelease.ManagedBy: opts.ControllerName,
+	})
+	return helmRelease
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,418 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rancher/helm-project-operator/pkg/applier"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DefaultHelmSDKWorkers is the default number of Helm operations that the helm-sdk backend will run concurrently
+	DefaultHelmSDKWorkers = 5
+
+	// DefaultHelmSDKTimeout is the default time to wait for any individual Kubernetes operation during a Helm operation
+	DefaultHelmSDKTimeout = 300 * time.Second
+)
+
+// NewHelmSDKBackend returns a DeploymentBackend that installs and upgrades releases in-process via the Helm SDK
+//
+// Helm operations are performed asynchronously by a bounded pool of workers, which ensures that a single release is never
+// operated on concurrently. The outcome of each operation is reported via the StatusReporter interface.
+func NewHelmSDKBackend(ctx context.Context, opts Options, configurationGetter ConfigurationGetter) (DeploymentBackend, error) {
+	if configurationGetter == nil {
+		return nil, errors.New("cannot create helm-sdk deployment backend without a way to get Helm action configurations")
+	}
+	chart, err := loadChart(opts.ChartContent)
+	if err != nil {
+		return nil, err
+	}
+	workers := opts.HelmSDKWorkers
+	if workers <= 0 {
+		workers = DefaultHelmSDKWorkers
+	}
+	b := &helmSDKBackend{
+		opts:                opts,
+		chart:               chart,
+		configurationGetter: configurationGetter,
+		desired:             make(map[string]Release),
+		statuses:            make(map[string]helmSDKReleaseStatus),
+		locks:               make(map[string]*sync.Mutex),
+	}
+	// note: the Applyinator ensures that the same release key is never processed by multiple workers at the same time
+	// and that multiple Deploy calls for the same release before it is processed only result in a single Helm operation
+	b.releaseApplyinator = applier.NewApplyinator("helm-sdk-release-applyinator", b.applyRelease, nil)
+	b.releaseApplyinator.Run(ctx, workers)
+	return b, nil
+}
+
+type helmSDKBackend struct {
+	opts  Options
+	chart *chart.Chart
+
+	configurationGetter ConfigurationGetter
+	releaseApplyinator  applier.Applyinator
+
+	// desired is the latest requested state of each release keyed by namespace/name
+	desired     map[string]Release
+	desiredLock sync.RWMutex
+
+	// statuses is the status of the last operation performed on each release keyed by namespace/name
+	statuses   map[string]helmSDKReleaseStatus
+	statusLock sync.RWMutex
+
+	// locks ensure that Helm operations and uninstalls are never performed concurrently on the same release
+	locks     map[string]*sync.Mutex
+	locksLock sync.Mutex
+
+	onStatusChange []func(release Release)
+}
+
+// helmSDKReleaseStatus is the ReleaseStatus along with the digest of the desired state that produced it
+type helmSDKReleaseStatus struct {
+	ReleaseStatus
+
+	digest string
+}
+
+// Deploy requests that the release is installed or upgraded and returns the HelmRelease that needs to be created for it
+func (b *helmSDKBackend) Deploy(release Release) ([]runtime.Object, error) {
+	if _, err := chartutil.ReadValues([]byte(release.ValuesContent)); err != nil {
+		return nil, fmt.Errorf("unable to parse values for release %s/%s: %s", release.Namespace, release.Name, err)
+	}
+	key := releaseKey(release)
+	digest := b.getDigest(release)
+	if status, ok := b.getStatus(key); ok && status.State == ReleaseFailedState && status.digest == digest {
+		// the last operation with this exact configuration failed; do not retry until the desired state changes
+		return []runtime.Object{getHelmRelease(b.opts, release)}, nil
+	}
+	b.desiredLock.Lock()
+	b.desired[key] = release
+	b.desiredLock.Unlock()
+	if status, ok := b.getStatus(key); !ok || status.digest != digest {
+		b.setStatus(release, ReleasePendingState, "Waiting for release to be deployed by the operator", digest)
+	}
+	b.releaseApplyinator.Apply(key)
+	return []runtime.Object{
+		getHelmRelease(b.opts, release),
+	}, nil
+}
+
+// Remove uninstalls the release if it exists
+func (b *helmSDKBackend) Remove(release Release) error {
+	key := releaseKey(release)
+	b.desiredLock.Lock()
+	delete(b.desired, key)
+	b.desiredLock.Unlock()
+
+	lock := b.getLock(key)
+	lock.Lock()
+	defer b.releaseLock(key, lock)
+
+	b.statusLock.Lock()
+	delete(b.statuses, key)
+	b.statusLock.Unlock()
+
+	// note: uninstalls are not performed as the release's ServiceAccount since it may already have been removed;
+	// this is safe since an uninstall only removes resources that were previously deployed as part of the release
+	cfg, err := b.configurationGetter(release.Namespace, "")
+	if err != nil {
+		return fmt.Errorf("unable to get Helm configuration for namespace %s: %s", release.Namespace, err)
+	}
+	history, err := getHistory(cfg, release.Name)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 || history[0].Info.Status == rspb.StatusUninstalled {
+		// nothing to uninstall
+		return nil
+	}
+	logrus.Infof("Uninstalling release %s/%s", release.Namespace, release.Name)
+	_, err = action.NewUninstall(cfg).Run(release.Name)
+	if err != nil {
+		return fmt.Errorf("unable to uninstall release %s/%s: %s", release.Namespace, release.Name, err)
+	}
+	return nil
+}
+
+// GetStatus returns the status of the last operation performed on the release
+func (b *helmSDKBackend) GetStatus(release Release) (ReleaseStatus, bool) {
+	status, ok := b.getStatus(releaseKey(release))
+	return status.ReleaseStatus, ok
+}
+
+// OnStatusChange registers a handler that is called whenever the status of a release changes
+func (b *helmSDKBackend) OnStatusChange(handler func(release Release)) {
+	b.onStatusChange = append(b.onStatusChange, handler)
+}
+
+// applyRelease is run by the worker pool to perform the Helm operation required to reach the desired state of a release
+func (b *helmSDKBackend) applyRelease(key string) error {
+	lock := b.getLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b.desiredLock.RLock()
+	release, ok := b.desired[key]
+	b.desiredLock.RUnlock()
+	if !ok {
+		// release was removed before it could be processed
+		return nil
+	}
+	digest := b.getDigest(release)
+	values, err := chartutil.ReadValues([]byte(release.ValuesContent))
+	if err != nil {
+		b.setStatus(release, ReleaseFailedState, fmt.Sprintf("Unable to parse values: %s", err), digest)
+		return nil
+	}
+	cfg, err := b.configurationGetter(release.Namespace, b.getImpersonateUser(release))
+	if err != nil {
+		// transient error, retry via the workqueue
+		return fmt.Errorf("unable to get Helm configuration for namespace %s: %s", release.Namespace, err)
+	}
+	history, err := getHistory(cfg, release.Name)
+	if err != nil {
+		// transient error, retry via the workqueue
+		return err
+	}
+	if len(history) > 0 && history[0].Info.Status.IsPending() {
+		// another operation is still in progress, retry via the workqueue
+		return fmt.Errorf("unable to deploy release %s/%s: another operation (%s) is in progress", release.Namespace, release.Name, history[0].Info.Status)
+	}
+	if len(history) > 0 && b.isUpToDate(history[0], values) {
+		b.setStatus(release, ReleaseDeployedState, fmt.Sprintf("Revision %d of release has been deployed", history[0].Version), digest)
+		return nil
+	}
+	b.setStatus(release, ReleaseDeployingState, "Performing Helm operation on release", digest)
+	rel, err := b.installOrUpgrade(cfg, release, values, history)
+	if err != nil {
+		// Helm operation failures are not retried until the desired state changes since retrying a
+		// failing configuration would only produce another failed revision of the release
+		b.setStatus(release, ReleaseFailedState, err.Error(), digest)
+		if apierrors.IsForbidden(err) {
+			// the permissions of the release's ServiceAccount may not have been created yet or may be modified
+			// outside of the desired state of the release, so retry via the workqueue
+			return err
+		}
+		return nil
+	}
+	b.setStatus(release, ReleaseDeployedState, fmt.Sprintf("Revision %d of release has been deployed", rel.Version), digest)
+	return nil
+}
+
+func (b *helmSDKBackend) installOrUpgrade(cfg *action.Configuration, release Release, values map[string]interface{}, history []*rspb.Release) (*rspb.Release, error) {
+	helmOptions := getHelmOptions(release)
+	if len(history) == 0 || !hasDeployedRevision(history) {
+		// install a new release, replacing any failed or uninstalled revisions that may already exist
+		return b.install(cfg, release, values, len(history) > 0)
+	}
+	logrus.Infof("Upgrading release %s/%s", release.Namespace, release.Name)
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = release.Namespace
+	upgrade.Timeout = helmOptions.timeout
+	upgrade.Wait = helmOptions.wait
+	upgrade.Atomic = helmOptions.atomic
+	rel, err := upgrade.Run(release.Name, b.chart, values)
+	if err == nil {
+		return rel, nil
+	}
+	err = fmt.Errorf("unable to upgrade release %s/%s: %w", release.Namespace, release.Name, err)
+	if helmOptions.failurePolicy != FailurePolicyReinstall || apierrors.IsForbidden(err) {
+		return nil, err
+	}
+	logrus.Infof("Uninstalling release %s/%s to reinstall it after failed upgrade: %s", release.Namespace, release.Name, err)
+	if _, uninstallErr := action.NewUninstall(cfg).Run(release.Name); uninstallErr != nil {
+		return nil, fmt.Errorf("%s; unable to uninstall release to reinstall it: %w", err, uninstallErr)
+	}
+	return b.install(cfg, release, values, true)
+}
+
+func (b *helmSDKBackend) install(cfg *action.Configuration, release Release, values map[string]interface{}, replace bool) (*rspb.Release, error) {
+	helmOptions := getHelmOptions(release)
+	logrus.Infof("Installing release %s/%s", release.Namespace, release.Name)
+	install := action.NewInstall(cfg)
+	install.ReleaseName = release.Name
+	install.Namespace = release.Namespace
+	install.Replace = replace
+	install.Timeout = helmOptions.timeout
+	install.Wait = helmOptions.wait
+	install.Atomic = helmOptions.atomic
+	rel, err := install.Run(b.chart, values)
+	if err != nil {
+		return nil, fmt.Errorf("unable to install release %s/%s: %w", release.Namespace, release.Name, err)
+	}
+	return rel, nil
+}
+
+// helmSDKOptions are the options passed to Helm actions based on the HelmOptions of a release
+type helmSDKOptions struct {
+	timeout       time.Duration
+	wait          bool
+	atomic        bool
+	failurePolicy string
+}
+
+// getHelmOptions returns the options passed to Helm actions for a release, defaulting to the defaults of the Helm CLI
+func getHelmOptions(release Release) helmSDKOptions {
+	helmOptions := helmSDKOptions{
+		timeout:       DefaultHelmSDKTimeout,
+		failurePolicy: FailurePolicyAbort,
+	}
+	if release.HelmOptions == nil {
+		return helmOptions
+	}
+	if release.HelmOptions.Timeout != nil {
+		helmOptions.timeout = release.HelmOptions.Timeout.Duration
+	}
+	// note: atomic implies wait in the Helm CLI
+	helmOptions.atomic = release.HelmOptions.Atomic
+	helmOptions.wait = release.HelmOptions.Wait || release.HelmOptions.Atomic
+	if len(release.HelmOptions.FailurePolicy) > 0 {
+		helmOptions.failurePolicy = release.HelmOptions.FailurePolicy
+	}
+	return helmOptions
+}
+
+// isUpToDate returns whether the last revision of a release was a successful deployment of this chart with the provided values
+func (b *helmSDKBackend) isUpToDate(last *rspb.Release, values map[string]interface{}) bool {
+	if last.Info.Status != rspb.StatusDeployed {
+		return false
+	}
+	if last.Chart == nil || last.Chart.Metadata == nil || last.Chart.Metadata.Version != b.chart.Metadata.Version {
+		return false
+	}
+	if len(last.Config) == 0 && len(values) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(last.Config, values)
+}
+
+// getDigest returns a digest that identifies the desired state of a release
+func (b *helmSDKBackend) getDigest(release Release) string {
+	helmOptionsBytes, _ := json.Marshal(release.HelmOptions)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(b.chart.Metadata.Version+"\n"+release.ServiceAccountName+"\n"+string(helmOptionsBytes)+"\n"+release.ValuesContent)))
+}
+
+// getImpersonateUser returns the user that Helm operations on a release should be performed as, if any
+func (b *helmSDKBackend) getImpersonateUser(release Release) string {
+	if len(release.ServiceAccountName) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("system:serviceaccount:%s:%s", b.opts.SystemNamespace, release.ServiceAccountName)
+}
+
+func (b *helmSDKBackend) getStatus(key string) (helmSDKReleaseStatus, bool) {
+	b.statusLock.RLock()
+	defer b.statusLock.RUnlock()
+	status, ok := b.statuses[key]
+	return status, ok
+}
+
+func (b *helmSDKBackend) setStatus(release Release, state, message, digest string) {
+	key := releaseKey(release)
+	status := helmSDKReleaseStatus{
+		ReleaseStatus: ReleaseStatus{
+			State:   state,
+			Message: message,
+		},
+		digest: digest,
+	}
+	b.statusLock.Lock()
+	currStatus, ok := b.statuses[key]
+	b.statuses[key] = status
+	b.statusLock.Unlock()
+	if ok && currStatus == status {
+		return
+	}
+	if state == ReleaseFailedState {
+		logrus.Errorf("Release %s failed to deploy: %s", key, message)
+	}
+	for _, handler := range b.onStatusChange {
+		handler(release)
+	}
+}
+
+func (b *helmSDKBackend) getLock(key string) *sync.Mutex {
+	b.locksLock.Lock()
+	defer b.locksLock.Unlock()
+	lock, ok := b.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		b.locks[key] = lock
+	}
+	return lock
+}
+
+// releaseLock removes the lock of a release that is no longer desired and unlocks it
+//
+// note: since the Applyinator never processes the same release on multiple workers at the same time, the only operation
+// that can still hold a removed lock is a single worker, which is done before the next worker obtains a new lock
+func (b *helmSDKBackend) releaseLock(key string, lock *sync.Mutex) {
+	b.locksLock.Lock()
+	delete(b.locks, key)
+	b.locksLock.Unlock()
+	lock.Unlock()
+}
+
+// releaseKey returns the key used to identify a release
+func releaseKey(release Release) string {
+	return fmt.Sprintf("%s/%s", release.Namespace, release.Name)
+}
+
+// getHistory returns all revisions of a release sorted from newest to oldest
+func getHistory(cfg *action.Configuration, name string) ([]*rspb.Release, error) {
+	history, err := cfg.Releases.History(name)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get history of release %s: %s", name, err)
+	}
+	releaseutil.Reverse(history, releaseutil.SortByRevision)
+	return history, nil
+}
+
+// hasDeployedRevision returns whether any revision of a release was successfully deployed, which means it can be upgraded
+func hasDeployedRevision(history []*rspb.Release) bool {
+	for _, rel := range history {
+		if rel.Info == nil {
+			continue
+		}
+		switch rel.Info.Status {
+		case rspb.StatusDeployed, rspb.StatusSuperseded, rspb.StatusPendingUpgrade, rspb.StatusPendingRollback:
+			return true
+		}
+	}
+	return false
+}
+
+// loadChart loads the base64 tgz contents of a Helm chart into memory
+func loadChart(chartContent string) (*chart.Chart, error) {
+	tgzChartBytes, err := base64.StdEncoding.DecodeString(chartContent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode chart content: %s", err)
+	}
+	chart, err := loader.LoadArchive(bytes.NewReader(tgzChartBytes))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load chart from chart content: %s", err)
+	}
+	return chart, nil
+}
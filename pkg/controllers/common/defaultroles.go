@@ -0,0 +1,39 @@
+package common
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultReleaseRolesOptions are options that can be provided to create default Roles in the Project Release Namespace for each subject role (tier)
+// if the deployed chart does not provide its own Roles marked with 'helm.cattle.io/project-helm-chart-role' and
+// 'helm.cattle.io/project-helm-chart-role-aggregate-from' for that tier
+type DefaultReleaseRolesOptions struct {
+	// Roles are the rules of the default Role created in the Project Release Namespace for each tier (e.g. admin, edit, view, or any tier in ClusterRoleTiers)
+	Roles map[string][]rbacv1.PolicyRule `json:"roles,omitempty"`
+}
+
+// Validate validates that every tier provided in the DefaultReleaseRolesOptions is a tier that this operator was started with
+func (o DefaultReleaseRolesOptions) Validate(opts Options) error {
+	defaultClusterRoles := GetDefaultClusterRoles(opts)
+	for subjectRole, rules := range o.Roles {
+		if _, ok := defaultClusterRoles[subjectRole]; !ok {
+			return fmt.Errorf("invalid default release role for tier %s: must be admin, edit, view, or a tier provided in ClusterRoleTiers", subjectRole)
+		}
+		if len(rules) == 0 {
+			return fmt.Errorf("invalid default release role for tier %s: must provide at least one rule", subjectRole)
+		}
+	}
+	return nil
+}
+
+// LoadDefaultReleaseRolesOptionsFromFile unmarshalls the struct found at the file to YAML and reads it into memory
+func LoadDefaultReleaseRolesOptionsFromFile(path string) (DefaultReleaseRolesOptions, error) {
+	var defaultReleaseRolesOptions DefaultReleaseRolesOptions
+	err := loadFromFile(path, func(data []byte) error {
+		return yaml.UnmarshalStrict(data, &defaultReleaseRolesOptions)
+	})
+	return defaultReleaseRolesOptions, err
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// loadFromFile reads the file found at the path (relative to the working directory) and passes its contents to unmarshal
+// If the file does not exist, unmarshal is never called since we just assume the default is used
+func loadFromFile(path string, unmarshal func(data []byte) error) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	abspath := filepath.Join(wd, path)
+	_, err = os.Stat(abspath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// we just assume the default is used
+			err = nil
+		}
+		return err
+	}
+	data, err := os.ReadFile(abspath)
+	if err != nil {
+		return err
+	}
+	return unmarshal(data)
+}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFromFile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := os.WriteFile("release-permissions.yaml", []byte("rules:\n- apiGroups: [\"apps\"]\n  resources: [\"deployments\"]\n  verbs: [\"get\"]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("invalid.yaml", []byte("unknownField: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	releasePermissionsOptions, err := LoadReleasePermissionsOptionsFromFile("release-permissions.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releasePermissionsOptions.Rules) != 1 {
+		t.Errorf("expected options to be loaded from file, found %v", releasePermissionsOptions)
+	}
+
+	releasePermissionsOptions, err = LoadReleasePermissionsOptionsFromFile("missing.yaml")
+	if err != nil {
+		t.Fatalf("expected a missing file to be ignored, found error: %s", err)
+	}
+	if !reflect.DeepEqual(releasePermissionsOptions, ReleasePermissionsOptions{}) {
+		t.Errorf("expected default options for a missing file, found %v", releasePermissionsOptions)
+	}
+
+	if _, err := LoadReleasePermissionsOptionsFromFile("invalid.yaml"); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+	if _, err := LoadHardeningOptionsFromFile("invalid.yaml"); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+	valuesOverride, err := LoadValuesOverrideFromFile("invalid.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valuesOverride["unknownField"] != true {
+		t.Errorf("expected values override to be loaded from file, found %v", valuesOverride)
+	}
+}
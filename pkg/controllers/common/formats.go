@@ -1,8 +1,87 @@
 package common
 
+import (
+	"fmt"
+
+	"github.com/rancher/helm-project-operator/pkg/naming"
+)
+
 const (
-	// ProjectRegistrationNamespaceFmt is the format used in order to create project registration namespaces if ProjectLabel is provided
-	// If SystemProjectLabel is also provided, the project release namespace will be this namespace with `-<ReleaseName>` suffixed, where
-	// ReleaseName is provided by the Project Operator that implements Helm Project Operator
-	ProjectRegistrationNamespaceFmt = "cattle-project-%s"
+	// DefaultProjectRegistrationNamespaceTemplate is the template used in order to create project registration namespaces if ProjectLabel is provided
+	// and no ProjectRegistrationNamespaceTemplate is configured
+	DefaultProjectRegistrationNamespaceTemplate = "cattle-project-{{ .ProjectID }}"
+
+	// DefaultProjectReleaseNamespaceTemplate is the template used in order to create dedicated project release namespaces if both ProjectLabel and
+	// ProjectReleaseLabelValue are provided and no ProjectReleaseNamespaceTemplate is configured. The namespace will be named after the Helm release,
+	// which is the name of the ProjectHelmChart with `-<ReleaseName>` suffixed, where ReleaseName is provided by the Project Operator that implements
+	// Helm Project Operator
+	DefaultProjectReleaseNamespaceTemplate = "{{ .ReleaseName }}"
+
+	// DefaultSharedProjectReleaseNamespaceTemplate is the template used in order to create the single Project Release Namespace shared by all
+	// ProjectHelmCharts in a project (across all Project Operators) if SharedProjectReleaseNamespace is provided and no ProjectReleaseNamespaceTemplate
+	// is configured. This namespace will be the project registration namespace with `-release` suffixed
+	DefaultSharedProjectReleaseNamespaceTemplate = "{{ .ProjectRegistrationNamespace }}-release"
 )
+
+// GetProjectRegistrationNamespaceTemplate returns the template used to render the names of Project Registration Namespaces
+func (opts RuntimeOptions) GetProjectRegistrationNamespaceTemplate() string {
+	if len(opts.ProjectRegistrationNamespaceTemplate) > 0 {
+		return opts.ProjectRegistrationNamespaceTemplate
+	}
+	return DefaultProjectRegistrationNamespaceTemplate
+}
+
+// GetProjectReleaseNamespaceTemplate returns the template used to render the names of Project Release Namespaces
+func (opts RuntimeOptions) GetProjectReleaseNamespaceTemplate() string {
+	switch {
+	case len(opts.ProjectReleaseNamespaceTemplate) > 0:
+		return opts.ProjectReleaseNamespaceTemplate
+	case opts.SharedProjectReleaseNamespace:
+		return DefaultSharedProjectReleaseNamespaceTemplate
+	default:
+		return DefaultProjectReleaseNamespaceTemplate
+	}
+}
+
+// validateNamingTemplates validates that the naming templates render valid and unique names for Project Registration Namespaces and Project Release Namespaces
+func (opts RuntimeOptions) validateNamingTemplates() error {
+	registrationNamespaceTemplates := append([]string{opts.GetProjectRegistrationNamespaceTemplate()}, opts.LegacyProjectRegistrationNamespaceTemplates...)
+	for _, registrationNamespaceTemplate := range registrationNamespaceTemplates {
+		err := naming.ValidateTemplate(registrationNamespaceTemplate,
+			naming.ProjectRegistrationNamespaceData{ProjectID: "p-abcde"},
+			naming.ProjectRegistrationNamespaceData{ProjectID: "p-fghij"},
+		)
+		if err != nil {
+			return fmt.Errorf("invalid project registration namespace template: %s", err)
+		}
+	}
+	releaseNamespaceTemplates := append([]string{opts.GetProjectReleaseNamespaceTemplate()}, opts.LegacyProjectReleaseNamespaceTemplates...)
+	for _, releaseNamespaceTemplate := range releaseNamespaceTemplates {
+		var err error
+		if opts.SharedProjectReleaseNamespace {
+			// shared namespaces must only be unique per project
+			err = naming.ValidateTemplate(releaseNamespaceTemplate,
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-abcde"},
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-fghij"},
+			)
+		} else if releaseNamespaceTemplate == DefaultProjectReleaseNamespaceTemplate {
+			// the default template is only unique per release name, so ProjectHelmCharts with the same name in different projects
+			// render the same namespace; this is handled by only allowing one ProjectHelmChart to claim a release
+			err = naming.ValidateTemplate(releaseNamespaceTemplate,
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-abcde", ProjectHelmChart: "chart-a", ReleaseName: "chart-a-release"},
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-abcde", ProjectHelmChart: "chart-b", ReleaseName: "chart-b-release"},
+			)
+		} else {
+			// dedicated namespaces must be unique per release, including releases of ProjectHelmCharts with the same name in different projects
+			err = naming.ValidateTemplate(releaseNamespaceTemplate,
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-abcde", ProjectHelmChart: "chart-a", ReleaseName: "chart-a-release"},
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-abcde", ProjectHelmChart: "chart-b", ReleaseName: "chart-b-release"},
+				naming.ProjectReleaseNamespaceData{ProjectRegistrationNamespace: "cattle-project-p-fghij", ProjectHelmChart: "chart-a", ReleaseName: "chart-a-release"},
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid project release namespace template: %s", err)
+		}
+	}
+	return nil
+}
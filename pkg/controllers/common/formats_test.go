@@ -0,0 +1,55 @@
+package common
+
+import "testing"
+
+func TestValidateNamingTemplates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		opts      RuntimeOptions
+		expectErr bool
+	}{
+		{
+			name: "default templates",
+			opts: RuntimeOptions{},
+		},
+		{
+			name: "default shared template",
+			opts: RuntimeOptions{SharedProjectReleaseNamespace: true},
+		},
+		{
+			name: "release namespace template unique across projects",
+			opts: RuntimeOptions{ProjectReleaseNamespaceTemplate: "{{ .ProjectRegistrationNamespace }}-{{ .ProjectHelmChart }}"},
+		},
+		{
+			name:      "release namespace template that collides across projects",
+			opts:      RuntimeOptions{ProjectReleaseNamespaceTemplate: "{{ .ProjectHelmChart }}-release"},
+			expectErr: true,
+		},
+		{
+			name:      "legacy release namespace template that collides across projects",
+			opts:      RuntimeOptions{LegacyProjectReleaseNamespaceTemplates: []string{"{{ .ReleaseName }}-legacy"}},
+			expectErr: true,
+		},
+		{
+			name:      "shared release namespace template that collides across projects",
+			opts:      RuntimeOptions{SharedProjectReleaseNamespace: true, ProjectReleaseNamespaceTemplate: "shared-release"},
+			expectErr: true,
+		},
+		{
+			name:      "registration namespace template that collides across projects",
+			opts:      RuntimeOptions{ProjectRegistrationNamespaceTemplate: "cattle-project"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.validateNamingTemplates()
+			if tc.expectErr && err == nil {
+				t.Fatalf("expected naming templates to be rejected")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("expected naming templates to be valid: %s", err)
+			}
+		})
+	}
+}
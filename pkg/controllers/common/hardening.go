@@ -1,9 +1,6 @@
 package common
 
 import (
-	"os"
-	"path/filepath"
-
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -32,22 +29,8 @@ type DefaultNetworkPolicyOptions networkingv1.NetworkPolicySpec
 // LoadHardeningOptionsFromFile unmarshalls the struct found at the file to YAML and reads it into memory
 func LoadHardeningOptionsFromFile(path string) (HardeningOptions, error) {
 	var hardeningOptions HardeningOptions
-	wd, err := os.Getwd()
-	if err != nil {
-		return HardeningOptions{}, err
-	}
-	abspath := filepath.Join(wd, path)
-	_, err = os.Stat(abspath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// we just assume the default is used
-			err = nil
-		}
-		return HardeningOptions{}, err
-	}
-	hardeningOptionsBytes, err := os.ReadFile(abspath)
-	if err != nil {
-		return hardeningOptions, err
-	}
-	return hardeningOptions, yaml.UnmarshalStrict(hardeningOptionsBytes, &hardeningOptions)
+	err := loadFromFile(path, func(data []byte) error {
+		return yaml.UnmarshalStrict(data, &hardeningOptions)
+	})
+	return hardeningOptions, err
 }
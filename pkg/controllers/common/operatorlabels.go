@@ -42,14 +42,50 @@ func GetCommonLabels(projectID string) map[string]string {
 	return labels
 }
 
+// Generated Names
+
+const (
+	// HelmProjectOperatorOriginalNameAnnotation records the original name of a resource created by this operator whose name had to be
+	// truncated (with a stable hash suffix) to be a valid name, e.g. due to a long project ID or ProjectHelmChart name
+	HelmProjectOperatorOriginalNameAnnotation = "helm.cattle.io/original-name"
+)
+
+// AddOriginalNameAnnotation adds the HelmProjectOperatorOriginalNameAnnotation to the provided annotations if the name of the resource
+// differs from the original name it was generated from
+func AddOriginalNameAnnotation(annotations map[string]string, originalName, name string) map[string]string {
+	if originalName == name {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[HelmProjectOperatorOriginalNameAnnotation] = originalName
+	return annotations
+}
+
 // Project Namespaces
 
 const (
 	// HelmProjectOperatedNamespaceOrphanedLabel marks all auto-generated namespaces that no longer have resources tracked
 	// by this operator; if a namespace has this label, it is safe to delete
 	HelmProjectOperatedNamespaceOrphanedLabel = "helm.cattle.io/helm-project-operator-orphaned"
+
+	// HelmProjectOperatedNamespaceOrphanedSinceAnnotation records the time (in RFC3339 format) at which the orphaned label was first
+	// observed on a namespace by the garbage collector; it is used to determine when an orphaned namespace can be garbage collected
+	//
+	// Note: this may be later than the time at which the orphaned label was added if garbage collection was not enabled at the time
+	HelmProjectOperatedNamespaceOrphanedSinceAnnotation = "helm.cattle.io/helm-project-operator-orphaned-since"
 )
 
+// IsOrphanedNamespace returns whether a namespace has been marked as orphaned by this operator
+func IsOrphanedNamespace(labels map[string]string) bool {
+	if labels == nil {
+		return false
+	}
+	value, ok := labels[HelmProjectOperatedNamespaceOrphanedLabel]
+	return ok && value == "true"
+}
+
 // GetProjectNamespaceLabels returns the labels to be added to all Project Namespaces
 func GetProjectNamespaceLabels(projectID, projectLabel, projectLabelValue string, isOrphaned bool) map[string]string {
 	labels := GetCommonLabels(projectID)
@@ -75,6 +111,11 @@ func GetProjectNamespaceAnnotations(projectID, projectLabel, clusterID string) m
 // Helm Resources (HelmCharts and HelmReleases)
 
 const (
+	// HelmProjectOperatorReleaseClaimAnnotation is an annotation that records the ProjectHelmChart (in the format <namespace>/<name>)
+	// that owns the release deployed by a HelmChart, HelmRelease, or any other resource created by the deployment backend.
+	// This is used to ensure that the owner of a release remains stable if multiple ProjectHelmCharts target the same release.
+	HelmProjectOperatorReleaseClaimAnnotation = "helm.cattle.io/project-helm-chart-claim"
+
 	// HelmProjectOperatorHelmAPIVersionLabel is a label that identifies the HelmAPIVersion that a HelmChart or HelmRelease is tied to
 	// This is used to identify whether a HelmChart or HelmRelease should be deleted from the cluster on uninstall
 	HelmProjectOperatorHelmAPIVersionLabel = "helm.cattle.io/helm-api-version"
@@ -94,3 +135,58 @@ const (
 	// The value of this label will be the release name of the Helm chart, which will be used to identify which ProjectHelmChart's enqueue should resynchronize this.
 	HelmProjectOperatorProjectHelmChartRoleBindingLabel = "helm.cattle.io/project-helm-chart-role-binding"
 )
+
+// RBAC Reports (ConfigMaps created in the Project Registration Namespace to report the RBAC computed for a ProjectHelmChart)
+
+const (
+	// HelmProjectOperatorRBACReportLabel is a label that identifies a ConfigMap as one that reports the RBAC computed for a ProjectHelmChart
+	// The value of this label will be the name of the ProjectHelmChart that the report is for.
+	HelmProjectOperatorRBACReportLabel = "helm.cattle.io/project-helm-chart-rbac-report"
+)
+
+// Values Revisions (Secrets created in the system namespace to record the history of spec.values)
+
+const (
+	// HelmProjectOperatorValuesRevisionLabel is a label that identifies a Secret as one that stores a revision of the spec.values of a ProjectHelmChart
+	// The value of this label will be the release name of the Helm chart, which will be used to identify which ProjectHelmChart the revision belongs to.
+	HelmProjectOperatorValuesRevisionLabel = "helm.cattle.io/project-helm-chart-values-revision"
+
+	// HelmProjectOperatorValuesRevisionAnnotation is an annotation that contains the revision number of a values revision Secret
+	HelmProjectOperatorValuesRevisionAnnotation = "helm.cattle.io/values-revision"
+
+	// HelmProjectOperatorValuesDigestAnnotation is an annotation that contains the digest of the values stored in a values revision Secret
+	HelmProjectOperatorValuesDigestAnnotation = "helm.cattle.io/values-digest"
+)
+
+// Synced Secrets and ConfigMaps (copied from the Project Registration Namespace into the Project Release Namespace)
+
+const (
+	// HelmProjectOperatorSyncedFromAnnotation is an annotation on a Secret or ConfigMap in the Project Release Namespace that identifies
+	// the Secret or ConfigMap (in the format <namespace>/<name>) in the Project Registration Namespace that it was copied from
+	HelmProjectOperatorSyncedFromAnnotation = "helm.cattle.io/project-helm-chart-synced-from"
+)
+
+const (
+	// helmDomain is the prefix of the labels and annotations used by Helm to track the resources of a release
+	helmDomain = "meta.helm.sh/"
+
+	// HelmReleaseNamespaceAnnotation is the annotation added by Helm to every resource deployed by a release that identifies the namespace of the release
+	HelmReleaseNamespaceAnnotation = helmDomain + "release-namespace"
+)
+
+// GetSyncedLabels returns the labels to be added to a Secret or ConfigMap synced into the Project Release Namespace
+// Note: labels managed by the operator or by Helm (including the sync label) are never copied to ensure that synced objects
+// are never synced again and cannot be mistaken for resources managed by the operator or by a Helm release
+func GetSyncedLabels(projectID string, sourceLabels map[string]string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range sourceLabels {
+		if strings.HasPrefix(k, operatorDomain) || strings.HasPrefix(k, helmDomain) {
+			continue
+		}
+		labels[k] = v
+	}
+	for k, v := range GetCommonLabels(projectID) {
+		labels[k] = v
+	}
+	return labels
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSyncedLabels(t *testing.T) {
+	sourceLabels := map[string]string{
+		"team":                   "payments",
+		"app.kubernetes.io/name": "example",
+		HelmProjectOperatorProjectHelmChartSyncLabel: "project-monitoring",
+		HelmProjectOperatedLabel:                     "true",
+		HelmProjectOperatorProjectLabel:              "p-other",
+		"meta.helm.sh/release-name":                  "project-monitoring",
+	}
+	expected := map[string]string{
+		"team":                          "payments",
+		"app.kubernetes.io/name":        "example",
+		HelmProjectOperatedLabel:        "true",
+		HelmProjectOperatorProjectLabel: "p-example",
+	}
+	if labels := GetSyncedLabels("p-example", sourceLabels); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected synced labels %v, found %v", expected, labels)
+	}
+}
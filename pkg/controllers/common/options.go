@@ -1,17 +1,24 @@
 package common
 
 import (
+	"fmt"
+
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Options defines options that can be set on initializing the HelmProjectOperator
 type Options struct {
 	RuntimeOptions
 	OperatorOptions
+
+	// systemNamespaceSelector is the SystemNamespaceSelector parsed on validating the Options
+	// Note: this cannot be stored on the RuntimeOptions since every field of the RuntimeOptions is parsed as a flag
+	systemNamespaceSelector labels.Selector
 }
 
 // Validate validates the provided Options
-func (opts Options) Validate() error {
+func (opts *Options) Validate() error {
 	if err := opts.OperatorOptions.Validate(); err != nil {
 		return err
 	}
@@ -20,16 +27,25 @@ func (opts Options) Validate() error {
 		return err
 	}
 
+	if len(opts.SystemNamespaceSelector) > 0 {
+		systemNamespaceSelector, err := labels.Parse(opts.SystemNamespaceSelector)
+		if err != nil {
+			return fmt.Errorf("invalid system namespace selector %q: %s", opts.SystemNamespaceSelector, err)
+		}
+		opts.systemNamespaceSelector = systemNamespaceSelector
+		logrus.Infof("Assuming namespaces that match the label selector %q are also system namespaces", opts.SystemNamespaceSelector)
+	}
+
 	// Cross option checks
 
 	if opts.Singleton {
 		logrus.Infof("Note: Operator only supports a single ProjectHelmChart per project registration namespace")
-		if len(opts.ProjectLabel) == 0 {
+		if len(opts.ProjectLabel) == 0 && !opts.ProjectCRD {
 			logrus.Warnf("It is only recommended to run a singleton Project Operator when --project-label is provided (currently not set). The current configuration of this operator would only allow a single ProjectHelmChart to be managed by this Operator.")
 		}
 	}
 
-	for subjectRole, defaultClusterRoleName := range GetDefaultClusterRoles(opts) {
+	for subjectRole, defaultClusterRoleName := range GetDefaultClusterRoles(*opts) {
 		logrus.Infof("RoleBindings will automatically be created for Roles in the Project Release Namespace marked with '%s': '<helm-release>' "+
 			"and '%s': '%s' based on ClusterRoleBindings or RoleBindings in the Project Registration namespace tied to ClusterRole %s",
 			HelmProjectOperatorProjectHelmChartRoleLabel, HelmProjectOperatorProjectHelmChartRoleAggregateFromLabel, subjectRole, defaultClusterRoleName,
@@ -38,3 +54,8 @@ func (opts Options) Validate() error {
 
 	return nil
 }
+
+// GetSystemNamespaceSelector returns the SystemNamespaceSelector parsed on validating the Options, which is nil if none was provided
+func (opts Options) GetSystemNamespaceSelector() labels.Selector {
+	return opts.systemNamespaceSelector
+}
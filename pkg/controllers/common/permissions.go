@@ -0,0 +1,70 @@
+package common
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// releasePermissionVerbs are the verbs granted on each resource in the DefaultReleasePermissionRules
+	releasePermissionVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+	// DefaultReleasePermissionRules are the rules granted to the ServiceAccount that deploys a release
+	// within the Project Release Namespace and every target namespace of the ProjectHelmChart
+	//
+	// These only cover the workloads, configuration, and RBAC resources that most charts deploy; any other
+	// resources must be granted via the Rules of the ReleasePermissionsOptions
+	DefaultReleasePermissionRules = []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "secrets", "services", "serviceaccounts", "persistentvolumeclaims", "pods"},
+			Verbs:     releasePermissionVerbs,
+		},
+		{
+			APIGroups: []string{"apps"},
+			Resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"},
+			Verbs:     releasePermissionVerbs,
+		},
+		{
+			APIGroups: []string{"batch"},
+			Resources: []string{"jobs", "cronjobs"},
+			Verbs:     releasePermissionVerbs,
+		},
+		{
+			APIGroups: []string{rbacv1.GroupName},
+			Resources: []string{"roles", "rolebindings"},
+			Verbs:     releasePermissionVerbs,
+		},
+	}
+
+	// AllReleasePermissionRules are the rules granted instead of the DefaultReleasePermissionRules if
+	// AllowAllResources is set in the ReleasePermissionsOptions
+	AllReleasePermissionRules = []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{rbacv1.APIGroupAll},
+			Resources: []string{rbacv1.ResourceAll},
+			Verbs:     []string{rbacv1.VerbAll},
+		},
+	}
+)
+
+// ReleasePermissionsOptions are options that can be provided to extend the permissions granted to the ServiceAccount
+// that deploys the release on behalf of each ProjectHelmChart. To enable this, specify ScopeReleasePermissions in the RuntimeOptions.
+type ReleasePermissionsOptions struct {
+	// AllowAllResources grants all verbs on all resources within the Project Release Namespace and every target namespace
+	// instead of the DefaultReleasePermissionRules
+	AllowAllResources bool `json:"allowAllResources,omitempty"`
+	// Rules are additional rules to grant within the Project Release Namespace and every target namespace
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// ClusterRules are rules to grant across the cluster, which are required if the chart deploys cluster-scoped resources
+	ClusterRules []rbacv1.PolicyRule `json:"clusterRules,omitempty"`
+}
+
+// LoadReleasePermissionsOptionsFromFile unmarshalls the struct found at the file to YAML and reads it into memory
+func LoadReleasePermissionsOptionsFromFile(path string) (ReleasePermissionsOptions, error) {
+	var releasePermissionsOptions ReleasePermissionsOptions
+	err := loadFromFile(path, func(data []byte) error {
+		return yaml.UnmarshalStrict(data, &releasePermissionsOptions)
+	})
+	return releasePermissionsOptions, err
+}
@@ -0,0 +1,25 @@
+package common
+
+// GetProjectLabels returns the labels that identify projects at each level of the project hierarchy, ordered from the innermost
+// (ProjectLabel) to the outermost level
+func (opts RuntimeOptions) GetProjectLabels() []string {
+	if len(opts.ProjectLabel) == 0 {
+		return nil
+	}
+	return append([]string{opts.ProjectLabel}, opts.ParentProjectLabels...)
+}
+
+// GetProjectLabelAndID returns the label that identifies the innermost project found on the provided labels of a namespace
+// along with the projectID of that project
+func (opts RuntimeOptions) GetProjectLabelAndID(labels map[string]string) (string, string, bool) {
+	if labels == nil {
+		return "", "", false
+	}
+	for _, projectLabel := range opts.GetProjectLabels() {
+		projectID, ok := labels[projectLabel]
+		if ok {
+			return projectLabel, projectID, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,54 @@
+package common
+
+import "testing"
+
+func TestGetProjectLabelAndID(t *testing.T) {
+	opts := RuntimeOptions{
+		ProjectLabel:        "field.cattle.io/projectId",
+		ParentProjectLabels: []string{"example.com/team", "example.com/org"},
+	}
+	testCases := []struct {
+		name                 string
+		labels               map[string]string
+		expectedProjectLabel string
+		expectedProjectID    string
+		expectedOK           bool
+	}{
+		{
+			name: "no labels",
+		},
+		{
+			name:   "no project labels",
+			labels: map[string]string{"team": "payments"},
+		},
+		{
+			name:                 "innermost level",
+			labels:               map[string]string{"field.cattle.io/projectId": "p-example", "example.com/org": "acme"},
+			expectedProjectLabel: "field.cattle.io/projectId",
+			expectedProjectID:    "p-example",
+			expectedOK:           true,
+		},
+		{
+			name:                 "parent levels only",
+			labels:               map[string]string{"example.com/team": "payments", "example.com/org": "acme"},
+			expectedProjectLabel: "example.com/team",
+			expectedProjectID:    "payments",
+			expectedOK:           true,
+		},
+		{
+			name:                 "outermost level only",
+			labels:               map[string]string{"example.com/org": "acme"},
+			expectedProjectLabel: "example.com/org",
+			expectedProjectID:    "acme",
+			expectedOK:           true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectLabel, projectID, ok := opts.GetProjectLabelAndID(tc.labels)
+			if projectLabel != tc.expectedProjectLabel || projectID != tc.expectedProjectID || ok != tc.expectedOK {
+				t.Errorf("expected (%s, %s, %t), found (%s, %s, %t)", tc.expectedProjectLabel, tc.expectedProjectID, tc.expectedOK, projectLabel, projectID, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"strings"
+)
+
+const (
+	// PropagateMetadataFromAll propagates metadata from both the Project Registration Namespace and the ProjectHelmChart
+	PropagateMetadataFromAll = "all"
+
+	// PropagateMetadataFromProjectHelmChart only propagates metadata from the ProjectHelmChart
+	PropagateMetadataFromProjectHelmChart = "project-helm-chart"
+
+	// PropagateMetadataFromProjectRegistrationNamespace only propagates metadata from the Project Registration Namespace
+	PropagateMetadataFromProjectRegistrationNamespace = "project-registration-namespace"
+
+	// operatorDomain is the prefix of all labels and annotations used by the operator
+	operatorDomain = "helm.cattle.io/"
+)
+
+// ShouldPropagateMetadataFromProjectHelmChart returns whether metadata should be propagated from ProjectHelmCharts
+func (opts RuntimeOptions) ShouldPropagateMetadataFromProjectHelmChart() bool {
+	return opts.PropagateMetadataFrom != PropagateMetadataFromProjectRegistrationNamespace
+}
+
+// ShouldPropagateMetadataFromProjectRegistrationNamespace returns whether metadata should be propagated from Project Registration Namespaces
+func (opts RuntimeOptions) ShouldPropagateMetadataFromProjectRegistrationNamespace() bool {
+	return opts.PropagateMetadataFrom != PropagateMetadataFromProjectHelmChart
+}
+
+// GetPropagatedMetadata returns the entries of the source labels or annotations whose keys match the provided allowlist
+// An entry in the allowlist that ends with '*' matches all keys with that prefix
+//
+// Note: labels and annotations in the domain of the operator (helm.cattle.io) are never propagated
+func GetPropagatedMetadata(source map[string]string, allowlist []string) map[string]string {
+	propagated := map[string]string{}
+	for k, v := range source {
+		if strings.HasPrefix(k, operatorDomain) {
+			continue
+		}
+		for _, allowed := range allowlist {
+			if k == allowed || (strings.HasSuffix(allowed, "*") && strings.HasPrefix(k, strings.TrimSuffix(allowed, "*"))) {
+				propagated[k] = v
+				break
+			}
+		}
+	}
+	return propagated
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ReleaseNamespaceResourcesOptions are options that can be provided to create a ResourceQuota and LimitRange in every Project Release Namespace
+// created by this Project Operator, along with the bounds within which ProjectHelmCharts are allowed to override them via spec.releaseNamespaceResources
+type ReleaseNamespaceResourcesOptions struct {
+	// ResourceQuota is the spec of the ResourceQuota created in every Project Release Namespace
+	ResourceQuota *corev1.ResourceQuotaSpec `json:"resourceQuota,omitempty"`
+	// LimitRange is the spec of the LimitRange created in every Project Release Namespace
+	LimitRange *corev1.LimitRangeSpec `json:"limitRange,omitempty"`
+	// MaxResourceQuota is the maximum value of each hard limit that a ProjectHelmChart can set on the ResourceQuota
+	// ProjectHelmCharts cannot set hard limits on resources that are not listed here
+	MaxResourceQuota corev1.ResourceList `json:"maxResourceQuota,omitempty"`
+	// MaxContainerLimits is the maximum value of each container limit or request that a ProjectHelmChart can set on the LimitRange
+	// ProjectHelmCharts cannot set container limits or requests on resources that are not listed here
+	MaxContainerLimits corev1.ResourceList `json:"maxContainerLimits,omitempty"`
+}
+
+// LoadReleaseNamespaceResourcesOptionsFromFile unmarshalls the struct found at the file to YAML and reads it into memory
+func LoadReleaseNamespaceResourcesOptionsFromFile(path string) (ReleaseNamespaceResourcesOptions, error) {
+	var releaseNamespaceResourcesOptions ReleaseNamespaceResourcesOptions
+	err := loadFromFile(path, func(data []byte) error {
+		return yaml.UnmarshalStrict(data, &releaseNamespaceResourcesOptions)
+	})
+	return releaseNamespaceResourcesOptions, err
+}
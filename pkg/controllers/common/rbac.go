@@ -1,10 +1,13 @@
 package common
 
 import (
+	"sort"
+
 	rbacv1 "k8s.io/api/rbac/v1"
 )
 
-// GetDefaultClusterRoles returns the default ClusterRoles that this operator was started with
+// GetDefaultClusterRoles returns the default ClusterRoles that this operator was started with, keyed by the name of the subject role (tier)
+// that Roles in the Project Release Namespace can aggregate from (e.g. admin, edit, view, or any tier provided in ClusterRoleTiers)
 func GetDefaultClusterRoles(opts Options) map[string]string {
 	clusterRoles := make(map[string]string)
 	if len(opts.AdminClusterRole) > 0 {
@@ -16,18 +19,27 @@ func GetDefaultClusterRoles(opts Options) map[string]string {
 	if len(opts.ViewClusterRole) > 0 {
 		clusterRoles["view"] = opts.ViewClusterRole
 	}
+	for subjectRole, clusterRole := range opts.ClusterRoleTiers {
+		if len(clusterRole) > 0 {
+			clusterRoles[subjectRole] = clusterRole
+		}
+	}
 	return clusterRoles
 }
 
-// IsDefaultClusterRoleRef returns whether the provided name is a default ClusterRole ref that this operator was
-// started with (e.g. the values provided to AdminClusterRole, EditClusterRole, or ViewClusterRole in RuntimeOptions)
-func IsDefaultClusterRoleRef(opts Options, roleRefName string) (string, bool) {
+// IsDefaultClusterRoleRef returns whether the provided name is a default ClusterRole ref that this operator was started with (e.g. the values
+// provided to AdminClusterRole, EditClusterRole, ViewClusterRole, or ClusterRoleTiers in RuntimeOptions) along with all subject roles tied to it
+//
+// Note: multiple subject roles can be tied to the same ClusterRole (e.g. an auditor tier that is also bound to view), so the returned subject roles are sorted
+func IsDefaultClusterRoleRef(opts Options, roleRefName string) ([]string, bool) {
+	var subjectRoles []string
 	for subjectRole, defaultClusterRoleName := range GetDefaultClusterRoles(opts) {
 		if roleRefName == defaultClusterRoleName {
-			return subjectRole, true
+			subjectRoles = append(subjectRoles, subjectRole)
 		}
 	}
-	return "", false
+	sort.Strings(subjectRoles)
+	return subjectRoles, len(subjectRoles) > 0
 }
 
 // FilterToUsersAndGroups returns a subset of the provided subjects that are only Users and Groups
@@ -51,3 +63,39 @@ func FilterToUsersAndGroups(subjects []rbacv1.Subject) []rbacv1.Subject {
 	}
 	return filtered
 }
+
+// ShouldAggregateServiceAccounts returns whether ServiceAccount subjects should be bound in the Project Release Namespace for the provided subject role (tier)
+func ShouldAggregateServiceAccounts(opts Options, subjectRole string) bool {
+	for _, tier := range opts.ServiceAccountSubjectTiers {
+		if tier == "*" || tier == subjectRole {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterToServiceAccounts returns a subset of the provided subjects that are only ServiceAccounts that live in one of the allowed namespaces
+// If a ServiceAccount subject does not specify a namespace, it is assumed to live in the provided bindingNamespace (if any)
+func FilterToServiceAccounts(subjects []rbacv1.Subject, bindingNamespace string, allowedNamespaces map[string]bool) []rbacv1.Subject {
+	var filtered []rbacv1.Subject
+	for _, subject := range subjects {
+		if subject.Kind != rbacv1.ServiceAccountKind || len(subject.APIGroup) != 0 {
+			continue
+		}
+		namespace := subject.Namespace
+		if len(namespace) == 0 {
+			namespace = bindingNamespace
+		}
+		if !allowedNamespaces[namespace] {
+			// only ServiceAccounts within the project can be bound
+			continue
+		}
+		// note: the namespace must be preserved since the binding will be created in a different namespace
+		filtered = append(filtered, rbacv1.Subject{
+			Kind:      subject.Kind,
+			Name:      subject.Name,
+			Namespace: namespace,
+		})
+	}
+	return filtered
+}
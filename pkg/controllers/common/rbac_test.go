@@ -0,0 +1,120 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestFilterToServiceAccounts(t *testing.T) {
+	allowedNamespaces := map[string]bool{
+		"cattle-project-p-example": true,
+		"p-example-app":            true,
+	}
+	testCases := []struct {
+		name             string
+		subjects         []rbacv1.Subject
+		bindingNamespace string
+		expected         []rbacv1.Subject
+	}{
+		{
+			name: "users and groups are filtered out",
+			subjects: []rbacv1.Subject{
+				{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "alice"},
+				{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "sre"},
+			},
+			bindingNamespace: "p-example-app",
+		},
+		{
+			name: "ServiceAccount in a project namespace",
+			subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "p-example-app", Name: "ci"},
+			},
+			expected: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "p-example-app", Name: "ci"},
+			},
+		},
+		{
+			name: "ServiceAccount without a namespace lives in the namespace of the binding",
+			subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "ci"},
+			},
+			bindingNamespace: "cattle-project-p-example",
+			expected: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "cattle-project-p-example", Name: "ci"},
+			},
+		},
+		{
+			name: "ServiceAccount without a namespace in a ClusterRoleBinding",
+			subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "ci"},
+			},
+		},
+		{
+			name: "ServiceAccount outside of the project",
+			subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "p-other-app", Name: "ci"},
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "kube-system", Name: "ci"},
+			},
+			bindingNamespace: "p-example-app",
+		},
+		{
+			name: "ServiceAccount with an API group",
+			subjects: []rbacv1.Subject{
+				{APIGroup: rbacv1.GroupName, Kind: rbacv1.ServiceAccountKind, Namespace: "p-example-app", Name: "ci"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := FilterToServiceAccounts(tc.subjects, tc.bindingNamespace, allowedNamespaces)
+			if !reflect.DeepEqual(filtered, tc.expected) {
+				t.Errorf("expected %v, found %v", tc.expected, filtered)
+			}
+		})
+	}
+}
+
+func TestShouldAggregateServiceAccounts(t *testing.T) {
+	testCases := []struct {
+		name                       string
+		serviceAccountSubjectTiers []string
+		subjectRole                string
+		expected                   bool
+	}{
+		{
+			name:        "no tiers",
+			subjectRole: "admin",
+		},
+		{
+			name:                       "listed tier",
+			serviceAccountSubjectTiers: []string{"edit", "admin"},
+			subjectRole:                "admin",
+			expected:                   true,
+		},
+		{
+			name:                       "unlisted tier",
+			serviceAccountSubjectTiers: []string{"edit"},
+			subjectRole:                "admin",
+		},
+		{
+			name:                       "all tiers",
+			serviceAccountSubjectTiers: []string{"*"},
+			subjectRole:                "monitoring-admin",
+			expected:                   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{
+				RuntimeOptions: RuntimeOptions{
+					ServiceAccountSubjectTiers: tc.serviceAccountSubjectTiers,
+				},
+			}
+			if aggregate := ShouldAggregateServiceAccounts(opts, tc.subjectRole); aggregate != tc.expected {
+				t.Errorf("expected ServiceAccounts to be aggregated: %t, found %t", tc.expected, aggregate)
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package common
 
 import (
-	"os"
-	"path/filepath"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type RuntimeOptions struct {
@@ -45,12 +50,47 @@ type RuntimeOptions struct {
 	// example: field.cattle.io/projectId
 	ProjectLabel string `usage:"Label on namespaces to create Project Registration Namespaces and watch for ProjectHelmCharts" env:"PROJECT_LABEL"`
 
+	// ParentProjectLabels are labels on namespaces that identify groups of projects (e.g. an organization that contains several projects), ordered
+	// from the innermost to the outermost level. Does nothing if ProjectLabel is not provided
+	// If provided, a Project Registration Namespace is also created for each unique value observed for each of these labels; ProjectHelmCharts
+	// created within it target all namespaces with that label value, regardless of the project they belong to
+	// example: example.com/org
+	//
+	// Note: Project Registration Namespaces of all levels are rendered from the same template, so label values must be unique across all levels
+	ParentProjectLabels []string `usage:"Labels on namespaces that identify groups of projects, ordered from the innermost to the outermost level" env:"PARENT_PROJECT_LABELS"`
+
+	// ProjectCRD configures the operator to identify projects based on cluster-scoped Project objects (projects.helm.cattle.io) instead of a label on namespaces.
+	// A Project Registration Namespace is created for each Project, whose target namespaces are the namespaces listed in spec.namespaces or selected
+	// by spec.namespaceSelector of the Project. Releases are deployed into the Project Registration Namespace unless spec.releaseNamespace is provided.
+	//
+	// Note: this cannot be provided along with ProjectLabel
+	ProjectCRD bool `name:"project-crd" usage:"Whether to identify projects based on Project objects instead of a label on namespaces" env:"PROJECT_CRD"`
+
+	// ProjectRegistrationNamespaceTemplate is the Go template used to render the names of Project Registration Namespaces. Does nothing if ProjectLabel is not provided
+	// The template is provided the ProjectID (e.g. tenant-{{ .ProjectID }}); by default, Project Registration Namespaces are named cattle-project-<projectID>
+	ProjectRegistrationNamespaceTemplate string `usage:"Go template used to render the names of Project Registration Namespaces (provided .ProjectID)" env:"PROJECT_REGISTRATION_NAMESPACE_TEMPLATE"`
+
+	// LegacyProjectRegistrationNamespaceTemplates are Go templates that were previously used to render the names of Project Registration Namespaces
+	// Existing namespaces that match these templates will continue to be treated as Project Registration Namespaces for their projects to ensure that
+	// ProjectHelmCharts within them are not orphaned after the ProjectRegistrationNamespaceTemplate is changed
+	LegacyProjectRegistrationNamespaceTemplates []string `usage:"Go templates previously used to render the names of Project Registration Namespaces whose existing namespaces should still be recognized" env:"LEGACY_PROJECT_REGISTRATION_NAMESPACE_TEMPLATES"`
+
 	// SystemProjectLabelValues are values of ProjectLabel that identify system namespaces. Does nothing if ProjectLabel is not provided
 	// example: p-ranch
 	// If both this and the ProjectLabel example are provided, any namespaces with label 'field.cattle.io/projectId: <system-project-label-value>'
 	// will be treated as a systemNamespace, which means that no ProjectHelmChart will be allowed to select it
 	SystemProjectLabelValues []string `usage:"Values on project label on namespaces that marks it as a system namespace" env:"SYSTEM_PROJECT_LABEL_VALUE"`
 
+	// SystemNamespacePatterns are glob patterns on the names of namespaces that identify system namespaces, in addition to the SystemNamespaces
+	// provided by the operator. Namespaces are evaluated against these patterns whenever they change
+	// example: kube-*,cattle-*-system
+	SystemNamespacePatterns []string `usage:"Glob patterns on the names of namespaces that mark them as system namespaces" env:"SYSTEM_NAMESPACE_PATTERNS"`
+
+	// SystemNamespaceSelector is a label selector on namespaces that identifies system namespaces, in addition to the SystemNamespaces
+	// provided by the operator. Namespaces are evaluated against this selector whenever they change
+	// example: platform.io/system=true
+	SystemNamespaceSelector string `usage:"Label selector on namespaces that marks them as system namespaces" env:"SYSTEM_NAMESPACE_SELECTOR"`
+
 	// ProjectReleaseLabelValue is the value of the ProjectLabel that should be added to Project Release Namespaces. Does nothing if ProjectLabel is not provided
 	// example: p-ranch
 	// If provided, dedicated Project Release namespaces will be created in the cluster for each ProjectHelmChart that needs a Helm Release
@@ -58,6 +98,18 @@ type RuntimeOptions struct {
 	// namespaces with this label value will be treated as a system namespace as well
 	ProjectReleaseLabelValue string `usage:"Value on project label on namespaces that marks it as a system namespace" env:"SYSTEM_PROJECT_LABEL_VALUE"`
 
+	// ProjectReleaseNamespaceTemplate is the Go template used to render the names of Project Release Namespaces. Does nothing if ProjectReleaseLabelValue is not provided
+	// The template is provided the ProjectRegistrationNamespace, the name of the ProjectHelmChart, and the ReleaseName (e.g. {{ .ProjectRegistrationNamespace }}-addons).
+	// By default, dedicated Project Release Namespaces are named after the release and shared Project Release Namespaces are named <project-registration-namespace>-release
+	//
+	// Note: if SharedProjectReleaseNamespace is provided, only the ProjectRegistrationNamespace is provided to the template
+	ProjectReleaseNamespaceTemplate string `usage:"Go template used to render the names of Project Release Namespaces (provided .ProjectRegistrationNamespace, .ProjectHelmChart, and .ReleaseName)" env:"PROJECT_RELEASE_NAMESPACE_TEMPLATE"`
+
+	// LegacyProjectReleaseNamespaceTemplates are Go templates that were previously used to render the names of Project Release Namespaces
+	// If a namespace created by the operator that matches one of these templates already exists, releases will continue to be deployed into it
+	// to ensure that existing releases are not moved after the ProjectReleaseNamespaceTemplate is changed
+	LegacyProjectReleaseNamespaceTemplates []string `usage:"Go templates previously used to render the names of Project Release Namespaces whose existing namespaces should continue to be used" env:"LEGACY_PROJECT_RELEASE_NAMESPACE_TEMPLATES"`
+
 	// AdminClusterRole configures the operator to automaticaly create RoleBindings on Roles in the Project Release Namespace marked with
 	// 'helm.cattle.io/project-helm-chart-role': '<helm-release>' and 'helm.cattle.io/project-helm-chart-role-aggregate-from': 'admin'
 	// based on ClusterRoleBindings or RoleBindings in the Project Registration namespace tied to the provided ClusterRole, if it exists
@@ -73,6 +125,37 @@ type RuntimeOptions struct {
 	// based on ClusterRoleBindings or RoleBindings in the Project Registration namespace tied to the provided ClusterRole, if it exists
 	ViewClusterRole string `usage:"ClusterRole tied to view users who should have permissions in the Project Release Namespace" env:"VIEW_CLUSTER_ROLE"`
 
+	// ClusterRoleTiers configures additional subject roles (tiers) beyond admin, edit, and view, keyed by the name of the tier. For each tier,
+	// the operator will automatically create RoleBindings on Roles in the Project Release Namespace marked with
+	// 'helm.cattle.io/project-helm-chart-role': '<helm-release>' and 'helm.cattle.io/project-helm-chart-role-aggregate-from': '<tier>'
+	// based on ClusterRoleBindings or RoleBindings in the Project Registration namespace tied to the provided ClusterRole, if it exists
+	// example: operator=project-operator,auditor=project-auditor,oncall=project-oncall
+	//
+	// Note: a tier named admin, edit, or view overrides the AdminClusterRole, EditClusterRole, or ViewClusterRole respectively
+	ClusterRoleTiers map[string]string `usage:"Additional tiers of users who should have permissions in the Project Release Namespace in the format <tier>=<cluster-role>" env:"CLUSTER_ROLE_TIERS"`
+
+	// AggregateProjectNamespaceRoleBindings configures the operator to also aggregate subjects from RoleBindings in each of the target namespaces of a
+	// ProjectHelmChart that are tied to the default ClusterRoles (e.g. AdminClusterRole, EditClusterRole, ViewClusterRole, or ClusterRoleTiers).
+	// By default, only RoleBindings in the Project Registration Namespace and ClusterRoleBindings are aggregated.
+	AggregateProjectNamespaceRoleBindings bool `usage:"Whether to also aggregate RoleBindings to default ClusterRoles in all project namespaces into RoleBindings in the Project Release Namespace" env:"AGGREGATE_PROJECT_NAMESPACE_ROLE_BINDINGS"`
+
+	// RBACReport configures the operator to record the RBAC computed for each ProjectHelmChart (the subjects of each tier, the bindings that each
+	// subject was collected from, and the Roles bound in the Project Release Namespace) in a ConfigMap in the Project Registration Namespace
+	// named <project-helm-chart>-rbac-report. By default, no report is recorded since it exposes the subjects of ClusterRoleBindings.
+	RBACReport bool `name:"rbac-report" usage:"Whether to record the RBAC computed for each ProjectHelmChart in a ConfigMap in the Project Registration Namespace" env:"RBAC_REPORT"`
+
+	// ClusterRoleBindingResyncDelay is the window within which changes to ClusterRoleBindings and project namespace RoleBindings tied to the
+	// default ClusterRoles (and changes to fan-out ClusterRoles) are coalesced
+	// Since such a change can affect many ProjectHelmCharts, each affected ProjectHelmChart is only enqueued once per window
+	// If set to 0, affected ProjectHelmCharts are enqueued immediately on every change
+	ClusterRoleBindingResyncDelay string `usage:"Window within which changes to ClusterRoleBindings to default ClusterRoles are coalesced before re-enqueuing affected ProjectHelmCharts" default:"5s" env:"CLUSTER_ROLE_BINDING_RESYNC_DELAY"`
+
+	// ServiceAccountSubjectTiers configures the operator to also bind ServiceAccounts to Roles in the Project Release Namespace for the provided tiers
+	// (e.g. admin, edit, view, or any tier in ClusterRoleTiers); '*' enables this for all tiers. By default, only Users and Groups are bound.
+	// Only ServiceAccounts that live in the Project Registration Namespace or the namespaces of the project are bound
+	// example: admin,operator
+	ServiceAccountSubjectTiers []string `usage:"Tiers whose ServiceAccount subjects from the project's namespaces should also be bound in the Project Release Namespace ('*' for all tiers)" env:"SERVICE_ACCOUNT_SUBJECT_TIERS"`
+
 	// DisableHardening turns off the controller that manages the default service account and a default NetworkPolicy deployed on all
 	// namespaces marked with the Helm Project Operated Label to prevent generated namespaces from breaking a CIS 1.16 Hardened Scan by patching
 	// the default ServiceAccount and creating a default secure NetworkPolicy.
@@ -98,12 +181,107 @@ type RuntimeOptions struct {
 	// DisableEmbeddedHelmController determines whether to disable embedded Helm Controller controller in favor of external Helm Controller
 	// This should be the default in most RKE2 clusters since the RKE2 server binary already embeds a Helm Controller instance that manages HelmCharts
 	DisableEmbeddedHelmController bool `usage:"Whether to disable embedded Helm Controller controller in favor of external Helm Controller (recommended for RKE2 clusters)" env:"DISABLE_EMBEDDED_HELM_CONTROLLER"`
+
+	// DeploymentBackend is the backend used to deploy the Helm chart on behalf of each ProjectHelmChart. One of:
+	// - helm-controller: creates HelmCharts that are deployed by a Helm Controller via Jobs in the system namespace (default)
+	// - helm-sdk: installs and upgrades releases directly from the operator via the Helm SDK without running any Jobs
+	// - fleet: creates Fleet Bundles in the FleetNamespace that are deployed by a Fleet controller
+	// Regardless of the backend chosen, a HelmRelease is created for each release to allow Helm Locker to lock it into place
+	DeploymentBackend string `usage:"Backend used to deploy Helm releases for ProjectHelmCharts (helm-controller, helm-sdk, or fleet)" default:"helm-controller" env:"DEPLOYMENT_BACKEND"`
+
+	// FleetNamespace is the namespace that Fleet Bundles are created in if the DeploymentBackend is fleet
+	FleetNamespace string `usage:"Namespace to create Fleet Bundles in if --deployment-backend=fleet" default:"fleet-local" env:"FLEET_NAMESPACE"`
+
+	// HelmSDKWorkers is the maximum number of Helm operations that will be performed concurrently if the DeploymentBackend is helm-sdk
+	// Operations on the same release are never performed concurrently, regardless of the number of workers
+	HelmSDKWorkers int `name:"helm-sdk-workers" usage:"Maximum number of concurrent Helm operations if --deployment-backend=helm-sdk" default:"5" env:"HELM_SDK_WORKERS"`
+
+	// ScopeReleasePermissions configures the operator to create a dedicated ServiceAccount for each ProjectHelmChart whose permissions are scoped
+	// to the Project Release Namespace and the target namespaces of the ProjectHelmChart. All Helm operations performed on the release
+	// are performed by impersonating that ServiceAccount, which ensures that a chart cannot modify resources outside of its project.
+	//
+	// Note: this is only supported by the helm-sdk DeploymentBackend since HelmCharts deployed by a Helm Controller always run as a
+	// ServiceAccount bound to cluster-admin in the system namespace
+	ScopeReleasePermissions bool `usage:"Whether to deploy each release as a dedicated ServiceAccount whose permissions are scoped to the project (requires --deployment-backend=helm-sdk)" env:"SCOPE_RELEASE_PERMISSIONS"`
+
+	// ReleasePermissionsFile is the path to the file that contains additional permissions to grant to the ServiceAccount that deploys each release
+	// By default, the ServiceAccount is only granted full access to resources within the Project Release Namespace and the target namespaces
+	ReleasePermissionsFile string `usage:"Path to file that contains additional permissions to grant to the ServiceAccount that deploys each release if --scope-release-permissions is provided" default:"release-permissions.yaml" env:"RELEASE_PERMISSIONS_FILE"`
+
+	// ReleaseNamespaceResourcesFile is the path to the file that contains the ResourceQuota and LimitRange to create in every Project Release Namespace
+	// along with the bounds within which ProjectHelmCharts can override them. By default, no ResourceQuota or LimitRange is created.
+	ReleaseNamespaceResourcesFile string `usage:"Path to file that contains the ResourceQuota and LimitRange to create in every Project Release Namespace" default:"release-namespace-resources.yaml" env:"RELEASE_NAMESPACE_RESOURCES_FILE"`
+
+	// DefaultReleaseRolesFile is the path to the file that contains the rules of the default Roles to create in the Project Release Namespace for each tier
+	// if the deployed chart does not provide its own Roles for that tier. By default, no default Roles are created.
+	DefaultReleaseRolesFile string `usage:"Path to file that contains the default Roles to create in the Project Release Namespace for each tier if a chart does not provide them" default:"default-release-roles.yaml" env:"DEFAULT_RELEASE_ROLES_FILE"`
+
+	// PropagatedLabels are the label keys that are copied from ProjectHelmCharts and Project Registration Namespaces onto all resources generated
+	// on behalf of a ProjectHelmChart (e.g. the Project Release Namespace, HelmCharts, HelmReleases, and RoleBindings)
+	// An entry that ends with '*' matches all keys with that prefix
+	// example: team,example.com/*
+	//
+	// Note: labels managed by the operator are never overridden
+	PropagatedLabels []string `usage:"Label keys (or prefixes ending in '*') copied from ProjectHelmCharts and Project Registration Namespaces onto generated resources" env:"PROPAGATED_LABELS"`
+
+	// PropagatedAnnotations are the annotation keys that are copied from ProjectHelmCharts and Project Registration Namespaces onto all resources generated
+	// on behalf of a ProjectHelmChart. An entry that ends with '*' matches all keys with that prefix
+	//
+	// Note: annotations managed by the operator are never overridden
+	PropagatedAnnotations []string `usage:"Annotation keys (or prefixes ending in '*') copied from ProjectHelmCharts and Project Registration Namespaces onto generated resources" env:"PROPAGATED_ANNOTATIONS"`
+
+	// PropagateMetadataFrom is the source of the PropagatedLabels and PropagatedAnnotations. One of:
+	// - all: copied from both the Project Registration Namespace and the ProjectHelmChart; values on the ProjectHelmChart take precedence (default)
+	// - project-helm-chart: only copied from the ProjectHelmChart
+	// - project-registration-namespace: only copied from the Project Registration Namespace
+	PropagateMetadataFrom string `usage:"Source of propagated labels and annotations (all, project-helm-chart, or project-registration-namespace)" default:"all" env:"PROPAGATE_METADATA_FROM"`
+
+	// AllowedHelmOptions are the options in spec.helmOptions that users are allowed to configure on ProjectHelmCharts
+	// By default, no options are allowed, which means that any ProjectHelmChart that provides spec.helmOptions will not be deployed
+	// example: timeout,failurePolicy
+	//
+	// Note: each allowed option must be supported by the DeploymentBackend
+	AllowedHelmOptions []string `usage:"Options in spec.helmOptions that users are allowed to configure on ProjectHelmCharts (timeout, atomic, wait, failurePolicy, backoffLimit, nodeSelector, or tolerations)" env:"ALLOWED_HELM_OPTIONS"`
+
+	// ValuesHistoryLimit is the maximum number of revisions of spec.values that are stored for each ProjectHelmChart
+	// Revisions are stored as immutable Secrets in the system namespace and can be restored via the rollback-to-revision annotation
+	// If set to 0, no revisions will be stored
+	ValuesHistoryLimit int `usage:"Maximum number of revisions of spec.values to store for each ProjectHelmChart (0 disables values history)" default:"10" env:"VALUES_HISTORY_LIMIT"`
+
+	// GarbageCollectOrphanedNamespaces configures the operator to delete Project Registration Namespaces and Project Release Namespaces
+	// that have been marked as orphaned for longer than the OrphanedNamespaceTTL. A namespace is only deleted if it contains no ProjectHelmCharts
+	// and no running workloads, and if it is not marked with the annotation 'helm.cattle.io/helm-project-operator-keep': 'true'
+	GarbageCollectOrphanedNamespaces bool `usage:"Whether to delete orphaned Project Registration and Project Release Namespaces after --orphaned-namespace-ttl" env:"GARBAGE_COLLECT_ORPHANED_NAMESPACES"`
+
+	// SharedProjectReleaseNamespace configures the operator to deploy the releases of all ProjectHelmCharts in a project into a single
+	// Project Release Namespace (<project-registration-namespace>-release) instead of a dedicated namespace per ProjectHelmChart.
+	// The shared namespace is only marked as orphaned once no ProjectHelmChart in the project uses it, including those of other Project Operators
+	// that are also deployed with this option.
+	//
+	// Note: this requires both ProjectLabel and ProjectReleaseLabelValue to be provided
+	SharedProjectReleaseNamespace bool `usage:"Whether to deploy the releases of all ProjectHelmCharts in a project into a single shared Project Release Namespace" env:"SHARED_PROJECT_RELEASE_NAMESPACE"`
+
+	// OrphanedNamespaceTTL is the grace period after a namespace is marked as orphaned before it can be garbage collected
+	// example: 24h
+	OrphanedNamespaceTTL string `usage:"Grace period after a namespace is marked as orphaned before it is deleted if --garbage-collect-orphaned-namespaces is provided" default:"168h" env:"ORPHANED_NAMESPACE_TTL"`
 }
 
 // Validate validates the provided RuntimeOptions
 func (opts RuntimeOptions) Validate() error {
+	cleanupMessage := fmt.Sprintf("these namespaces will need to be manually cleaned up if they have the label '%s': 'true'", HelmProjectOperatedNamespaceOrphanedLabel)
+	if opts.GarbageCollectOrphanedNamespaces {
+		orphanedNamespaceTTL, err := time.ParseDuration(opts.OrphanedNamespaceTTL)
+		if err != nil {
+			return fmt.Errorf("invalid orphaned namespace TTL %s: %s", opts.OrphanedNamespaceTTL, err)
+		}
+		if orphanedNamespaceTTL < 0 {
+			return fmt.Errorf("invalid orphaned namespace TTL %s: must not be negative", opts.OrphanedNamespaceTTL)
+		}
+		cleanupMessage = fmt.Sprintf("these namespaces will automatically be deleted %s after they are marked with the label '%s': 'true' unless they contain ProjectHelmCharts or workloads or are marked with '%s': 'true'", orphanedNamespaceTTL, HelmProjectOperatedNamespaceOrphanedLabel, HelmProjectOperatedNamespaceKeepAnnotation)
+	}
+
 	if len(opts.ProjectLabel) > 0 {
-		logrus.Infof("Creating dedicated project registration namespaces to discover ProjectHelmCharts based on the value found for the project label '%s' on all namespaces in the cluster, excluding system namespaces; these namespaces will need to be manually cleaned up if they have the label '%s': 'true'", opts.ProjectLabel, HelmProjectOperatedNamespaceOrphanedLabel)
+		logrus.Infof("Creating dedicated project registration namespaces to discover ProjectHelmCharts based on the value found for the project label '%s' on all namespaces in the cluster, excluding system namespaces; %s", opts.ProjectLabel, cleanupMessage)
 		if len(opts.SystemProjectLabelValues) > 0 {
 			for _, systemProjectLabel := range opts.SystemProjectLabelValues {
 				logrus.Infof("Assuming namespaces tagged with %s=%s are also system namespaces", opts.ProjectLabel, systemProjectLabel)
@@ -111,15 +289,163 @@ func (opts RuntimeOptions) Validate() error {
 		}
 		if len(opts.ProjectReleaseLabelValue) > 0 {
 			logrus.Infof("Assuming namespaces tagged with %s=%s are also system namespaces", opts.ProjectLabel, opts.ProjectReleaseLabelValue)
-			logrus.Infof("Creating dedicated project release namespaces for ProjectHelmCharts with label '%s': '%s'; these namespaces will need to be manually cleaned up if they have the label '%s': 'true'", opts.ProjectLabel, opts.ProjectReleaseLabelValue, HelmProjectOperatedNamespaceOrphanedLabel)
+			if opts.SharedProjectReleaseNamespace {
+				logrus.Infof("Creating a single shared project release namespace per project for all ProjectHelmCharts with label '%s': '%s'; %s", opts.ProjectLabel, opts.ProjectReleaseLabelValue, cleanupMessage)
+			} else {
+				logrus.Infof("Creating dedicated project release namespaces for ProjectHelmCharts with label '%s': '%s'; %s", opts.ProjectLabel, opts.ProjectReleaseLabelValue, cleanupMessage)
+			}
 		}
 		if len(opts.ClusterID) > 0 {
 			logrus.Infof("Marking project registration namespaces with %s=%s:<projectID>", opts.ProjectLabel, opts.ClusterID)
 		}
 	}
 
-	if len(opts.HelmJobImage) > 0 {
-		logrus.Infof("Using %s as spec.JobImage on all generated HelmChart resources", opts.HelmJobImage)
+	if len(opts.ParentProjectLabels) > 0 {
+		if len(opts.ProjectLabel) == 0 {
+			return fmt.Errorf("cannot identify groups of projects based on parent project labels without providing a project label")
+		}
+		projectLabels := map[string]bool{
+			opts.ProjectLabel: true,
+		}
+		for _, parentProjectLabel := range opts.ParentProjectLabels {
+			if errs := validation.IsQualifiedName(parentProjectLabel); len(errs) > 0 {
+				return fmt.Errorf("invalid parent project label %q: %s", parentProjectLabel, strings.Join(errs, ", "))
+			}
+			if projectLabels[parentProjectLabel] {
+				return fmt.Errorf("invalid parent project label %s: each project label can only be provided once", parentProjectLabel)
+			}
+			projectLabels[parentProjectLabel] = true
+		}
+		logrus.Infof("Creating dedicated project registration namespaces for each group of projects based on the values found for the parent project labels %s", strings.Join(opts.ParentProjectLabels, ", "))
+	}
+
+	if opts.ProjectCRD {
+		if len(opts.ProjectLabel) > 0 {
+			return fmt.Errorf("cannot identify projects based on both Project objects and the project label %s", opts.ProjectLabel)
+		}
+		if len(opts.ProjectReleaseLabelValue) > 0 {
+			return fmt.Errorf("cannot create project release namespaces when identifying projects based on Project objects")
+		}
+		logrus.Infof("Creating dedicated project registration namespaces to discover ProjectHelmCharts for each Project object in the cluster; %s", cleanupMessage)
+	}
+
+	for _, systemNamespacePattern := range opts.SystemNamespacePatterns {
+		if _, err := path.Match(systemNamespacePattern, ""); err != nil {
+			return fmt.Errorf("invalid system namespace pattern %q: %s", systemNamespacePattern, err)
+		}
+	}
+	if len(opts.SystemNamespacePatterns) > 0 {
+		logrus.Infof("Assuming namespaces whose names match any of the patterns %s are also system namespaces", strings.Join(opts.SystemNamespacePatterns, ", "))
+	}
+
+	if opts.SharedProjectReleaseNamespace && (len(opts.ProjectLabel) == 0 || len(opts.ProjectReleaseLabelValue) == 0) {
+		return fmt.Errorf("cannot use a shared project release namespace without providing both a project label and a project release label value")
+	}
+
+	for subjectRole, clusterRole := range opts.ClusterRoleTiers {
+		// the name of the tier is the value of the aggregate-from label on Roles in the Project Release Namespace
+		if errs := validation.IsValidLabelValue(subjectRole); len(subjectRole) == 0 || len(errs) > 0 {
+			return fmt.Errorf("invalid cluster role tier %q: must be a non-empty valid label value", subjectRole)
+		}
+		if len(clusterRole) == 0 {
+			return fmt.Errorf("invalid cluster role tier %s: must provide a ClusterRole", subjectRole)
+		}
+	}
+
+	for _, subjectRole := range opts.ServiceAccountSubjectTiers {
+		if subjectRole == "*" {
+			continue
+		}
+		if _, ok := opts.ClusterRoleTiers[subjectRole]; ok {
+			continue
+		}
+		if subjectRole != "admin" && subjectRole != "edit" && subjectRole != "view" {
+			return fmt.Errorf("invalid service account subject tier %s: must be '*', admin, edit, view, or a tier provided in ClusterRoleTiers", subjectRole)
+		}
+	}
+	clusterRoleBindingResyncDelay, err := time.ParseDuration(opts.ClusterRoleBindingResyncDelay)
+	if err != nil {
+		return fmt.Errorf("invalid cluster role binding resync delay %s: %s", opts.ClusterRoleBindingResyncDelay, err)
+	}
+	if clusterRoleBindingResyncDelay < 0 {
+		return fmt.Errorf("invalid cluster role binding resync delay %s: must not be negative", opts.ClusterRoleBindingResyncDelay)
+	}
+
+	if opts.RBACReport {
+		logrus.Infof("Recording the RBAC computed for each ProjectHelmChart in a ConfigMap with label %s in the Project Registration Namespace", HelmProjectOperatorRBACReportLabel)
+	}
+
+	if opts.AggregateProjectNamespaceRoleBindings {
+		logrus.Infof("Aggregating RoleBindings to default ClusterRoles in all project namespaces into RoleBindings in the Project Release Namespace")
+	}
+
+	if len(opts.ServiceAccountSubjectTiers) > 0 {
+		logrus.Infof("Binding ServiceAccounts from the namespaces of each project to Roles in the Project Release Namespace for tiers: %s", strings.Join(opts.ServiceAccountSubjectTiers, ", "))
+	}
+
+	if err := opts.validateNamingTemplates(); err != nil {
+		return err
+	}
+	if (len(opts.ProjectLabel) > 0 || opts.ProjectCRD) && len(opts.ProjectRegistrationNamespaceTemplate) > 0 {
+		logrus.Infof("Naming project registration namespaces based on the template %q", opts.ProjectRegistrationNamespaceTemplate)
+	}
+	if len(opts.ProjectLabel) > 0 && len(opts.ProjectReleaseLabelValue) > 0 && len(opts.ProjectReleaseNamespaceTemplate) > 0 {
+		logrus.Infof("Naming project release namespaces based on the template %q", opts.ProjectReleaseNamespaceTemplate)
+	}
+
+	switch opts.DeploymentBackend {
+	case "", backend.HelmControllerBackend:
+		if len(opts.HelmJobImage) > 0 {
+			logrus.Infof("Using %s as spec.JobImage on all generated HelmChart resources", opts.HelmJobImage)
+		}
+	case backend.HelmSDKBackend:
+		if opts.HelmSDKWorkers <= 0 {
+			return fmt.Errorf("invalid number of Helm SDK workers %d: must be greater than 0", opts.HelmSDKWorkers)
+		}
+		logrus.Infof("Deploying Helm releases directly from the operator via the Helm SDK with %d workers", opts.HelmSDKWorkers)
+	case backend.FleetBackend:
+		logrus.Infof("Deploying Helm releases via Fleet Bundles created in namespace %s", opts.FleetNamespace)
+	default:
+		return fmt.Errorf("invalid deployment backend %s: must be one of %s, %s, or %s", opts.DeploymentBackend, backend.HelmControllerBackend, backend.HelmSDKBackend, backend.FleetBackend)
+	}
+
+	if len(opts.AllowedHelmOptions) > 0 {
+		supportedHelmOptions := backend.SupportedHelmOptions(opts.DeploymentBackend)
+		for _, allowedHelmOption := range opts.AllowedHelmOptions {
+			if !slices.Contains(supportedHelmOptions, allowedHelmOption) {
+				return fmt.Errorf("cannot allow helm option %s with deployment backend %s: must be one of %s", allowedHelmOption, opts.DeploymentBackend, strings.Join(supportedHelmOptions, ", "))
+			}
+			if backend.IsJobHelmOption(allowedHelmOption) && opts.DisableEmbeddedHelmController {
+				return fmt.Errorf("cannot allow helm option %s if the embedded Helm Controller is disabled: an external Helm Controller does not respect it", allowedHelmOption)
+			}
+		}
+		logrus.Infof("Allowing ProjectHelmCharts to configure spec.helmOptions: %s", strings.Join(opts.AllowedHelmOptions, ", "))
+	}
+
+	switch opts.PropagateMetadataFrom {
+	case "", PropagateMetadataFromAll, PropagateMetadataFromProjectHelmChart, PropagateMetadataFromProjectRegistrationNamespace:
+	default:
+		return fmt.Errorf("invalid source of propagated metadata %s: must be one of %s, %s, or %s", opts.PropagateMetadataFrom, PropagateMetadataFromAll, PropagateMetadataFromProjectHelmChart, PropagateMetadataFromProjectRegistrationNamespace)
+	}
+	if len(opts.PropagatedLabels) > 0 {
+		logrus.Infof("Propagating labels onto all resources generated for ProjectHelmCharts: %s", strings.Join(opts.PropagatedLabels, ", "))
+	}
+	if len(opts.PropagatedAnnotations) > 0 {
+		logrus.Infof("Propagating annotations onto all resources generated for ProjectHelmCharts: %s", strings.Join(opts.PropagatedAnnotations, ", "))
+	}
+
+	if opts.ValuesHistoryLimit < 0 {
+		return fmt.Errorf("invalid values history limit %d: must not be negative", opts.ValuesHistoryLimit)
+	}
+	if opts.ValuesHistoryLimit > 0 {
+		logrus.Infof("Storing up to %d revisions of spec.values for each ProjectHelmChart in the system namespace; revisions can be restored with the annotation '%s': '<revision>'", opts.ValuesHistoryLimit, HelmProjectOperatorRollbackToRevisionAnnotation)
+	}
+
+	if opts.ScopeReleasePermissions {
+		if opts.DeploymentBackend != backend.HelmSDKBackend {
+			return fmt.Errorf("cannot scope release permissions with deployment backend %s: only %s is supported", opts.DeploymentBackend, backend.HelmSDKBackend)
+		}
+		logrus.Infof("Deploying each release as a dedicated ServiceAccount in the system namespace whose permissions are scoped to the Project Release Namespace and target namespaces")
 	}
 
 	if len(opts.NodeName) > 0 {
@@ -138,22 +464,8 @@ func (opts RuntimeOptions) Validate() error {
 // LoadValuesOverrideFromFile unmarshalls the struct found at the file to YAML and reads it into memory
 func LoadValuesOverrideFromFile(path string) (v1alpha1.GenericMap, error) {
 	var valuesOverride v1alpha1.GenericMap
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	abspath := filepath.Join(wd, path)
-	_, err = os.Stat(abspath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// we just assume the default is used
-			err = nil
-		}
-		return nil, err
-	}
-	valuesOverrideBytes, err := os.ReadFile(abspath)
-	if err != nil {
-		return nil, err
-	}
-	return valuesOverride, yaml.Unmarshal(valuesOverrideBytes, &valuesOverride)
+	err := loadFromFile(path, func(data []byte) error {
+		return yaml.Unmarshal(data, &valuesOverride)
+	})
+	return valuesOverride, err
 }
@@ -1,6 +1,11 @@
 package common
 
-import v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+import (
+	"fmt"
+	"strconv"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+)
 
 // User-Applied Labels
 // Note: These labels are expected to be applied by users (or by Jobs, in the case of cleanup), to mark a resources as one that needs
@@ -24,6 +29,71 @@ func HasCleanupLabel(projectHelmChart *v1alpha1.ProjectHelmChart) bool {
 	return shouldCleanup && value == "true"
 }
 
+const (
+	// HelmProjectOperatorRollbackToRevisionAnnotation is an annotation attached to ProjectHelmCharts to restore the spec.values of a revision
+	// listed in status.valuesRevisions; on seeing this annotation, the operator will replace spec.values with the values of that revision
+	// and automatically remove this annotation from the ProjectHelmChart
+	HelmProjectOperatorRollbackToRevisionAnnotation = "helm.cattle.io/rollback-to-revision"
+)
+
+// GetRollbackToRevision returns the values revision that a ProjectHelmChart should be rolled back to, if requested
+func GetRollbackToRevision(projectHelmChart *v1alpha1.ProjectHelmChart) (int, bool, error) {
+	if projectHelmChart.Annotations == nil {
+		return 0, false, nil
+	}
+	value, ok := projectHelmChart.Annotations[HelmProjectOperatorRollbackToRevisionAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, true, fmt.Errorf("invalid value for annotation %s: %s must be a positive integer", HelmProjectOperatorRollbackToRevisionAnnotation, value)
+	}
+	return revision, true, nil
+}
+
+const (
+	// HelmProjectOperatorTakeOverReleaseAnnotation is an annotation attached to ProjectHelmCharts to take over a release that is
+	// currently owned by another ProjectHelmChart that would deploy a release with the same name. Without this annotation,
+	// the ProjectHelmChart that currently owns the release (or the oldest ProjectHelmChart, if no owner has been recorded) keeps it.
+	HelmProjectOperatorTakeOverReleaseAnnotation = "helm.cattle.io/take-over-release"
+)
+
+// HasTakeOverReleaseAnnotation returns whether a ProjectHelmChart has requested to take over its release
+func HasTakeOverReleaseAnnotation(projectHelmChart *v1alpha1.ProjectHelmChart) bool {
+	if projectHelmChart.Annotations == nil {
+		return false
+	}
+	value, ok := projectHelmChart.Annotations[HelmProjectOperatorTakeOverReleaseAnnotation]
+	return ok && value == "true"
+}
+
+// Project Namespaces
+
+const (
+	// HelmProjectOperatedNamespaceKeepAnnotation is an annotation attached to orphaned Project Registration Namespaces or Project Release
+	// Namespaces to prevent them from being garbage collected by the operator
+	HelmProjectOperatedNamespaceKeepAnnotation = "helm.cattle.io/helm-project-operator-keep"
+)
+
+// HasKeepAnnotation returns whether a namespace should never be garbage collected
+func HasKeepAnnotation(annotations map[string]string) bool {
+	if annotations == nil {
+		return false
+	}
+	value, ok := annotations[HelmProjectOperatedNamespaceKeepAnnotation]
+	return ok && value == "true"
+}
+
+// Project Registration Namespace Secrets and ConfigMaps
+
+const (
+	// HelmProjectOperatorProjectHelmChartSyncLabel is a label that identifies a Secret or ConfigMap in the Project Registration Namespace that
+	// should be copied into the Project Release Namespace and kept in sync. The value of this label will be the release name of the Helm chart,
+	// which will be used to identify which ProjectHelmChart's release namespace the Secret or ConfigMap should be synced into.
+	HelmProjectOperatorProjectHelmChartSyncLabel = "helm.cattle.io/project-helm-chart-sync"
+)
+
 // Project Release Namespace ConfigMaps
 
 const (
@@ -44,4 +114,18 @@ const (
 	// the operator will automatically create a RoleBinding in the Project Release Namespace binding all subjects who have that permission across all namespaces in the project
 	// to the Role that contains this label. This label will only be viewed if the Role has HelmProjectOperatorProjectHelmChartRoleLabel set as well
 	HelmProjectOperatorProjectHelmChartRoleAggregateFromLabel = "helm.cattle.io/project-helm-chart-role-aggregate-from"
+
+	// HelmProjectOperatorProjectHelmChartRoleFanOutLabel is a label that identifies a Project Helm Chart Role (or a ClusterRole with the same labels)
+	// whose permissions should also be granted in every target project namespace of the ProjectHelmChart. The value of this label must be "true".
+	// For a Role in the Project Release Namespace, the operator copies the Role into each target project namespace and binds it there;
+	// for a ClusterRole, the operator creates a RoleBinding to the ClusterRole in each target project namespace
+	HelmProjectOperatorProjectHelmChartRoleFanOutLabel = "helm.cattle.io/project-helm-chart-role-fan-out"
 )
+
+// HasFanOutLabel returns whether the provided labels mark a Project Helm Chart Role or ClusterRole for fan-out into all target project namespaces
+func HasFanOutLabel(labels map[string]string) bool {
+	if labels == nil {
+		return false
+	}
+	return labels[HelmProjectOperatorProjectHelmChartRoleFanOutLabel] == "true"
+}
@@ -9,20 +9,27 @@ import (
 	"github.com/k3s-io/helm-controller/pkg/controllers/chart"
 	k3shelm "github.com/k3s-io/helm-controller/pkg/generated/controllers/helm.cattle.io"
 	k3shelmcontroller "github.com/k3s-io/helm-controller/pkg/generated/controllers/helm.cattle.io/v1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 	"github.com/rancher/helm-project-operator/pkg/controllers/hardened"
 	"github.com/rancher/helm-project-operator/pkg/controllers/namespace"
+	"github.com/rancher/helm-project-operator/pkg/controllers/orphaned"
 	"github.com/rancher/helm-project-operator/pkg/controllers/project"
+	hpocore "github.com/rancher/helm-project-operator/pkg/generated/controllers/core"
+	hpocorecontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/core/v1"
 	helmproject "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io"
 	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
 	"github.com/rancher/helm-project-operator/pkg/helm-locker/controllers/release"
 	helmlocker "github.com/rancher/helm-project-operator/pkg/helm-locker/generated/controllers/helm.cattle.io"
 	helmlockercontroller "github.com/rancher/helm-project-operator/pkg/helm-locker/generated/controllers/helm.cattle.io/v1alpha1"
 	"github.com/rancher/helm-project-operator/pkg/helm-locker/objectset"
+	"github.com/rancher/helm-project-operator/pkg/helm-locker/releases"
 	"github.com/rancher/lasso/pkg/cache"
 	"github.com/rancher/lasso/pkg/client"
 	"github.com/rancher/lasso/pkg/controller"
 	"github.com/rancher/wrangler/pkg/apply"
+	apps "github.com/rancher/wrangler/pkg/generated/controllers/apps"
+	appscontroller "github.com/rancher/wrangler/pkg/generated/controllers/apps/v1"
 	batch "github.com/rancher/wrangler/pkg/generated/controllers/batch"
 	batchcontroller "github.com/rancher/wrangler/pkg/generated/controllers/batch/v1"
 	"github.com/rancher/wrangler/pkg/generated/controllers/core"
@@ -54,7 +61,9 @@ type appContext struct {
 	Dynamic    dynamic.Interface
 	K8s        kubernetes.Interface
 	Core       corecontroller.Interface
+	Apps       appscontroller.Interface
 	Networking networkingcontroller.Interface
+	Quota      hpocorecontroller.Interface
 
 	HelmLocker        helmlockercontroller.Interface
 	ObjectSetRegister objectset.LockableRegister
@@ -133,6 +142,9 @@ func Register(ctx context.Context, systemNamespace string, cfg clientcmd.ClientC
 		// enqueues
 		appCtx.ProjectHelmChart(),
 		appCtx.ProjectHelmChart().Cache(),
+		// watches and updates status
+		appCtx.Project(),
+		appCtx.Project().Cache(),
 		appCtx.Dynamic,
 	)
 
@@ -140,14 +152,84 @@ func Register(ctx context.Context, systemNamespace string, cfg clientcmd.ClientC
 		opts.ControllerName = "helm-project-operator"
 	}
 
+	if opts.GarbageCollectOrphanedNamespaces {
+		// note: events on namespaces are recorded in the default namespace since namespaces are cluster-scoped,
+		// so a separate broadcaster that is not restricted to the system namespace is required
+		namespaceEventBroadcaster := record.NewBroadcaster()
+		namespaceEventBroadcaster.StartLogging(logrus.Debugf)
+		namespaceEventBroadcaster.StartRecordingToSink(&typedv1.EventSinkImpl{
+			Interface: appCtx.K8s.CoreV1().Events(""),
+		})
+		namespaceRecorder := namespaceEventBroadcaster.NewRecorder(schemes.All, corev1.EventSource{
+			Component: "helm-project-operator",
+			Host:      opts.NodeName,
+		})
+		err = orphaned.Register(ctx,
+			opts,
+			// watches and deletes
+			appCtx.Core.Namespace(),
+			appCtx.Core.Namespace().Cache(),
+			// lists
+			appCtx.Core.Pod(),
+			appCtx.Core.PersistentVolumeClaim(),
+			appCtx.Apps.Deployment(),
+			appCtx.Apps.StatefulSet(),
+			appCtx.Apps.DaemonSet(),
+			// watches
+			appCtx.ProjectHelmChart(),
+			appCtx.ProjectHelmChart().Cache(),
+			namespaceRecorder,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	valuesOverride, err := common.LoadValuesOverrideFromFile(opts.ValuesOverrideFile)
 	if err != nil {
 		return err
 	}
+
+	releaseNamespaceResources, err := common.LoadReleaseNamespaceResourcesOptionsFromFile(opts.ReleaseNamespaceResourcesFile)
+	if err != nil {
+		return err
+	}
+
+	defaultReleaseRoles, err := common.LoadDefaultReleaseRolesOptionsFromFile(opts.DefaultReleaseRolesFile)
+	if err != nil {
+		return err
+	}
+	if err := defaultReleaseRoles.Validate(opts); err != nil {
+		return err
+	}
+
+	var releasePermissions common.ReleasePermissionsOptions
+	if opts.ScopeReleasePermissions {
+		releasePermissions, err = common.LoadReleasePermissionsOptionsFromFile(opts.ReleasePermissionsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	deploymentBackend, err := backend.New(ctx, opts.DeploymentBackend, backend.Options{
+		SystemNamespace: systemNamespace,
+		ControllerName:  opts.ControllerName,
+		ChartContent:    opts.ChartContent,
+		HelmJobImage:    opts.HelmJobImage,
+		FleetNamespace:  opts.FleetNamespace,
+		HelmSDKWorkers:  opts.HelmSDKWorkers,
+	}, backend.NewConfigurationGetter(cfg))
+	if err != nil {
+		return err
+	}
+
 	project.Register(ctx,
 		systemNamespace,
 		opts,
 		valuesOverride,
+		releasePermissions,
+		releaseNamespaceResources,
+		defaultReleaseRoles,
 		appCtx.Apply,
 		// watches
 		appCtx.ProjectHelmChart(),
@@ -156,16 +238,26 @@ func Register(ctx context.Context, systemNamespace string, cfg clientcmd.ClientC
 		appCtx.Core.ConfigMap().Cache(),
 		appCtx.RBAC.Role(),
 		appCtx.RBAC.Role().Cache(),
+		appCtx.RBAC.ClusterRole(),
+		appCtx.RBAC.ClusterRole().Cache(),
 		appCtx.RBAC.ClusterRoleBinding(),
 		appCtx.RBAC.ClusterRoleBinding().Cache(),
 		// watches and generates
 		appCtx.HelmController.HelmChart(),
 		appCtx.HelmLocker.HelmRelease(),
+		appCtx.HelmLocker.HelmRelease().Cache(),
 		appCtx.Core.Namespace(),
 		appCtx.Core.Namespace().Cache(),
 		appCtx.RBAC.RoleBinding(),
 		appCtx.RBAC.RoleBinding().Cache(),
+		appCtx.Core.ServiceAccount(),
+		appCtx.Quota.ResourceQuota(),
+		appCtx.Quota.LimitRange(),
+		appCtx.Core.Secret(),
+		appCtx.Core.Secret().Cache(),
 		projectGetter,
+		releases.NewHelmReleaseGetter(appCtx.K8s),
+		deploymentBackend,
 	)
 
 	if !opts.DisableEmbeddedHelmLocker {
@@ -184,13 +276,14 @@ func Register(ctx context.Context, systemNamespace string, cfg clientcmd.ClientC
 		)
 	}
 
-	if !opts.DisableEmbeddedHelmController {
+	if !opts.DisableEmbeddedHelmController && usesHelmController(opts) {
 		logrus.Infof("Registering embedded Helm Controller...")
 		chart.Register(ctx,
 			systemNamespace,
 			opts.ControllerName,
 			appCtx.K8s,
-			appCtx.Apply,
+			// respect the Job options in spec.helmOptions that cannot be configured via the HelmChart spec
+			appCtx.Apply.WithInjector(backend.NewHelmChartJobInjector(appCtx.HelmController.HelmChart().Cache())),
 			recorder,
 			appCtx.HelmController.HelmChart(),
 			appCtx.HelmController.HelmChart().Cache(),
@@ -213,6 +306,11 @@ func Register(ctx context.Context, systemNamespace string, cfg clientcmd.ClientC
 	return nil
 }
 
+// usesHelmController returns whether the configured deployment backend relies on a Helm Controller to deploy HelmCharts
+func usesHelmController(opts common.Options) bool {
+	return len(opts.DeploymentBackend) == 0 || opts.DeploymentBackend == backend.HelmControllerBackend
+}
+
 func controllerFactory(rest *rest.Config) (controller.SharedControllerFactory, error) {
 	rateLimit := workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 60*time.Second)
 	clientFactory, err := client.NewSharedClientFactory(rest, nil)
@@ -266,6 +364,14 @@ func newContext(cfg clientcmd.ClientConfig, systemNamespace string, opts common.
 	}
 	corev := core.Core().V1()
 
+	apps, err := apps.NewFactoryFromConfigWithOptions(client, &generic.FactoryOptions{
+		SharedControllerFactory: scf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	appsv := apps.Apps().V1()
+
 	networking, err := networking.NewFactoryFromConfigWithOptions(client, &generic.FactoryOptions{
 		SharedControllerFactory: scf,
 	})
@@ -274,10 +380,18 @@ func newContext(cfg clientcmd.ClientConfig, systemNamespace string, opts common.
 	}
 	networkingv := networking.Networking().V1()
 
+	quota, err := hpocore.NewFactoryFromConfigWithOptions(client, &generic.FactoryOptions{
+		SharedControllerFactory: scf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	quotav := quota.Core().V1()
+
 	// Helm Project Controller
 
 	var namespace string // by default, this is unset so we watch everything
-	if len(opts.ProjectLabel) == 0 {
+	if len(opts.ProjectLabel) == 0 && !opts.ProjectCRD {
 		// we only need to watch the systemNamespace
 		namespace = systemNamespace
 	}
@@ -339,7 +453,9 @@ func newContext(cfg clientcmd.ClientConfig, systemNamespace string, opts common.
 		Dynamic:    dynamic,
 		K8s:        k8s,
 		Core:       corev,
+		Apps:       appsv,
 		Networking: networkingv,
+		Quota:      quotav,
 
 		HelmLocker:        helmlockerv,
 		ObjectSetRegister: objectSetRegister,
@@ -355,7 +471,9 @@ func newContext(cfg clientcmd.ClientConfig, systemNamespace string, opts common.
 		ClientConfig: cfg,
 		starters: []start.Starter{
 			core,
+			apps,
 			networking,
+			quota,
 			batch,
 			rbac,
 			helm,
@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 )
@@ -26,6 +27,7 @@ type handler struct {
 	opts            common.Options
 
 	systemNamespaceTracker              Tracker
+	systemNamespaceSelector             labels.Selector
 	projectRegistrationNamespaceTracker Tracker
 
 	namespaces            corecontroller.NamespaceController
@@ -33,6 +35,8 @@ type handler struct {
 	configmaps            corecontroller.ConfigMapController
 	projectHelmCharts     helmprojectcontroller.ProjectHelmChartController
 	projectHelmChartCache helmprojectcontroller.ProjectHelmChartCache
+	projects              helmprojectcontroller.ProjectController
+	projectCache          helmprojectcontroller.ProjectCache
 
 	projectRegistrationNamespaceApplyinator applier.Applyinator
 }
@@ -47,6 +51,8 @@ func Register(
 	configmaps corecontroller.ConfigMapController,
 	projectHelmCharts helmprojectcontroller.ProjectHelmChartController,
 	projectHelmChartCache helmprojectcontroller.ProjectHelmChartCache,
+	projects helmprojectcontroller.ProjectController,
+	projectCache helmprojectcontroller.ProjectCache,
 	dynamic dynamic.Interface,
 ) ProjectGetter {
 
@@ -59,12 +65,15 @@ func Register(
 		questionsYaml:                       questionsYaml,
 		opts:                                opts,
 		systemNamespaceTracker:              NewTracker(),
+		systemNamespaceSelector:             opts.GetSystemNamespaceSelector(),
 		projectRegistrationNamespaceTracker: NewTracker(),
 		namespaces:                          namespaces,
 		namespaceCache:                      namespaceCache,
 		configmaps:                          configmaps,
 		projectHelmCharts:                   projectHelmCharts,
 		projectHelmChartCache:               projectHelmChartCache,
+		projects:                            projects,
+		projectCache:                        projectCache,
 	}
 
 	// note: this implements a workqueue that ensures that applies only happen once at a time even if a bunch of namespaces in a project
@@ -78,10 +87,10 @@ func Register(
 
 	h.initIndexers()
 
-	if len(opts.ProjectLabel) == 0 {
+	if len(opts.ProjectLabel) == 0 && !opts.ProjectCRD {
 		namespaces.OnChange(ctx, "on-namespace-change", h.OnSingleNamespaceChange)
 
-		return NewSingleNamespaceProjectGetter(systemNamespace, opts.SystemNamespaces, namespaces)
+		return NewSingleNamespaceProjectGetter(systemNamespace, opts.SystemNamespaces, h.isDynamicSystemNamespace, namespaces)
 	}
 
 	// the namespaceApply is only needed in a multi-namespace setup
@@ -92,6 +101,23 @@ func Register(
 		WithCacheTypes(namespaces).
 		WithNoDeleteGVK(namespaces.GroupVersionKind())
 
+	if opts.ProjectCRD {
+		namespaces.OnChange(ctx, "on-namespace-change", h.OnProjectNamespaceChange)
+
+		projects.OnChange(ctx, "on-project-change", h.OnProjectChange)
+
+		projects.OnRemove(ctx, "on-project-remove", h.OnProjectRemove)
+
+		h.initSystemNamespaces(h.opts.SystemNamespaces, h.systemNamespaceTracker)
+
+		err := h.initProjects()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		return NewProjectBasedProjectGetter(h.isProjectRegistrationNamespace, h.isSystemNamespace, h.namespaceCache, h.projectCache)
+	}
+
 	namespaces.OnChange(ctx, "on-namespace-change", h.OnMultiNamespaceChange)
 
 	h.initSystemNamespaces(h.opts.SystemNamespaces, h.systemNamespaceTracker)
@@ -101,7 +127,7 @@ func Register(
 		logrus.Fatal(err)
 	}
 
-	return NewLabelBasedProjectGetter(h.opts.ProjectLabel, h.isProjectRegistrationNamespace, h.isSystemNamespace, h.namespaces)
+	return NewLabelBasedProjectGetter(h.opts.GetProjectLabels(), h.isProjectRegistrationNamespace, h.isSystemNamespace, h.namespaces)
 }
 
 // Single Namespace Handler
@@ -112,6 +138,10 @@ func (h *handler) OnSingleNamespaceChange(_ string, namespace *corev1.Namespace)
 
 		logrus.Debugf("Enqueue system namespace to ensure that rolebindings are updated in OnSingleNamespaceChange: %s", h.systemNamespace)
 		h.namespaces.Enqueue(h.systemNamespace)
+		if h.hasDynamicSystemNamespaces() {
+			// the namespace may have started or stopped being a system namespace
+			return namespace, h.enqueueProjectHelmChartsSelectingNamespace(namespace)
+		}
 		return namespace, nil
 	}
 	if namespace.DeletionTimestamp != nil {
@@ -153,9 +183,10 @@ func (h *handler) OnMultiNamespaceChange(_ string, namespace *corev1.Namespace)
 		}
 		return namespace, nil
 	case h.isSystemNamespace(namespace):
-		// nothing to do, we always ignore system namespaces
+		// we always ignore system namespaces, but the namespace may have just become a system namespace
+		// (e.g. if a label matching the SystemNamespaceSelector was added), so existing projects it belonged to are reconciled
 		logrus.Debugf("Ignoring system namespace: %s", namespace)
-		return namespace, nil
+		return namespace, h.applyExistingProjectRegistrationNamespacesForNamespace(namespace)
 	default:
 		err := h.applyProjectRegistrationNamespaceForNamespace(namespace)
 		if err != nil {
@@ -177,11 +208,11 @@ func (h *handler) enqueueProjectNamespaces(projectRegistrationNamespace *corev1.
 		return nil
 	}
 	// projectRegistrationNamespace was modified or removed, so we should re-enqueue any namespaces tied to it
-	projectID, ok := expectedNamespace.Labels[h.opts.ProjectLabel]
+	projectLabel, projectID, ok := h.opts.GetProjectLabelAndID(expectedNamespace.Labels)
 	if !ok {
 		return fmt.Errorf("could not find project that projectRegistrationNamespace %s is tied to", projectRegistrationNamespace.Name)
 	}
-	projectNamespaces, err := h.namespaceCache.GetByIndex(NamespacesByProjectExcludingRegistrationID, projectID)
+	projectNamespaces, err := h.namespaceCache.GetByIndex(NamespacesByProjectExcludingRegistrationID, getProjectKey(projectLabel, projectID))
 	if err != nil {
 		return err
 	}
@@ -202,38 +233,39 @@ func (h *handler) applyProjectRegistrationNamespaceForNamespace(namespace *corev
 		logrus.Debugf("Error updating namespace %s with %s labels", namespace, projectID)
 		return nil
 	}
-	if !inProject {
-		return nil
-	}
 
-	logrus.Infof("Calling projectRegistrationNamespaceApplyinator for project %s", projectID)
-	// Note: why do we use an Applyinator.Apply here instead of just directly
-	// running h.applyProjectRegistrationNamespace?
-	//
-	// If we ran the logic for applying a Project Registration Namespace here,
-	// on every time a Project Namespace was re-enqueued, that would result in projects
-	// with a lot of namespaces all trying to run the exact same apply operation
-	// at the exact same time; however, the client-go workqueue implementation
-	// (which lasso controllers use under the hood as well) allow us to add the registration
-	// namespace to the queue with certain guarantees, namely this one that we need:
-	//
-	// * Stingy: a single item will not be processed multiple times concurrently,
-	// and if an item is added multiple times before it can be processed, it
-	// will only be processed once.
-	//
-	// This ensures that the actual application of a project registration namespace
-	// will only happen once, regardless of how many enqueues, which prevents us
-	// from hammering wrangler.Apply operations and forcing wrangler.Apply to engage
-	// in rate limiting (and output noisy logs)
-	h.projectRegistrationNamespaceApplyinator.Apply(projectID)
+	for _, projectKey := range h.getProjectKeysFromNamespaceLabels(namespace) {
+		logrus.Infof("Calling projectRegistrationNamespaceApplyinator for project %s", projectKey)
+		// Note: why do we use an Applyinator.Apply here instead of just directly
+		// running h.applyProjectRegistrationNamespace?
+		//
+		// If we ran the logic for applying a Project Registration Namespace here,
+		// on every time a Project Namespace was re-enqueued, that would result in projects
+		// with a lot of namespaces all trying to run the exact same apply operation
+		// at the exact same time; however, the client-go workqueue implementation
+		// (which lasso controllers use under the hood as well) allow us to add the registration
+		// namespace to the queue with certain guarantees, namely this one that we need:
+		//
+		// * Stingy: a single item will not be processed multiple times concurrently,
+		// and if an item is added multiple times before it can be processed, it
+		// will only be processed once.
+		//
+		// This ensures that the actual application of a project registration namespace
+		// will only happen once, regardless of how many enqueues, which prevents us
+		// from hammering wrangler.Apply operations and forcing wrangler.Apply to engage
+		// in rate limiting (and output noisy logs)
+		h.projectRegistrationNamespaceApplyinator.Apply(projectKey)
+	}
 
 	return nil
 }
 
-func (h *handler) applyProjectRegistrationNamespace(projectID string) error {
+func (h *handler) applyProjectRegistrationNamespace(projectKey string) error {
+	projectLabel, projectID := parseProjectKey(projectKey)
+
 	// Calculate whether to add the orphaned label
 	var isOrphaned bool
-	projectNamespaces, err := h.namespaceCache.GetByIndex(NamespacesByProjectExcludingRegistrationID, projectID)
+	projectNamespaces, err := h.namespaceCache.GetByIndex(NamespacesByProjectExcludingRegistrationID, projectKey)
 	if err != nil {
 		return err
 	}
@@ -250,35 +282,44 @@ func (h *handler) applyProjectRegistrationNamespace(projectID string) error {
 		isOrphaned = true
 	}
 
+	_, err = h.reconcileProjectRegistrationNamespace(projectLabel, projectID, isOrphaned)
+	return err
+}
+
+// reconcileProjectRegistrationNamespace applies the Project Registration Namespace of a project identified by the provided project label
+// along with its data and re-enqueues all ProjectHelmCharts within it
+func (h *handler) reconcileProjectRegistrationNamespace(projectLabel, projectID string, isOrphaned bool) (*corev1.Namespace, error) {
 	// get the resources and validate them
-	projectRegistrationNamespace := h.getProjectRegistrationNamespace(projectID, isOrphaned)
-	// ensure that the projectRegistrationNamespace created from this projectID is valid
-	if len(projectRegistrationNamespace.Name) > 63 {
-		// ensure that we don't try to create a namespace with too big of a name
-		logrus.Errorf("could not apply namespace with name %s: name is above 63 characters", projectRegistrationNamespace.Name)
-		return nil
+	projectRegistrationNamespace, err := h.getProjectRegistrationNamespace(projectLabel, projectID, isOrphaned)
+	if err != nil {
+		return nil, err
 	}
 
 	// Trigger the apply and set the projectRegistrationNamespace
 	err = h.namespaceApply.ApplyObjects(projectRegistrationNamespace)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// get the projectRegistrationNamespace after applying to get a valid object to pass in as the owner of the next apply
 	projectRegistrationNamespace, err = h.namespaces.Get(projectRegistrationNamespace.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("unable to get project registration namespace from cache after create: %s", err)
+		return nil, fmt.Errorf("unable to get project registration namespace from cache after create: %s", err)
 	}
 	h.projectRegistrationNamespaceTracker.Set(projectRegistrationNamespace)
 
+	// ensure that Project Registration Namespaces created based on a legacy template are still recognized
+	if err := h.registerLegacyProjectRegistrationNamespaces(projectLabel, projectID, projectRegistrationNamespace.Name); err != nil {
+		return nil, fmt.Errorf("unable to register legacy project registration namespaces for project %s: %s", projectID, err)
+	}
+
 	if projectRegistrationNamespace.DeletionTimestamp != nil {
 		// When a namespace gets deleted, the ConfigMap deployed in that namespace and all ProjectHelmCharts should also get deleted
 		// Therefore, we do not need to apply anything in this situation to avoid spamming logs with trying to apply
 		// a resource to a namespace that is being terminated
 		//
 		// We expect this to be recalled when the project registration namespace is recreated anyways
-		return nil
+		return projectRegistrationNamespace, nil
 	}
 
 	// Trigger applying the data for this projectRegistrationNamespace
@@ -286,16 +327,16 @@ func (h *handler) applyProjectRegistrationNamespace(projectID string) error {
 	objs = append(objs, h.getConfigMap(projectID, projectRegistrationNamespace))
 	err = h.configureApplyForNamespace(projectRegistrationNamespace).ApplyObjects(objs...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// ensure that all ProjectHelmCharts are re-enqueued within this projectRegistrationNamespace
 	err = h.enqueueProjectHelmChartsForNamespace(projectRegistrationNamespace)
 	if err != nil {
-		return fmt.Errorf("unable to re-enqueue ProjectHelmCharts on reconciling change to namespaces in project %s: %s", projectID, err)
+		return nil, fmt.Errorf("unable to re-enqueue ProjectHelmCharts on reconciling change to namespaces in project %s: %s", projectID, err)
 	}
 
-	return nil
+	return projectRegistrationNamespace, nil
 }
 
 func (h *handler) updateNamespaceWithHelmOperatorProjectLabel(namespace *corev1.Namespace, projectID string, inProject bool) error {
@@ -353,6 +394,9 @@ func (h *handler) isSystemNamespace(namespace *corev1.Namespace) bool {
 	if isTrackedSystemNamespace {
 		return true
 	}
+	if h.isDynamicSystemNamespace(namespace) {
+		return true
+	}
 
 	var systemProjectLabelValues []string
 	if len(h.opts.SystemProjectLabelValues) != 0 {
@@ -2,9 +2,13 @@ package namespace
 
 import (
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
 	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -29,11 +33,13 @@ type ProjectGetter interface {
 type Checker func(namespace *corev1.Namespace) bool
 
 // NewLabelBasedProjectGetter returns a ProjectGetter that gets target project namespaces that meet the following criteria:
-// 1) Must have the same projectLabel value as the namespace where the ProjectHelmChart lives in
+// 1) Must have the same value for the first of the projectLabels found on the namespace where the ProjectHelmChart lives in
 // 2) Must not be a project registration namespace
 // 3) Must not be a system namespace
+//
+// Note: projectLabels should be ordered from the innermost to the outermost level of the project hierarchy
 func NewLabelBasedProjectGetter(
-	projectLabel string,
+	projectLabels []string,
 	isProjectRegistrationNamespace Checker,
 	isSystemNamespace Checker,
 	namespaces corecontroller.NamespaceController,
@@ -55,9 +61,16 @@ func NewLabelBasedProjectGetter(
 				}
 				return nil, err
 			}
-			projectLabelValue, ok := namespace.Labels[projectLabel]
-			if !ok {
-				return nil, fmt.Errorf("could not find value of label %s in namespace %s", projectLabel, namespace.Name)
+			var projectLabel, projectLabelValue string
+			for _, label := range projectLabels {
+				value, ok := namespace.Labels[label]
+				if ok {
+					projectLabel, projectLabelValue = label, value
+					break
+				}
+			}
+			if len(projectLabel) == 0 {
+				return nil, fmt.Errorf("could not find value of project labels %s in namespace %s", strings.Join(projectLabels, ", "), namespace.Name)
 			}
 			return namespaces.List(metav1.ListOptions{
 				LabelSelector: fmt.Sprintf("%s=%s", projectLabel, projectLabelValue),
@@ -69,10 +82,11 @@ func NewLabelBasedProjectGetter(
 // NewSingleNamespaceProjectGetter returns a ProjectGetter that gets target project namespaces that meet the following criteria:
 // 1) Must match the labels provided on spec.projectNamespaceSelector of the projectHelmChart in question
 // 2) Must not be the registration namespace
-// 3) Must not be part of the provided systemNamespaces
+// 3) Must not be part of the provided systemNamespaces or be identified as a system namespace by isDynamicSystemNamespace
 func NewSingleNamespaceProjectGetter(
 	registrationNamespace string,
 	systemNamespaces []string,
+	isDynamicSystemNamespace Checker,
 	namespaces corecontroller.NamespaceController,
 ) ProjectGetter {
 	isSystemNamespace := make(map[string]bool)
@@ -87,8 +101,8 @@ func NewSingleNamespaceProjectGetter(
 			return namespace.Name == registrationNamespace
 		},
 		isSystemNamespace: func(namespace *corev1.Namespace) bool {
-			// only track explicit systemNamespaces
-			return isSystemNamespace[namespace.Name]
+			// only track explicit systemNamespaces and namespaces that match the system namespace patterns or selector
+			return isSystemNamespace[namespace.Name] || isDynamicSystemNamespace(namespace)
 		},
 
 		getProjectNamespaces: func(projectHelmChart *v1alpha1.ProjectHelmChart) (*corev1.NamespaceList, error) {
@@ -119,6 +133,81 @@ func NewSingleNamespaceProjectGetter(
 	}
 }
 
+// NewProjectBasedProjectGetter returns a ProjectGetter that gets target project namespaces that meet the following criteria:
+// 1) Must be listed in spec.namespaces or match spec.namespaceSelector of the Project tied to the namespace where the ProjectHelmChart lives in
+// 2) Must not be a project registration namespace
+// 3) Must not be a system namespace
+func NewProjectBasedProjectGetter(
+	isProjectRegistrationNamespace Checker,
+	isSystemNamespace Checker,
+	namespaceCache corecontroller.NamespaceCache,
+	projectCache helmprojectcontroller.ProjectCache,
+) ProjectGetter {
+	return &projectGetter{
+		isProjectRegistrationNamespace: isProjectRegistrationNamespace,
+		isSystemNamespace:              isSystemNamespace,
+
+		getProjectNamespaces: func(projectHelmChart *v1alpha1.ProjectHelmChart) (*corev1.NamespaceList, error) {
+			// source of truth is the Project tied to the namespace that the ProjectHelmChart lives within
+			namespace, err := namespaceCache.Get(projectHelmChart.Namespace)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					// The projectHelmChart is not in a namespace that exists anymore, this implies it may have been deleted
+					// Therefore, there are no project namespaces associated with this ProjectHelmChart
+					return nil, nil
+				}
+				return nil, err
+			}
+			projectID, ok := namespace.Labels[common.HelmProjectOperatorProjectLabel]
+			if !ok {
+				return nil, fmt.Errorf("could not find value of label %s in namespace %s", common.HelmProjectOperatorProjectLabel, namespace.Name)
+			}
+			project, err := projectCache.Get(projectID)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					// The Project has been deleted, so there are no project namespaces associated with this ProjectHelmChart
+					return nil, nil
+				}
+				return nil, err
+			}
+			if project.DeletionTimestamp != nil {
+				return nil, nil
+			}
+			namespaces, err := namespaceCache.List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			namespaceList := &corev1.NamespaceList{}
+			for _, ns := range namespaces {
+				inProject, err := isNamespaceInProject(project, ns)
+				if err != nil {
+					return nil, err
+				}
+				if !inProject {
+					continue
+				}
+				namespaceList.Items = append(namespaceList.Items, *ns)
+			}
+			return namespaceList, nil
+		},
+	}
+}
+
+// isNamespaceInProject returns whether a namespace is listed in spec.namespaces or matches spec.namespaceSelector of a Project
+func isNamespaceInProject(project *v1alpha1.Project, namespace *corev1.Namespace) (bool, error) {
+	if slices.Contains(project.Spec.Namespaces, namespace.Name) {
+		return true, nil
+	}
+	if project.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(project.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespaceSelector on project %s: %s", project.Name, err)
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
 type projectGetter struct {
 	namespaces corecontroller.NamespaceController
 
@@ -1,20 +1,35 @@
 package namespace
 
 import (
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 	corev1 "k8s.io/api/core/v1"
 )
 
 const (
-	// NamespacesByProjectExcludingRegistrationID is an index mapping namespaces to project that they belong into
-	// The index will omit any namespaces considered to be the Project Registration namespace or a system namespace
+	// NamespacesByProjectExcludingRegistrationID is an index mapping namespaces to the projects that they belong into, one for each level
+	// of the project hierarchy (see getProjectKey). The index will omit any namespaces considered to be the Project Registration namespace
+	// or a system namespace
 	NamespacesByProjectExcludingRegistrationID = "helm.cattle.io/namespaces-by-project-id-excluding-registration"
+
+	// ProjectByNamespace is an index mapping Projects to the namespaces that they list in spec.namespaces or were last observed to
+	// contain in status.namespaces. Projects that provide spec.namespaceSelector are additionally indexed under ProjectSelectsNamespaces
+	// since whether they select a namespace can only be evaluated against the labels of that namespace
+	ProjectByNamespace = "helm.cattle.io/project-by-namespace"
+
+	// ProjectSelectsNamespaces is the value of ProjectByNamespace for Projects that provide spec.namespaceSelector
+	// Note: this is not a valid namespace name, so it never collides with the name of a namespace
+	ProjectSelectsNamespaces = "*"
 )
 
 // initIndexers initializes indexers that allow for more efficient computations on related resources without relying on additional
 // calls to be made to the Kubernetes API by referencing the cache instead
 func (h *handler) initIndexers() {
 	h.namespaceCache.AddIndexer(NamespacesByProjectExcludingRegistrationID, h.namespaceToProjectIDExcludingRegistration)
+
+	if h.opts.ProjectCRD {
+		h.projectCache.AddIndexer(ProjectByNamespace, h.projectToNamespace)
+	}
 }
 
 func (h *handler) namespaceToProjectIDExcludingRegistration(namespace *corev1.Namespace) ([]string, error) {
@@ -32,10 +47,18 @@ func (h *handler) namespaceToProjectIDExcludingRegistration(namespace *corev1.Na
 		// to be scoped to namespaces that are project registration namespaces
 		return nil, nil
 	}
-	projectID, inProject := h.getProjectIDFromNamespaceLabels(namespace)
-	if !inProject {
-		// nothing to do
+	return h.getProjectKeysFromNamespaceLabels(namespace), nil
+}
+
+func (h *handler) projectToNamespace(project *v1alpha1.Project) ([]string, error) {
+	if project == nil {
 		return nil, nil
 	}
-	return []string{projectID}, nil
+	var namespaces []string
+	namespaces = append(namespaces, project.Spec.Namespaces...)
+	namespaces = append(namespaces, project.Status.Namespaces...)
+	if project.Spec.NamespaceSelector != nil {
+		namespaces = append(namespaces, ProjectSelectsNamespaces)
+	}
+	return namespaces, nil
 }
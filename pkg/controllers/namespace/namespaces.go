@@ -2,10 +2,12 @@ package namespace
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // initSystemNamespaces initializes all System Namespaces on the Tracker
@@ -46,3 +48,25 @@ func (h *handler) initProjectRegistrationNamespaces() error {
 	}
 	return nil
 }
+
+// isDynamicSystemNamespace returns whether a namespace is a system namespace based on its name matching one of the SystemNamespacePatterns
+// or its labels matching the SystemNamespaceSelector
+func (h *handler) isDynamicSystemNamespace(namespace *corev1.Namespace) bool {
+	if namespace == nil {
+		return false
+	}
+	for _, systemNamespacePattern := range h.opts.SystemNamespacePatterns {
+		if matched, _ := path.Match(systemNamespacePattern, namespace.Name); matched {
+			return true
+		}
+	}
+	if h.systemNamespaceSelector == nil {
+		return false
+	}
+	return h.systemNamespaceSelector.Matches(labels.Set(namespace.Labels))
+}
+
+// hasDynamicSystemNamespaces returns whether system namespaces can be identified based on SystemNamespacePatterns or the SystemNamespaceSelector
+func (h *handler) hasDynamicSystemNamespaces() bool {
+	return len(h.opts.SystemNamespacePatterns) > 0 || h.systemNamespaceSelector != nil
+}
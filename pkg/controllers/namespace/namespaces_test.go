@@ -0,0 +1,81 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestIsDynamicSystemNamespace(t *testing.T) {
+	systemNamespaceSelector, err := labels.Parse("example.com/system=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name                    string
+		systemNamespacePatterns []string
+		systemNamespaceSelector labels.Selector
+		namespace               *corev1.Namespace
+		expected                bool
+	}{
+		{
+			name:                    "nil namespace",
+			systemNamespacePatterns: []string{"*"},
+		},
+		{
+			name:      "no patterns and nil selector",
+			namespace: newNamespace("kube-system", map[string]string{"example.com/system": "true"}),
+		},
+		{
+			name:                    "matches glob pattern",
+			systemNamespacePatterns: []string{"cattle-*-system", "kube-*"},
+			namespace:               newNamespace("cattle-fleet-system", nil),
+			expected:                true,
+		},
+		{
+			name:                    "does not match glob pattern",
+			systemNamespacePatterns: []string{"cattle-*-system"},
+			namespace:               newNamespace("cattle-project-p-example", nil),
+		},
+		{
+			name:                    "invalid glob pattern",
+			systemNamespacePatterns: []string{"cattle-[-system"},
+			namespace:               newNamespace("cattle-fleet-system", nil),
+		},
+		{
+			name:                    "matches selector",
+			systemNamespaceSelector: systemNamespaceSelector,
+			namespace:               newNamespace("monitoring", map[string]string{"example.com/system": "true"}),
+			expected:                true,
+		},
+		{
+			name:                    "does not match selector",
+			systemNamespaceSelector: systemNamespaceSelector,
+			namespace:               newNamespace("monitoring", map[string]string{"example.com/system": "false"}),
+		},
+		{
+			name:                    "matches selector but not glob pattern",
+			systemNamespacePatterns: []string{"kube-*"},
+			systemNamespaceSelector: systemNamespaceSelector,
+			namespace:               newNamespace("monitoring", map[string]string{"example.com/system": "true"}),
+			expected:                true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						SystemNamespacePatterns: tc.systemNamespacePatterns,
+					},
+				},
+				systemNamespaceSelector: tc.systemNamespaceSelector,
+			}
+			if isSystemNamespace := h.isDynamicSystemNamespace(tc.namespace); isSystemNamespace != tc.expected {
+				t.Errorf("expected system namespace to be %t, found %t", tc.expected, isSystemNamespace)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package namespace
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Project Handlers
+// Note: these are only registered if projects are identified based on Project objects (see RuntimeOptions.ProjectCRD)
+
+func (h *handler) OnProjectChange(_ string, project *v1alpha1.Project) (*v1alpha1.Project, error) {
+	if project == nil || project.DeletionTimestamp != nil {
+		return project, nil
+	}
+	if err := validateProject(project); err != nil {
+		// the Project will be re-enqueued once it is modified, so there is no need to retry
+		logrus.Errorf("Unable to register project %s: %s", project.Name, err)
+		return project, nil
+	}
+	projectRegistrationNamespace, err := h.reconcileProjectRegistrationNamespace(h.getProjectRegistrationNamespaceProjectLabel(), project.Name, false)
+	if err != nil {
+		return project, err
+	}
+	projectNamespaces, err := h.getProjectNamespaces(project)
+	if err != nil {
+		return project, err
+	}
+	if project.Status.RegistrationNamespace == projectRegistrationNamespace.Name && slices.Equal(project.Status.Namespaces, projectNamespaces) {
+		return project, nil
+	}
+	projectCopy := project.DeepCopy()
+	projectCopy.Status.RegistrationNamespace = projectRegistrationNamespace.Name
+	projectCopy.Status.Namespaces = projectNamespaces
+	return h.projects.UpdateStatus(projectCopy)
+}
+
+func (h *handler) OnProjectRemove(_ string, project *v1alpha1.Project) (*v1alpha1.Project, error) {
+	if project == nil {
+		return nil, nil
+	}
+	_, projectRegistrationNamespaceName, err := h.getProjectRegistrationNamespaceName(h.opts.GetProjectRegistrationNamespaceTemplate(), project.Name)
+	if err != nil {
+		return project, err
+	}
+	if _, err := h.namespaceCache.Get(projectRegistrationNamespaceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			// nothing to orphan
+			return project, nil
+		}
+		return project, err
+	}
+	// note: we never delete Project Registration Namespaces; we only mark them as orphaned, which also re-enqueues
+	// all ProjectHelmCharts within them to remove their releases since the Project no longer has any namespaces
+	logrus.Infof("Project %s was removed; marking project registration namespace %s as orphaned", project.Name, projectRegistrationNamespaceName)
+	_, err = h.reconcileProjectRegistrationNamespace(h.getProjectRegistrationNamespaceProjectLabel(), project.Name, true)
+	return project, err
+}
+
+// Project Namespace Handler
+
+func (h *handler) OnProjectNamespaceChange(_ string, namespace *corev1.Namespace) (*corev1.Namespace, error) {
+	if namespace == nil {
+		return namespace, nil
+	}
+
+	switch {
+	// note: the check for a project registration namespace must happen before
+	// we check for whether it is a system namespace to ensure that a project
+	// registration namespace that is modified or removed is always recreated
+	case h.isProjectRegistrationNamespace(namespace):
+		// ensure that we are working with the projectRegistrationNamespace that we expect, not the one we found
+		expectedNamespace, exists := h.projectRegistrationNamespaceTracker.Get(namespace.Name)
+		if namespace.DeletionTimestamp != nil {
+			logrus.Debugf("%s has deletion timestamp %v in OnProjectNamespaceChange()", namespace.Name, namespace.DeletionTimestamp)
+			h.projectRegistrationNamespaceTracker.Delete(namespace)
+		}
+		if !exists {
+			return namespace, nil
+		}
+		projectID, ok := expectedNamespace.Labels[common.HelmProjectOperatorProjectLabel]
+		if !ok {
+			return namespace, fmt.Errorf("could not find project that projectRegistrationNamespace %s is tied to", namespace.Name)
+		}
+		// projectRegistrationNamespace was modified or removed, so we should re-enqueue the Project tied to it
+		h.projects.Enqueue(projectID)
+		return namespace, nil
+	case h.isSystemNamespace(namespace):
+		// we always ignore system namespaces, but the namespace may have just become a system namespace
+		// (e.g. if a label matching the SystemNamespaceSelector was added), so Projects that it belonged to are re-enqueued
+		logrus.Debugf("Ignoring system namespace: %s", namespace.Name)
+		return namespace, h.enqueueProjectsForNamespace(namespace)
+	default:
+		return namespace, h.enqueueProjectsForNamespace(namespace)
+	}
+}
+
+// enqueueProjectsForNamespace enqueues all Projects that the namespace belongs to or was previously observed to belong to
+func (h *handler) enqueueProjectsForNamespace(namespace *corev1.Namespace) error {
+	projects, err := h.projectCache.GetByIndex(ProjectByNamespace, namespace.Name)
+	if err != nil {
+		return err
+	}
+	selectingProjects, err := h.projectCache.GetByIndex(ProjectByNamespace, ProjectSelectsNamespaces)
+	if err != nil {
+		return err
+	}
+	for _, project := range selectingProjects {
+		inProject, err := isNamespaceInProject(project, namespace)
+		if err != nil {
+			logrus.Warnf("Unable to check whether namespace %s belongs to project %s: %s", namespace.Name, project.Name, err)
+			continue
+		}
+		if !inProject {
+			continue
+		}
+		projects = append(projects, project)
+	}
+	for _, project := range projects {
+		h.projects.Enqueue(project.Name)
+	}
+	return nil
+}
+
+// getProjectNamespaces returns the sorted names of the namespaces that are currently members of a Project
+func (h *handler) getProjectNamespaces(project *v1alpha1.Project) ([]string, error) {
+	namespaces, err := h.namespaceCache.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var projectNamespaces []string
+	for _, namespace := range namespaces {
+		if h.isProjectRegistrationNamespace(namespace) || h.isSystemNamespace(namespace) {
+			continue
+		}
+		inProject, err := isNamespaceInProject(project, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !inProject {
+			continue
+		}
+		projectNamespaces = append(projectNamespaces, namespace.Name)
+	}
+	sort.Strings(projectNamespaces)
+	return projectNamespaces, nil
+}
+
+// validateProject returns an error if a Project cannot be registered
+//
+// Note: the name of a Project is used as the projectID, which is recorded as the value of a label on its Project Registration Namespace
+// and in the projectNamespaceSelector provided to charts; unlike label values, the names of cluster-scoped objects can be longer than 63 characters
+func validateProject(project *v1alpha1.Project) error {
+	if errs := validation.IsValidLabelValue(project.Name); len(errs) > 0 {
+		return fmt.Errorf("name must be a valid label value: %s", strings.Join(errs, ", "))
+	}
+	if project.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(project.Spec.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespaceSelector: %s", err)
+		}
+	}
+	return nil
+}
+
+// initProjects creates the Project Registration Namespaces of all Projects and initializes them on the Tracker
+func (h *handler) initProjects() error {
+	projectList, err := h.projects.List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to list projects to create project registration namespaces: %s", err)
+	}
+	if projectList == nil {
+		return nil
+	}
+	logrus.Infof("Identifying and registering projectRegistrationNamespaces for Projects...")
+	// Q: Why don't we use Enqueue here?
+	//
+	// Similar to initProjectRegistrationNamespaces, we need to guarantee that all Project Registration Namespaces
+	// are tracked before providing the ProjectGetter interface to other controllers that need it.
+	for _, project := range projectList.Items {
+		if project.DeletionTimestamp != nil {
+			continue
+		}
+		if err := validateProject(&project); err != nil {
+			logrus.Errorf("Unable to register project %s: %s", project.Name, err)
+			continue
+		}
+		if _, err := h.reconcileProjectRegistrationNamespace(h.getProjectRegistrationNamespaceProjectLabel(), project.Name, false); err != nil {
+			return fmt.Errorf("unable to initialize projectRegistrationNamespaces before starting other handlers that utilize ProjectGetter: %s", err)
+		}
+	}
+	return nil
+}
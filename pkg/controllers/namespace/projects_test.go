@@ -0,0 +1,260 @@
+package namespace
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// fakeNamespaceCache is a NamespaceCache backed by the provided namespaces
+type fakeNamespaceCache struct {
+	corecontroller.NamespaceCache
+
+	namespaces []*corev1.Namespace
+}
+
+func (c *fakeNamespaceCache) Get(name string) (*corev1.Namespace, error) {
+	for _, namespace := range c.namespaces {
+		if namespace.Name == name {
+			return namespace, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("namespaces"), name)
+}
+
+func (c *fakeNamespaceCache) List(selector labels.Selector) ([]*corev1.Namespace, error) {
+	var namespaces []*corev1.Namespace
+	for _, namespace := range c.namespaces {
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// fakeProjectCache is a ProjectCache backed by the provided Projects that supports a single indexer
+type fakeProjectCache struct {
+	helmprojectcontroller.ProjectCache
+
+	projects []*v1alpha1.Project
+	indexer  func(project *v1alpha1.Project) ([]string, error)
+}
+
+func (c *fakeProjectCache) Get(name string) (*v1alpha1.Project, error) {
+	for _, project := range c.projects {
+		if project.Name == name {
+			return project, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("projects"), name)
+}
+
+func (c *fakeProjectCache) GetByIndex(_, key string) ([]*v1alpha1.Project, error) {
+	var projects []*v1alpha1.Project
+	for _, project := range c.projects {
+		indices, err := c.indexer(project)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index == key {
+				projects = append(projects, project)
+				break
+			}
+		}
+	}
+	return projects, nil
+}
+
+// fakeProjectController is a ProjectController that records the names of enqueued Projects
+type fakeProjectController struct {
+	helmprojectcontroller.ProjectController
+
+	enqueued []string
+}
+
+func (c *fakeProjectController) Enqueue(name string) {
+	c.enqueued = append(c.enqueued, name)
+}
+
+func newNamespace(name string, namespaceLabels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: namespaceLabels,
+		},
+	}
+}
+
+func newProject(name string, namespaces []string, namespaceSelector *metav1.LabelSelector) *v1alpha1.Project {
+	return &v1alpha1.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.ProjectSpec{
+			Namespaces:        namespaces,
+			NamespaceSelector: namespaceSelector,
+		},
+	}
+}
+
+func TestIsNamespaceInProject(t *testing.T) {
+	namespace := newNamespace("app", map[string]string{"team": "payments"})
+	testCases := []struct {
+		name      string
+		project   *v1alpha1.Project
+		inProject bool
+		expectErr bool
+	}{
+		{
+			name:      "listed in spec.namespaces",
+			project:   newProject("project", []string{"app"}, nil),
+			inProject: true,
+		},
+		{
+			name:    "not listed without a selector",
+			project: newProject("project", []string{"other"}, nil),
+		},
+		{
+			name:      "matches selector",
+			project:   newProject("project", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}),
+			inProject: true,
+		},
+		{
+			name:    "does not match selector",
+			project: newProject("project", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "billing"}}),
+		},
+		{
+			name: "invalid selector",
+			project: newProject("project", nil, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: "Invalid"},
+			}}),
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inProject, err := isNamespaceInProject(tc.project, namespace)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+			if inProject != tc.inProject {
+				t.Errorf("expected namespace in project to be %t, found %t", tc.inProject, inProject)
+			}
+		})
+	}
+}
+
+func TestValidateProject(t *testing.T) {
+	testCases := []struct {
+		name      string
+		project   *v1alpha1.Project
+		expectErr bool
+	}{
+		{
+			name:    "valid project",
+			project: newProject("payments.example.com", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}),
+		},
+		{
+			name:      "name longer than a label value",
+			project:   newProject(strings.Repeat("a", 64), nil, nil),
+			expectErr: true,
+		},
+		{
+			name: "invalid selector",
+			project: newProject("payments", nil, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpIn},
+			}}),
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateProject(tc.project)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestEnqueueProjectsForNamespace(t *testing.T) {
+	previousMember := newProject("previous", nil, nil)
+	previousMember.Status.Namespaces = []string{"app"}
+	projects := []*v1alpha1.Project{
+		newProject("listed", []string{"app"}, nil),
+		newProject("unrelated", []string{"other"}, nil),
+		newProject("selecting", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}),
+		newProject("not-selecting", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "billing"}}),
+		previousMember,
+	}
+	h := &handler{}
+	projectController := &fakeProjectController{}
+	h.projects = projectController
+	h.projectCache = &fakeProjectCache{projects: projects, indexer: h.projectToNamespace}
+
+	if err := h.enqueueProjectsForNamespace(newNamespace("app", map[string]string{"team": "payments"})); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(projectController.enqueued)
+	if expected := []string{"listed", "previous", "selecting"}; !reflect.DeepEqual(projectController.enqueued, expected) {
+		t.Errorf("expected %v to be enqueued, found %v", expected, projectController.enqueued)
+	}
+}
+
+func TestProjectBasedProjectGetter(t *testing.T) {
+	registrationNamespace := newNamespace("cattle-project-payments", map[string]string{common.HelmProjectOperatorProjectLabel: "payments"})
+	systemNamespace := newNamespace("kube-system", map[string]string{"team": "payments"})
+	namespaceCache := &fakeNamespaceCache{
+		namespaces: []*corev1.Namespace{
+			registrationNamespace,
+			systemNamespace,
+			newNamespace("listed", nil),
+			newNamespace("selected", map[string]string{"team": "payments"}),
+			newNamespace("other", map[string]string{"team": "billing"}),
+		},
+	}
+	projectCache := &fakeProjectCache{
+		projects: []*v1alpha1.Project{
+			newProject("payments", []string{"listed"}, &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}),
+		},
+	}
+	getter := NewProjectBasedProjectGetter(
+		func(namespace *corev1.Namespace) bool { return namespace.Name == registrationNamespace.Name },
+		func(namespace *corev1.Namespace) bool { return namespace.Name == systemNamespace.Name },
+		namespaceCache,
+		projectCache,
+	)
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: registrationNamespace.Name,
+		},
+	}
+	targetNamespaces, err := getter.GetTargetProjectNamespaces(projectHelmChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"listed", "selected"}; !reflect.DeepEqual(targetNamespaces, expected) {
+		t.Errorf("expected target namespaces %v, found %v", expected, targetNamespaces)
+	}
+
+	projectCache.projects = nil
+	targetNamespaces, err = getter.GetTargetProjectNamespaces(projectHelmChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targetNamespaces) != 0 {
+		t.Errorf("expected no target namespaces once the Project is deleted, found %v", targetNamespaces)
+	}
+}
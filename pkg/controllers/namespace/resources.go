@@ -6,6 +6,7 @@ import (
 
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -13,18 +14,57 @@ import (
 // The only exception is namespaces since those are handled by the main controller OnChange
 
 // getProjectRegistrationNamespace returns the namespace created on behalf of a new Project that has been identified based on
-// unique values observed for all namespaces with the label h.opts.ProjectLabel
-func (h *handler) getProjectRegistrationNamespace(projectID string, isOrphaned bool) *corev1.Namespace {
-	if len(h.opts.ProjectLabel) == 0 {
-		return nil
+// unique values observed for all namespaces with the provided project label (h.opts.ProjectLabel or one of h.opts.ParentProjectLabels)
+// or based on a Project object
+func (h *handler) getProjectRegistrationNamespace(projectLabel, projectID string, isOrphaned bool) (*corev1.Namespace, error) {
+	if len(h.opts.ProjectLabel) == 0 && !h.opts.ProjectCRD {
+		return nil, nil
+	}
+	originalName, name, err := h.getProjectRegistrationNamespaceName(h.opts.GetProjectRegistrationNamespaceTemplate(), projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.validateProjectRegistrationNamespaceLevel(projectLabel, projectID, name); err != nil {
+		return nil, err
+	}
+	var annotations map[string]string
+	if len(h.opts.ProjectLabel) > 0 && projectLabel == h.opts.ProjectLabel {
+		// only Project Registration Namespaces of the innermost level are imported into the project
+		annotations = common.GetProjectNamespaceAnnotations(projectID, h.opts.ProjectLabel, h.opts.ClusterID)
 	}
 	return &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        fmt.Sprintf(common.ProjectRegistrationNamespaceFmt, projectID),
-			Annotations: common.GetProjectNamespaceAnnotations(projectID, h.opts.ProjectLabel, h.opts.ClusterID),
-			Labels:      common.GetProjectNamespaceLabels(projectID, h.opts.ProjectLabel, projectID, isOrphaned),
+			Name:        name,
+			Annotations: common.AddOriginalNameAnnotation(annotations, originalName, name),
+			Labels:      common.GetProjectNamespaceLabels(projectID, projectLabel, projectID, isOrphaned),
 		},
+	}, nil
+}
+
+// validateProjectRegistrationNamespaceLevel returns an error if the Project Registration Namespace with the provided name already exists for a project
+// at another level of the project hierarchy, which happens if the same label value is used on multiple levels since Project Registration Namespaces
+// of all levels are named based on the same template
+func (h *handler) validateProjectRegistrationNamespaceLevel(projectLabel, projectID, name string) error {
+	if len(h.opts.ParentProjectLabels) == 0 || h.opts.ProjectCRD {
+		// there is only one level of projects
+		return nil
+	}
+	namespace, err := h.namespaceCache.Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !common.HasHelmProjectOperatedLabel(namespace.Labels) {
+		return nil
+	}
+	existingProjectLabel, existingProjectID, ok := h.opts.GetProjectLabelAndID(namespace.Labels)
+	if !ok || existingProjectLabel == projectLabel {
+		return nil
 	}
+	return fmt.Errorf("unable to create project registration namespace for %s: namespace %s is already the project registration namespace for %s; label values must be unique across all levels of the project hierarchy",
+		getProjectKey(projectLabel, projectID), name, getProjectKey(existingProjectLabel, existingProjectID))
 }
 
 // getConfigMap returns the values.yaml and questions.yaml ConfigMap that is expected to be created in all Project Registration Namespaces
@@ -0,0 +1,66 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateProjectRegistrationNamespaceLevel(t *testing.T) {
+	opts := common.Options{
+		RuntimeOptions: common.RuntimeOptions{
+			ProjectLabel:        "field.cattle.io/projectId",
+			ParentProjectLabels: []string{"example.com/org"},
+		},
+	}
+	testCases := []struct {
+		name         string
+		namespace    *corev1.Namespace
+		projectLabel string
+		expectErr    bool
+	}{
+		{
+			name:         "namespace does not exist",
+			projectLabel: "field.cattle.io/projectId",
+		},
+		{
+			name:         "namespace exists for the same level",
+			namespace:    newNamespace("cattle-project-acme", common.GetProjectNamespaceLabels("acme", "field.cattle.io/projectId", "acme", false)),
+			projectLabel: "field.cattle.io/projectId",
+		},
+		{
+			name:         "namespace exists for a parent level",
+			namespace:    newNamespace("cattle-project-acme", common.GetProjectNamespaceLabels("acme", "example.com/org", "acme", false)),
+			projectLabel: "field.cattle.io/projectId",
+			expectErr:    true,
+		},
+		{
+			name:         "namespace exists for an inner level",
+			namespace:    newNamespace("cattle-project-acme", common.GetProjectNamespaceLabels("acme", "field.cattle.io/projectId", "acme", false)),
+			projectLabel: "example.com/org",
+			expectErr:    true,
+		},
+		{
+			name:         "namespace exists but is not operated",
+			namespace:    newNamespace("cattle-project-acme", map[string]string{"example.com/org": "acme"}),
+			projectLabel: "field.cattle.io/projectId",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaceCache := &fakeNamespaceCache{}
+			if tc.namespace != nil {
+				namespaceCache.namespaces = []*corev1.Namespace{tc.namespace}
+			}
+			h := &handler{
+				opts:           opts,
+				namespaceCache: namespaceCache,
+			}
+			err := h.validateProjectRegistrationNamespaceLevel(tc.projectLabel, "acme", "cattle-project-acme")
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -2,9 +2,15 @@ package namespace
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/helm-project-operator/pkg/naming"
 	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
@@ -32,6 +38,44 @@ func (h *handler) getProjectIDFromNamespaceLabels(namespace *corev1.Namespace) (
 	return projectID, namespaceInProject
 }
 
+// getProjectKeysFromNamespaceLabels returns the keys of all projects that a namespace belongs to based on the labels on the namespace,
+// one for each level of the project hierarchy (see getProjectKey)
+func (h *handler) getProjectKeysFromNamespaceLabels(namespace *corev1.Namespace) []string {
+	labels := namespace.GetLabels()
+	if labels == nil {
+		return nil
+	}
+	var projectKeys []string
+	for _, projectLabel := range h.opts.GetProjectLabels() {
+		projectID, ok := labels[projectLabel]
+		if !ok {
+			continue
+		}
+		projectKeys = append(projectKeys, getProjectKey(projectLabel, projectID))
+	}
+	return projectKeys
+}
+
+// getProjectKey returns a key that uniquely identifies a project across all levels of the project hierarchy
+func getProjectKey(projectLabel, projectID string) string {
+	return fmt.Sprintf("%s=%s", projectLabel, projectID)
+}
+
+// parseProjectKey returns the project label and projectID encoded in a key returned by getProjectKey
+func parseProjectKey(projectKey string) (string, string) {
+	projectLabel, projectID, _ := strings.Cut(projectKey, "=")
+	return projectLabel, projectID
+}
+
+// getProjectRegistrationNamespaceProjectLabel returns the label whose value identifies the project of a Project Registration Namespace
+func (h *handler) getProjectRegistrationNamespaceProjectLabel() string {
+	if h.opts.ProjectCRD {
+		// Project objects are not tied to a label on namespaces, so the projectID is only recorded on the operator's own label
+		return common.HelmProjectOperatorProjectLabel
+	}
+	return h.opts.ProjectLabel
+}
+
 // enqueueProjectHelmChartsForNamespace simply enqueues all ProjectHelmCharts in a namespace
 func (h *handler) enqueueProjectHelmChartsForNamespace(namespace *corev1.Namespace) error {
 	projectHelmCharts, err := h.projectHelmChartCache.List(namespace.Name, labels.Everything())
@@ -43,3 +87,85 @@ func (h *handler) enqueueProjectHelmChartsForNamespace(namespace *corev1.Namespa
 	}
 	return nil
 }
+
+// applyExistingProjectRegistrationNamespacesForNamespace triggers applying the Project Registration Namespaces of all projects that a namespace
+// belongs to based on its labels, as long as those Project Registration Namespaces already exist
+func (h *handler) applyExistingProjectRegistrationNamespacesForNamespace(namespace *corev1.Namespace) error {
+	for _, projectKey := range h.getProjectKeysFromNamespaceLabels(namespace) {
+		_, projectID := parseProjectKey(projectKey)
+		_, name, err := h.getProjectRegistrationNamespaceName(h.opts.GetProjectRegistrationNamespaceTemplate(), projectID)
+		if err != nil {
+			return err
+		}
+		if !h.projectRegistrationNamespaceTracker.Has(name) {
+			continue
+		}
+		h.projectRegistrationNamespaceApplyinator.Apply(projectKey)
+	}
+	return nil
+}
+
+// enqueueProjectHelmChartsSelectingNamespace enqueues all ProjectHelmCharts in the system namespace whose spec.projectNamespaceSelector
+// matches the provided namespace
+func (h *handler) enqueueProjectHelmChartsSelectingNamespace(namespace *corev1.Namespace) error {
+	projectHelmCharts, err := h.projectHelmChartCache.List(h.systemNamespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, projectHelmChart := range projectHelmCharts {
+		selector, err := metav1.LabelSelectorAsSelector(projectHelmChart.Spec.ProjectNamespaceSelector)
+		if err != nil || !selector.Matches(labels.Set(namespace.Labels)) {
+			continue
+		}
+		h.projectHelmCharts.Enqueue(projectHelmChart.Namespace, projectHelmChart.Name)
+	}
+	return nil
+}
+
+// getProjectRegistrationNamespaceName returns the original name rendered by the provided template for the Project Registration Namespace of a project
+// along with the name that is actually used for the namespace, which is truncated if the original name is too long or invalid
+func (h *handler) getProjectRegistrationNamespaceName(namespaceTemplate, projectID string) (string, string, error) {
+	originalName, err := naming.Render(namespaceTemplate, naming.ProjectRegistrationNamespaceData{ProjectID: projectID})
+	if err != nil {
+		return "", "", err
+	}
+	// ensure that long or invalid project IDs still result in a valid namespace name
+	return originalName, naming.SafeName(originalName, naming.MaxNamespaceNameLength), nil
+}
+
+// registerLegacyProjectRegistrationNamespaces registers existing Project Registration Namespaces of a project that were created based on a
+// legacy template, which ensures that ProjectHelmCharts in those namespaces continue to be managed after the template has been changed
+func (h *handler) registerLegacyProjectRegistrationNamespaces(projectLabel, projectID, projectRegistrationNamespaceName string) error {
+	for _, legacyTemplate := range h.opts.LegacyProjectRegistrationNamespaceTemplates {
+		_, name, err := h.getProjectRegistrationNamespaceName(legacyTemplate, projectID)
+		if err != nil {
+			return err
+		}
+		if name == projectRegistrationNamespaceName {
+			continue
+		}
+		legacyNamespace, err := h.namespaceCache.Get(name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !common.HasHelmProjectOperatedLabel(legacyNamespace.Labels) || legacyNamespace.Labels[projectLabel] != projectID {
+			// only namespaces that were created by a Helm Project Operator for this project can be legacy Project Registration Namespaces
+			continue
+		}
+		if legacyNamespace.DeletionTimestamp != nil {
+			h.projectRegistrationNamespaceTracker.Delete(legacyNamespace)
+			continue
+		}
+		if !h.projectRegistrationNamespaceTracker.Has(legacyNamespace.Name) {
+			logrus.Infof("Registering legacy project registration namespace %s for project %s; ProjectHelmCharts in this namespace should be moved to %s", legacyNamespace.Name, projectID, projectRegistrationNamespaceName)
+		}
+		h.projectRegistrationNamespaceTracker.Set(legacyNamespace)
+		if err := h.enqueueProjectHelmChartsForNamespace(legacyNamespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
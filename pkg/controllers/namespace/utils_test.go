@@ -0,0 +1,42 @@
+package namespace
+
+import "testing"
+
+func TestProjectKey(t *testing.T) {
+	testCases := []struct {
+		name         string
+		projectLabel string
+		projectID    string
+		expectedKey  string
+	}{
+		{
+			name:         "project label",
+			projectLabel: "field.cattle.io/projectId",
+			projectID:    "p-example",
+			expectedKey:  "field.cattle.io/projectId=p-example",
+		},
+		{
+			name:         "parent project label",
+			projectLabel: "example.com/org",
+			projectID:    "acme",
+			expectedKey:  "example.com/org=acme",
+		},
+		{
+			name:         "empty projectID",
+			projectLabel: "example.com/org",
+			expectedKey:  "example.com/org=",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectKey := getProjectKey(tc.projectLabel, tc.projectID)
+			if projectKey != tc.expectedKey {
+				t.Errorf("expected key %s, found %s", tc.expectedKey, projectKey)
+			}
+			projectLabel, projectID := parseProjectKey(projectKey)
+			if projectLabel != tc.projectLabel || projectID != tc.projectID {
+				t.Errorf("expected to parse (%s, %s) from %s, found (%s, %s)", tc.projectLabel, tc.projectID, projectKey, projectLabel, projectID)
+			}
+		})
+	}
+}
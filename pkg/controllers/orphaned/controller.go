@@ -0,0 +1,225 @@
+package orphaned
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	appscontroller "github.com/rancher/wrangler/pkg/generated/controllers/apps/v1"
+	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// recheckInterval is the interval at which an orphaned namespace whose TTL has expired is re-evaluated
+	// if it could not be garbage collected since it still contains ProjectHelmCharts, workloads or persistent data
+	recheckInterval = 10 * time.Minute
+)
+
+type handler struct {
+	ttl time.Duration
+
+	namespaces            corecontroller.NamespaceController
+	namespaceCache        corecontroller.NamespaceCache
+	pods                  corecontroller.PodClient
+	pvcs                  corecontroller.PersistentVolumeClaimClient
+	deployments           appscontroller.DeploymentClient
+	statefulSets          appscontroller.StatefulSetClient
+	daemonSets            appscontroller.DaemonSetClient
+	projectHelmCharts     helmprojectcontroller.ProjectHelmChartController
+	projectHelmChartCache helmprojectcontroller.ProjectHelmChartCache
+
+	recorder record.EventRecorder
+}
+
+func Register(
+	ctx context.Context,
+	opts common.Options,
+	namespaces corecontroller.NamespaceController,
+	namespaceCache corecontroller.NamespaceCache,
+	pods corecontroller.PodClient,
+	pvcs corecontroller.PersistentVolumeClaimClient,
+	deployments appscontroller.DeploymentClient,
+	statefulSets appscontroller.StatefulSetClient,
+	daemonSets appscontroller.DaemonSetClient,
+	projectHelmCharts helmprojectcontroller.ProjectHelmChartController,
+	projectHelmChartCache helmprojectcontroller.ProjectHelmChartCache,
+	recorder record.EventRecorder,
+) error {
+	ttl, err := time.ParseDuration(opts.OrphanedNamespaceTTL)
+	if err != nil {
+		return fmt.Errorf("unable to parse orphaned namespace TTL %s: %s", opts.OrphanedNamespaceTTL, err)
+	}
+
+	h := &handler{
+		ttl:                   ttl,
+		namespaces:            namespaces,
+		namespaceCache:        namespaceCache,
+		pods:                  pods,
+		pvcs:                  pvcs,
+		deployments:           deployments,
+		statefulSets:          statefulSets,
+		daemonSets:            daemonSets,
+		projectHelmCharts:     projectHelmCharts,
+		projectHelmChartCache: projectHelmChartCache,
+		recorder:              recorder,
+	}
+
+	h.initResolvers(ctx)
+
+	namespaces.OnChange(ctx, "garbage-collect-orphaned-namespace", h.OnChange)
+
+	return nil
+}
+
+func (h *handler) OnChange(_ string, namespace *corev1.Namespace) (*corev1.Namespace, error) {
+	if namespace == nil {
+		return namespace, nil
+	}
+	if namespace.DeletionTimestamp != nil {
+		return namespace, nil
+	}
+	if !common.HasHelmProjectOperatedLabel(namespace.Labels) {
+		// only garbage collect namespaces created by the operator
+		return namespace, nil
+	}
+
+	orphanedSince, hasOrphanedSince := namespace.Annotations[common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation]
+	if !common.IsOrphanedNamespace(namespace.Labels) {
+		if !hasOrphanedSince {
+			return namespace, nil
+		}
+		// the namespace was adopted again, so reset the time at which it was orphaned
+		namespaceCopy := namespace.DeepCopy()
+		delete(namespaceCopy.Annotations, common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation)
+		return h.namespaces.Update(namespaceCopy)
+	}
+	if !hasOrphanedSince {
+		// record when the namespace was first observed as orphaned
+		//
+		// Note: this is the time at which this controller first observed the orphaned label, not the time at which the label was
+		// added. If the label was added while garbage collection was disabled or the operator was not running, the TTL starts
+		// from when the operator first observes it, so an orphaned namespace is never deleted earlier than the TTL allows.
+		namespaceCopy := namespace.DeepCopy()
+		if namespaceCopy.Annotations == nil {
+			namespaceCopy.Annotations = map[string]string{}
+		}
+		namespaceCopy.Annotations[common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		return h.namespaces.Update(namespaceCopy)
+	}
+
+	if common.HasKeepAnnotation(namespace.Annotations) {
+		logrus.Debugf("Skipping garbage collection of orphaned namespace %s since it has annotation %s", namespace.Name, common.HelmProjectOperatedNamespaceKeepAnnotation)
+		return namespace, nil
+	}
+
+	remaining, err := getRemainingTTL(orphanedSince, h.ttl, time.Now())
+	if err != nil {
+		logrus.Warnf("Unable to parse annotation %s on orphaned namespace %s: %s", common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation, namespace.Name, err)
+		return namespace, nil
+	}
+	if remaining > 0 {
+		h.namespaces.EnqueueAfter(namespace.Name, remaining)
+		return namespace, nil
+	}
+
+	// ensure that deleting the namespace would not destroy any user data
+	reason, err := h.getGarbageCollectionBlocker(namespace)
+	if err != nil {
+		return namespace, err
+	}
+	if len(reason) > 0 {
+		h.recorder.Eventf(namespace, corev1.EventTypeWarning, "OrphanedNamespaceNotDeleted",
+			"Orphaned namespace %s was not garbage collected since it %s", namespace.Name, reason)
+		h.namespaces.EnqueueAfter(namespace.Name, recheckInterval)
+		return namespace, nil
+	}
+
+	logrus.Infof("Garbage collecting namespace %s since it has been orphaned since %s", namespace.Name, orphanedSince)
+	err = h.namespaces.Delete(namespace.Name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return namespace, fmt.Errorf("unable to garbage collect orphaned namespace %s: %s", namespace.Name, err)
+	}
+	h.recorder.Eventf(namespace, corev1.EventTypeNormal, "OrphanedNamespaceDeleted",
+		"Orphaned namespace %s was garbage collected since it has been orphaned for more than %s", namespace.Name, h.ttl)
+	return namespace, nil
+}
+
+// getRemainingTTL returns how long a namespace that was orphaned at the provided time must still wait before it is garbage collected
+func getRemainingTTL(orphanedSince string, ttl time.Duration, now time.Time) (time.Duration, error) {
+	orphanedTime, err := time.Parse(time.RFC3339, orphanedSince)
+	if err != nil {
+		return 0, err
+	}
+	return ttl - now.Sub(orphanedTime), nil
+}
+
+// getGarbageCollectionBlocker returns a reason why the namespace cannot be garbage collected, if one exists
+//
+// A namespace is not garbage collected if it contains ProjectHelmCharts, PersistentVolumeClaims (which may retain user data
+// even if no workload currently mounts them), or workloads that were not created by the operator: running pods as well as
+// Deployments, StatefulSets and DaemonSets, which may currently be scaled down or unschedulable.
+//
+// Note: these resources are only listed from the namespace once its TTL has expired, rather than being watched across the
+// cluster, since a single resource that was not created by the operator is enough to block garbage collection
+func (h *handler) getGarbageCollectionBlocker(namespace *corev1.Namespace) (string, error) {
+	projectHelmCharts, err := h.projectHelmChartCache.List(namespace.Name, labels.Everything())
+	if err != nil {
+		return "", fmt.Errorf("unable to list ProjectHelmCharts in namespace %s: %s", namespace.Name, err)
+	}
+	if len(projectHelmCharts) > 0 {
+		return fmt.Sprintf("contains %d ProjectHelmChart(s)", len(projectHelmCharts)), nil
+	}
+	pvcs, err := h.pvcs.List(namespace.Name, metav1.ListOptions{
+		Limit: 1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to list persistent volume claims in namespace %s: %s", namespace.Name, err)
+	}
+	if len(pvcs.Items) > 0 {
+		return "contains persistent volume claims", nil
+	}
+	// only consider workloads that were not created by the operator
+	workloadListOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("!%s", common.HelmProjectOperatedLabel),
+		Limit:         1,
+	}
+	deployments, err := h.deployments.List(namespace.Name, workloadListOptions)
+	if err != nil {
+		return "", fmt.Errorf("unable to list deployments in namespace %s: %s", namespace.Name, err)
+	}
+	if len(deployments.Items) > 0 {
+		return "contains deployments", nil
+	}
+	statefulSets, err := h.statefulSets.List(namespace.Name, workloadListOptions)
+	if err != nil {
+		return "", fmt.Errorf("unable to list statefulsets in namespace %s: %s", namespace.Name, err)
+	}
+	if len(statefulSets.Items) > 0 {
+		return "contains statefulsets", nil
+	}
+	daemonSets, err := h.daemonSets.List(namespace.Name, workloadListOptions)
+	if err != nil {
+		return "", fmt.Errorf("unable to list daemonsets in namespace %s: %s", namespace.Name, err)
+	}
+	if len(daemonSets.Items) > 0 {
+		return "contains daemonsets", nil
+	}
+	podListOptions := workloadListOptions
+	podListOptions.FieldSelector = fmt.Sprintf("status.phase!=%s,status.phase!=%s", corev1.PodSucceeded, corev1.PodFailed)
+	pods, err := h.pods.List(namespace.Name, podListOptions)
+	if err != nil {
+		return "", fmt.Errorf("unable to list pods in namespace %s: %s", namespace.Name, err)
+	}
+	if len(pods.Items) > 0 {
+		return "contains running pods", nil
+	}
+	return "", nil
+}
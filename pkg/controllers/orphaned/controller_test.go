@@ -0,0 +1,346 @@
+package orphaned
+
+import (
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	appscontroller "github.com/rancher/wrangler/pkg/generated/controllers/apps/v1"
+	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// fakePodClient returns the provided pods on List and records the options it was called with
+type fakePodClient struct {
+	corecontroller.PodClient
+
+	pods        []corev1.Pod
+	listOptions metav1.ListOptions
+}
+
+func (c *fakePodClient) List(_ string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	c.listOptions = opts
+	return &corev1.PodList{Items: c.pods}, nil
+}
+
+// fakePersistentVolumeClaimClient returns the provided persistent volume claims on List and records the options it was called with
+type fakePersistentVolumeClaimClient struct {
+	corecontroller.PersistentVolumeClaimClient
+
+	pvcs        []corev1.PersistentVolumeClaim
+	listOptions metav1.ListOptions
+}
+
+func (c *fakePersistentVolumeClaimClient) List(_ string, opts metav1.ListOptions) (*corev1.PersistentVolumeClaimList, error) {
+	c.listOptions = opts
+	return &corev1.PersistentVolumeClaimList{Items: c.pvcs}, nil
+}
+
+// fakeDeploymentClient returns the provided deployments on List and records the options it was called with
+type fakeDeploymentClient struct {
+	appscontroller.DeploymentClient
+
+	deployments []appsv1.Deployment
+	listOptions metav1.ListOptions
+}
+
+func (c *fakeDeploymentClient) List(_ string, opts metav1.ListOptions) (*appsv1.DeploymentList, error) {
+	c.listOptions = opts
+	return &appsv1.DeploymentList{Items: c.deployments}, nil
+}
+
+// fakeStatefulSetClient returns the provided statefulsets on List and records the options it was called with
+type fakeStatefulSetClient struct {
+	appscontroller.StatefulSetClient
+
+	statefulSets []appsv1.StatefulSet
+	listOptions  metav1.ListOptions
+}
+
+func (c *fakeStatefulSetClient) List(_ string, opts metav1.ListOptions) (*appsv1.StatefulSetList, error) {
+	c.listOptions = opts
+	return &appsv1.StatefulSetList{Items: c.statefulSets}, nil
+}
+
+// fakeDaemonSetClient returns the provided daemonsets on List and records the options it was called with
+type fakeDaemonSetClient struct {
+	appscontroller.DaemonSetClient
+
+	daemonSets  []appsv1.DaemonSet
+	listOptions metav1.ListOptions
+}
+
+func (c *fakeDaemonSetClient) List(_ string, opts metav1.ListOptions) (*appsv1.DaemonSetList, error) {
+	c.listOptions = opts
+	return &appsv1.DaemonSetList{Items: c.daemonSets}, nil
+}
+
+// fakeNamespaceController records the namespaces that were updated or enqueued
+type fakeNamespaceController struct {
+	corecontroller.NamespaceController
+
+	updated       []*corev1.Namespace
+	enqueuedAfter []string
+}
+
+func (c *fakeNamespaceController) Update(namespace *corev1.Namespace) (*corev1.Namespace, error) {
+	c.updated = append(c.updated, namespace)
+	return namespace, nil
+}
+
+func (c *fakeNamespaceController) EnqueueAfter(name string, _ time.Duration) {
+	c.enqueuedAfter = append(c.enqueuedAfter, name)
+}
+
+// fakeProjectHelmChartCache returns the provided ProjectHelmCharts on List
+type fakeProjectHelmChartCache struct {
+	helmprojectcontroller.ProjectHelmChartCache
+
+	projectHelmCharts []*v1alpha1.ProjectHelmChart
+}
+
+func (c *fakeProjectHelmChartCache) List(_ string, _ labels.Selector) ([]*v1alpha1.ProjectHelmChart, error) {
+	return c.projectHelmCharts, nil
+}
+
+func TestGetRemainingTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		orphanedSince string
+		ttl           time.Duration
+		expected      time.Duration
+		expectErr     bool
+	}{
+		{
+			name:          "ttl has not expired",
+			orphanedSince: "2024-01-01T11:00:00Z",
+			ttl:           2 * time.Hour,
+			expected:      time.Hour,
+		},
+		{
+			name:          "ttl expires now",
+			orphanedSince: "2024-01-01T10:00:00Z",
+			ttl:           2 * time.Hour,
+			expected:      0,
+		},
+		{
+			name:          "ttl has expired",
+			orphanedSince: "2024-01-01T09:00:00Z",
+			ttl:           2 * time.Hour,
+			expected:      -time.Hour,
+		},
+		{
+			name:          "invalid orphaned since",
+			orphanedSince: "yesterday",
+			ttl:           2 * time.Hour,
+			expectErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			remaining, err := getRemainingTTL(tc.orphanedSince, tc.ttl, now)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error on parsing %q", tc.orphanedSince)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remaining != tc.expected {
+				t.Errorf("expected %s remaining, found %s", tc.expected, remaining)
+			}
+		})
+	}
+}
+
+func TestGetGarbageCollectionBlocker(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-project-p-example"}}
+	objectMeta := metav1.ObjectMeta{Name: "workload", Namespace: namespace.Name}
+	testCases := []struct {
+		name              string
+		projectHelmCharts []*v1alpha1.ProjectHelmChart
+		pvcs              []corev1.PersistentVolumeClaim
+		deployments       []appsv1.Deployment
+		statefulSets      []appsv1.StatefulSet
+		daemonSets        []appsv1.DaemonSet
+		pods              []corev1.Pod
+		blocked           bool
+	}{
+		{
+			name: "empty namespace",
+		},
+		{
+			name: "namespace with a ProjectHelmChart",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				{ObjectMeta: metav1.ObjectMeta{Name: "project-monitoring", Namespace: namespace.Name}},
+			},
+			blocked: true,
+		},
+		{
+			name:    "namespace with a persistent volume claim",
+			pvcs:    []corev1.PersistentVolumeClaim{{ObjectMeta: objectMeta}},
+			blocked: true,
+		},
+		{
+			name:        "namespace with a deployment",
+			deployments: []appsv1.Deployment{{ObjectMeta: objectMeta}},
+			blocked:     true,
+		},
+		{
+			name:         "namespace with a statefulset",
+			statefulSets: []appsv1.StatefulSet{{ObjectMeta: objectMeta}},
+			blocked:      true,
+		},
+		{
+			name:       "namespace with a daemonset",
+			daemonSets: []appsv1.DaemonSet{{ObjectMeta: objectMeta}},
+			blocked:    true,
+		},
+		{
+			name:    "namespace with a running pod",
+			pods:    []corev1.Pod{{ObjectMeta: objectMeta}},
+			blocked: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pvcs := &fakePersistentVolumeClaimClient{pvcs: tc.pvcs}
+			deployments := &fakeDeploymentClient{deployments: tc.deployments}
+			statefulSets := &fakeStatefulSetClient{statefulSets: tc.statefulSets}
+			daemonSets := &fakeDaemonSetClient{daemonSets: tc.daemonSets}
+			pods := &fakePodClient{pods: tc.pods}
+			h := &handler{
+				pvcs:                  pvcs,
+				deployments:           deployments,
+				statefulSets:          statefulSets,
+				daemonSets:            daemonSets,
+				pods:                  pods,
+				projectHelmChartCache: &fakeProjectHelmChartCache{projectHelmCharts: tc.projectHelmCharts},
+			}
+			reason, err := h.getGarbageCollectionBlocker(namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blocked := len(reason) > 0; blocked != tc.blocked {
+				t.Errorf("expected blocked to be %t, found reason %q", tc.blocked, reason)
+			}
+			if tc.blocked {
+				return
+			}
+			if pvcs.listOptions.Limit != 1 || len(pvcs.listOptions.LabelSelector) != 0 {
+				t.Errorf("expected all persistent volume claims to be listed with a limit of 1, found %v", pvcs.listOptions)
+			}
+			for kind, listOptions := range map[string]metav1.ListOptions{
+				"deployments":  deployments.listOptions,
+				"statefulsets": statefulSets.listOptions,
+				"daemonsets":   daemonSets.listOptions,
+				"pods":         pods.listOptions,
+			} {
+				if listOptions.Limit != 1 {
+					t.Errorf("expected %s to be listed with a limit of 1, found %d", kind, listOptions.Limit)
+				}
+				if listOptions.LabelSelector != "!helm.cattle.io/helm-project-operated" {
+					t.Errorf("expected %s created by the operator to be excluded, found label selector %q", kind, listOptions.LabelSelector)
+				}
+			}
+			if pods.listOptions.FieldSelector != "status.phase!=Succeeded,status.phase!=Failed" {
+				t.Errorf("expected completed pods to be excluded, found field selector %q", pods.listOptions.FieldSelector)
+			}
+		})
+	}
+}
+
+func TestOnChangeRecordsOrphanedSince(t *testing.T) {
+	newNamespace := func(orphaned bool, orphanedSince string) *corev1.Namespace {
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cattle-project-p-example",
+				Labels: map[string]string{
+					common.HelmProjectOperatedLabel: "true",
+				},
+				Annotations: map[string]string{},
+			},
+		}
+		if orphaned {
+			namespace.Labels[common.HelmProjectOperatedNamespaceOrphanedLabel] = "true"
+		}
+		if len(orphanedSince) > 0 {
+			namespace.Annotations[common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation] = orphanedSince
+		}
+		return namespace
+	}
+	testCases := []struct {
+		name                  string
+		namespace             *corev1.Namespace
+		expectUpdate          bool
+		expectedOrphanedSince bool
+		expectedEnqueueAfter  bool
+	}{
+		{
+			name:      "namespace that is not orphaned",
+			namespace: newNamespace(false, ""),
+		},
+		{
+			name:                  "orphaned label is first observed",
+			namespace:             newNamespace(true, ""),
+			expectUpdate:          true,
+			expectedOrphanedSince: true,
+		},
+		{
+			name:         "namespace is adopted again",
+			namespace:    newNamespace(false, "2024-01-01T00:00:00Z"),
+			expectUpdate: true,
+		},
+		{
+			name:                 "orphaned namespace within its TTL",
+			namespace:            newNamespace(true, time.Now().UTC().Format(time.RFC3339)),
+			expectedEnqueueAfter: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaces := &fakeNamespaceController{}
+			h := &handler{
+				ttl:        time.Hour,
+				namespaces: namespaces,
+			}
+			before := time.Now().UTC().Truncate(time.Second)
+			if _, err := h.OnChange(tc.namespace.Name, tc.namespace); err != nil {
+				t.Fatal(err)
+			}
+			if !tc.expectUpdate {
+				if len(namespaces.updated) != 0 {
+					t.Errorf("expected namespace to not be updated, found %v", namespaces.updated)
+				}
+			} else {
+				if len(namespaces.updated) != 1 {
+					t.Fatalf("expected namespace to be updated once, found %d updates", len(namespaces.updated))
+				}
+				orphanedSince, ok := namespaces.updated[0].Annotations[common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation]
+				if ok != tc.expectedOrphanedSince {
+					t.Fatalf("expected annotation %s to be set: %t, found %q", common.HelmProjectOperatedNamespaceOrphanedSinceAnnotation, tc.expectedOrphanedSince, orphanedSince)
+				}
+				if ok {
+					orphanedTime, err := time.Parse(time.RFC3339, orphanedSince)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if orphanedTime.Before(before) {
+						t.Errorf("expected orphaned since to be the time the orphaned label was first observed, found %s", orphanedSince)
+					}
+				}
+			}
+			if enqueued := len(namespaces.enqueuedAfter) > 0; enqueued != tc.expectedEnqueueAfter {
+				t.Errorf("expected namespace to be enqueued after its TTL: %t, found %t", tc.expectedEnqueueAfter, enqueued)
+			}
+		})
+	}
+}
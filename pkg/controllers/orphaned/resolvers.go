@@ -0,0 +1,35 @@
+package orphaned
+
+import (
+	"context"
+
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// initResolvers initializes resolvers that re-evaluate orphaned namespaces when their contents change
+func (h *handler) initResolvers(ctx context.Context) {
+	relatedresource.WatchClusterScoped(
+		ctx, "watch-orphaned-namespace-contents", h.resolveOrphanedNamespaceContents, h.namespaces,
+		h.projectHelmCharts,
+	)
+}
+
+func (h *handler) resolveOrphanedNamespaceContents(namespace, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	ns, err := h.namespaceCache.Get(namespace)
+	if err != nil {
+		// namespace is probably being deleted, which means we don't need to resolve anything
+		return nil, nil
+	}
+	if !common.IsOrphanedNamespace(ns.Labels) {
+		// only care about the contents of orphaned namespaces
+		return nil, nil
+	}
+	return []relatedresource.Key{{
+		Name: namespace,
+	}}, nil
+}
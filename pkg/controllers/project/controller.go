@@ -3,14 +3,17 @@ package project
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"github.com/k3s-io/helm-controller/pkg/controllers/chart"
 	k3shelmcontroller "github.com/k3s-io/helm-controller/pkg/generated/controllers/helm.cattle.io/v1"
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 	"github.com/rancher/helm-project-operator/pkg/controllers/namespace"
+	hpocorecontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/core/v1"
 	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
 	helmlockercontroller "github.com/rancher/helm-project-operator/pkg/helm-locker/generated/controllers/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/helm-locker/releases"
 	"github.com/rancher/helm-project-operator/pkg/remove"
 	"github.com/rancher/wrangler/pkg/apply"
 	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
@@ -22,29 +25,44 @@ import (
 )
 
 var (
-	DefaultJobImage = chart.DefaultJobImage
+	DefaultJobImage = backend.DefaultJobImage
 )
 
 type handler struct {
-	systemNamespace         string
-	opts                    common.Options
-	valuesOverride          v1alpha1.GenericMap
-	apply                   apply.Apply
-	projectHelmCharts       helmprojectcontroller.ProjectHelmChartController
-	projectHelmChartCache   helmprojectcontroller.ProjectHelmChartCache
-	configmaps              corecontroller.ConfigMapController
-	configmapCache          corecontroller.ConfigMapCache
-	roles                   rbaccontroller.RoleController
-	roleCache               rbaccontroller.RoleCache
-	clusterrolebindings     rbaccontroller.ClusterRoleBindingController
-	clusterrolebindingCache rbaccontroller.ClusterRoleBindingCache
-	helmCharts              k3shelmcontroller.HelmChartController
-	helmReleases            helmlockercontroller.HelmReleaseController
-	namespaces              corecontroller.NamespaceController
-	namespaceCache          corecontroller.NamespaceCache
-	rolebindings            rbaccontroller.RoleBindingController
-	rolebindingCache        rbaccontroller.RoleBindingCache
-	projectGetter           namespace.ProjectGetter
+	systemNamespace           string
+	opts                      common.Options
+	valuesOverride            v1alpha1.GenericMap
+	releasePermissions        common.ReleasePermissionsOptions
+	releaseNamespaceResources common.ReleaseNamespaceResourcesOptions
+	defaultReleaseRoles       common.DefaultReleaseRolesOptions
+	apply                     apply.Apply
+	projectHelmCharts         helmprojectcontroller.ProjectHelmChartController
+	projectHelmChartCache     helmprojectcontroller.ProjectHelmChartCache
+	configmaps                corecontroller.ConfigMapController
+	configmapCache            corecontroller.ConfigMapCache
+	roles                     rbaccontroller.RoleController
+	roleCache                 rbaccontroller.RoleCache
+	clusterroles              rbaccontroller.ClusterRoleController
+	clusterroleCache          rbaccontroller.ClusterRoleCache
+	clusterrolebindings       rbaccontroller.ClusterRoleBindingController
+	clusterrolebindingCache   rbaccontroller.ClusterRoleBindingCache
+	helmCharts                k3shelmcontroller.HelmChartController
+	helmReleases              helmlockercontroller.HelmReleaseController
+	helmReleaseCache          helmlockercontroller.HelmReleaseCache
+	namespaces                corecontroller.NamespaceController
+	namespaceCache            corecontroller.NamespaceCache
+	rolebindings              rbaccontroller.RoleBindingController
+	rolebindingCache          rbaccontroller.RoleBindingCache
+	serviceaccounts           corecontroller.ServiceAccountController
+	resourceQuotas            hpocorecontroller.ResourceQuotaController
+	limitRanges               hpocorecontroller.LimitRangeController
+	secrets                   corecontroller.SecretController
+	secretCache               corecontroller.SecretCache
+	projectGetter             namespace.ProjectGetter
+	releaseGetter             releases.HelmReleaseGetter
+	backend                   backend.DeploymentBackend
+
+	clusterRoleBindingResyncDelay time.Duration
 }
 
 func Register(
@@ -52,6 +70,9 @@ func Register(
 	systemNamespace string,
 	opts common.Options,
 	valuesOverride v1alpha1.GenericMap,
+	releasePermissions common.ReleasePermissionsOptions,
+	releaseNamespaceResources common.ReleaseNamespaceResourcesOptions,
+	defaultReleaseRoles common.DefaultReleaseRolesOptions,
 	apply apply.Apply,
 	projectHelmCharts helmprojectcontroller.ProjectHelmChartController,
 	projectHelmChartCache helmprojectcontroller.ProjectHelmChartCache,
@@ -59,15 +80,25 @@ func Register(
 	configmapCache corecontroller.ConfigMapCache,
 	roles rbaccontroller.RoleController,
 	roleCache rbaccontroller.RoleCache,
+	clusterroles rbaccontroller.ClusterRoleController,
+	clusterroleCache rbaccontroller.ClusterRoleCache,
 	clusterrolebindings rbaccontroller.ClusterRoleBindingController,
 	clusterrolebindingCache rbaccontroller.ClusterRoleBindingCache,
 	helmCharts k3shelmcontroller.HelmChartController,
 	helmReleases helmlockercontroller.HelmReleaseController,
+	helmReleaseCache helmlockercontroller.HelmReleaseCache,
 	namespaces corecontroller.NamespaceController,
 	namespaceCache corecontroller.NamespaceCache,
 	rolebindings rbaccontroller.RoleBindingController,
 	rolebindingCache rbaccontroller.RoleBindingCache,
+	serviceaccounts corecontroller.ServiceAccountController,
+	resourceQuotas hpocorecontroller.ResourceQuotaController,
+	limitRanges hpocorecontroller.LimitRangeController,
+	secrets corecontroller.SecretController,
+	secretCache corecontroller.SecretCache,
 	projectGetter namespace.ProjectGetter,
+	releaseGetter releases.HelmReleaseGetter,
+	deploymentBackend backend.DeploymentBackend,
 ) {
 
 	apply = apply.
@@ -79,30 +110,58 @@ func Register(
 			helmCharts,
 			helmReleases,
 			namespaces,
-			rolebindings).
+			roles,
+			rolebindings,
+			clusterroles,
+			clusterrolebindings,
+			serviceaccounts,
+			resourceQuotas,
+			limitRanges,
+			configmaps,
+			secrets).
 		WithNoDeleteGVK(namespaces.GroupVersionKind())
 
 	h := &handler{
-		systemNamespace:         systemNamespace,
-		opts:                    opts,
-		valuesOverride:          valuesOverride,
-		apply:                   apply,
-		projectHelmCharts:       projectHelmCharts,
-		projectHelmChartCache:   projectHelmChartCache,
-		configmaps:              configmaps,
-		configmapCache:          configmapCache,
-		roles:                   roles,
-		clusterrolebindings:     clusterrolebindings,
-		clusterrolebindingCache: clusterrolebindingCache,
-		roleCache:               roleCache,
-		helmCharts:              helmCharts,
-		helmReleases:            helmReleases,
-		namespaces:              namespaces,
-		namespaceCache:          namespaceCache,
-		rolebindings:            rolebindings,
-		rolebindingCache:        rolebindingCache,
-		projectGetter:           projectGetter,
+		systemNamespace:           systemNamespace,
+		opts:                      opts,
+		valuesOverride:            valuesOverride,
+		releasePermissions:        releasePermissions,
+		releaseNamespaceResources: releaseNamespaceResources,
+		defaultReleaseRoles:       defaultReleaseRoles,
+		apply:                     apply,
+		projectHelmCharts:         projectHelmCharts,
+		projectHelmChartCache:     projectHelmChartCache,
+		configmaps:                configmaps,
+		configmapCache:            configmapCache,
+		roles:                     roles,
+		clusterrolebindings:       clusterrolebindings,
+		clusterrolebindingCache:   clusterrolebindingCache,
+		roleCache:                 roleCache,
+		clusterroles:              clusterroles,
+		clusterroleCache:          clusterroleCache,
+		helmCharts:                helmCharts,
+		helmReleases:              helmReleases,
+		helmReleaseCache:          helmReleaseCache,
+		namespaces:                namespaces,
+		namespaceCache:            namespaceCache,
+		rolebindings:              rolebindings,
+		rolebindingCache:          rolebindingCache,
+		serviceaccounts:           serviceaccounts,
+		resourceQuotas:            resourceQuotas,
+		limitRanges:               limitRanges,
+		secrets:                   secrets,
+		secretCache:               secretCache,
+		projectGetter:             projectGetter,
+		releaseGetter:             releaseGetter,
+		backend:                   deploymentBackend,
+	}
+
+	clusterRoleBindingResyncDelay, err := time.ParseDuration(opts.ClusterRoleBindingResyncDelay)
+	if err != nil {
+		// this should never happen since the RuntimeOptions are validated on startup
+		logrus.Errorf("unable to parse cluster role binding resync delay %s, enqueuing immediately: %s", opts.ClusterRoleBindingResyncDelay, err)
 	}
+	h.clusterRoleBindingResyncDelay = clusterRoleBindingResyncDelay
 
 	h.initIndexers()
 
@@ -117,16 +176,19 @@ func Register(
 	// To resolve this, we simply prefix the provided managedBy string to the generatingHandler controller's name only to ensure that the
 	// set ID specified will only target this particular controller
 	generatingHandlerName := fmt.Sprintf("%s-project-helm-chart-registration", opts.ControllerName)
-	helmprojectcontroller.RegisterProjectHelmChartGeneratingHandler(ctx,
+	registerProjectHelmChartGeneratingHandler(ctx,
 		projectHelmCharts,
 		apply,
-		"",
 		generatingHandlerName,
 		h.OnChange,
 		&generic.GeneratingHandlerOptions{
 			AllowClusterScoped: true,
 		})
 
+	if opts.ValuesHistoryLimit > 0 {
+		projectHelmCharts.OnChange(ctx, "on-project-helm-chart-rollback-to-revision", h.OnRollbackToRevision)
+	}
+
 	remove.RegisterScopedOnRemoveHandler(ctx, projectHelmCharts, "on-project-helm-chart-remove",
 		func(_ string, obj runtime.Object) (bool, error) {
 			if obj == nil {
@@ -141,7 +203,7 @@ func Register(
 		helmprojectcontroller.FromProjectHelmChartHandlerToHandler(h.OnRemove),
 	)
 
-	err := h.initRemoveCleanupLabels()
+	err = h.initRemoveCleanupLabels()
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -187,6 +249,9 @@ func (h *handler) OnChange(projectHelmChart *v1alpha1.ProjectHelmChart, projectH
 	if common.HasCleanupLabel(projectHelmChart) {
 		projectHelmChartStatus = h.getCleanupStatus(projectHelmChart, projectHelmChartStatus)
 		logrus.Infof("Cleaning up HelmChart and HelmRelease for ProjectHelmChart %s/%s", projectHelmChart.Namespace, projectHelmChart.Name)
+		if err := h.removeRelease(projectHelmChart); err != nil {
+			return nil, projectHelmChartStatus, err
+		}
 		return nil, projectHelmChartStatus, nil
 	}
 
@@ -197,57 +262,76 @@ func (h *handler) OnChange(projectHelmChart *v1alpha1.ProjectHelmChart, projectH
 	}
 	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
 
-	// check if the releaseName is already tracked by another ProjectHelmChart
-	projectHelmCharts, err := h.projectHelmChartCache.GetByIndex(ProjectHelmChartByReleaseName, releaseName)
+	// check if the release is owned by another ProjectHelmChart
+	releaseOwner, err := h.getReleaseOwner(projectHelmChart)
 	if err != nil {
-		return nil, projectHelmChartStatus, fmt.Errorf("unable to get ProjectHelmCharts to verify if release is already tracked: %s", err)
+		return nil, projectHelmChartStatus, err
 	}
-	for _, conflictingProjectHelmChart := range projectHelmCharts {
-		if conflictingProjectHelmChart == nil {
-			continue
-		}
-		if projectHelmChart.Name == conflictingProjectHelmChart.Name && projectHelmChart.Namespace == conflictingProjectHelmChart.Namespace {
-			// looking at the same projectHelmChart that we have at hand
-			continue
-		}
-		if len(conflictingProjectHelmChart.Status.Status) == 0 {
-			// the other ProjectHelmChart hasn't been processed yet, so let it fail out whenever it is processed
-			continue
-		}
-		if conflictingProjectHelmChart.Status.Status == "UnableToCreateHelmRelease" {
-			// the other ProjectHelmChart is the one that will not be able to progress, so we can continue to update this one
-			continue
-		}
-		// we have found another ProjectHelmChart that already exists and is tracking this release with some non-conflicting status
+	if getReleaseClaimant(releaseOwner) != getReleaseClaimant(projectHelmChart) {
 		err = fmt.Errorf(
-			"ProjectHelmChart %s/%s already tracks release %s/%s",
-			conflictingProjectHelmChart.Namespace, conflictingProjectHelmChart.Name,
-			releaseName, releaseNamespace,
+			"ProjectHelmChart %s already owns release %s/%s; add the annotation '%s': 'true' to this ProjectHelmChart to take over the release",
+			getReleaseClaimant(releaseOwner), releaseNamespace, releaseName, common.HelmProjectOperatorTakeOverReleaseAnnotation,
 		)
 		projectHelmChartStatus = h.getUnableToCreateHelmReleaseStatus(projectHelmChart, projectHelmChartStatus, err)
 		return nil, projectHelmChartStatus, nil
 	}
+	if err := h.enqueueOtherProjectHelmChartsOnClaim(projectHelmChart); err != nil {
+		return nil, projectHelmChartStatus, err
+	}
 
 	// set basic statuses
 	projectHelmChartStatus.SystemNamespace = h.systemNamespace
 	projectHelmChartStatus.ReleaseNamespace = releaseNamespace
 	projectHelmChartStatus.ReleaseName = releaseName
 
+	// record the values that were deployed in the values revision history
+	//
+	// Note: the values revisions are returned on every path below that applies objects (paths that return errSkipApply leave
+	// them untouched) to ensure that the history that may be used to roll back the ProjectHelmChart is never pruned. If the LastDeployedRelease is updated by this reconcile, the new revision is
+	// recorded once the ProjectHelmChart is re-enqueued on updating its status.
+	valuesRevisions, err := h.getValuesRevisions(projectHelmChart, &projectHelmChartStatus)
+	if err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to record values revision for ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+	objs = append(objs, valuesRevisions...)
+
 	// gather target project namespaces
 	targetProjectNamespaces, err := h.projectGetter.GetTargetProjectNamespaces(projectHelmChart)
 	if err != nil {
 		return nil, projectHelmChartStatus, fmt.Errorf("unable to find project namespaces to deploy ProjectHelmChart: %s", err)
 	}
 	if len(targetProjectNamespaces) == 0 {
-		projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, true, projectHelmChart)
-		if projectReleaseNamespace != nil {
+		if err := h.removeRelease(projectHelmChart); err != nil {
+			return nil, projectHelmChartStatus, err
+		}
+		isOrphaned, err := h.shouldOrphanProjectReleaseNamespace(projectHelmChart)
+		if err != nil {
+			return nil, projectHelmChartStatus, err
+		}
+		if h.isSharedProjectReleaseNamespace(projectHelmChart) {
+			if err := h.applySharedProjectReleaseNamespace(projectID, isOrphaned, projectHelmChart); err != nil {
+				return nil, projectHelmChartStatus, err
+			}
+		} else if projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, isOrphaned, projectHelmChart); projectReleaseNamespace != nil {
 			objs = append(objs, projectReleaseNamespace)
 		}
 		projectHelmChartStatus = h.getNoTargetNamespacesStatus(projectHelmChart, projectHelmChartStatus)
 		return objs, projectHelmChartStatus, nil
 	}
 
-	if releaseNamespace != h.systemNamespace && releaseNamespace != projectHelmChart.Namespace {
+	// ensure that the release namespace provided, if any, belongs to the project
+	if err := h.validateReleaseNamespace(projectHelmChart, targetProjectNamespaces); err != nil {
+		projectHelmChartStatus = h.getInvalidReleaseNamespaceStatus(projectHelmChart, projectHelmChartStatus, err)
+		return nil, projectHelmChartStatus, errSkipApply
+	}
+
+	if h.isSharedProjectReleaseNamespace(projectHelmChart) {
+		// a shared release namespace is applied outside of the set of this ProjectHelmChart since it is used by all ProjectHelmCharts in the project
+		if err := h.applySharedProjectReleaseNamespace(projectID, false, projectHelmChart); err != nil {
+			return nil, projectHelmChartStatus, err
+		}
+		targetProjectNamespaces = append(targetProjectNamespaces, releaseNamespace)
+	} else if h.isOperatorManagedReleaseNamespace(projectHelmChart) {
 		// need to add release namespace to list of objects to be created
 		projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, false, projectHelmChart)
 		objs = append(objs, projectReleaseNamespace)
@@ -262,7 +346,31 @@ func (h *handler) OnChange(projectHelmChart *v1alpha1.ProjectHelmChart, projectH
 	if err != nil {
 		err = fmt.Errorf("unable to marshall spec.values: %s", err)
 		projectHelmChartStatus = h.getValuesParseErrorStatus(projectHelmChart, projectHelmChartStatus, err)
-		return nil, projectHelmChartStatus, nil
+		return valuesRevisions, projectHelmChartStatus, nil
+	}
+
+	// roll back to the values of the last deployed release if the provided values failed to deploy
+	valuesContentBytes, rolledBack, err := h.getValuesContentToDeploy(projectHelmChart, &projectHelmChartStatus, projectID, targetProjectNamespaces, valuesContentBytes)
+	if err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to determine values to deploy for ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+
+	// ensure that the helm options provided are allowed
+	if err := h.validateHelmOptions(projectHelmChart); err != nil {
+		projectHelmChartStatus = h.getHelmOptionsErrorStatus(projectHelmChart, projectHelmChartStatus, err)
+		return nil, projectHelmChartStatus, errSkipApply
+	}
+
+	// ensure that the release namespace resources provided are within the bounds configured by the operator
+	if err := h.validateReleaseNamespaceResources(projectHelmChart); err != nil {
+		projectHelmChartStatus = h.getReleaseNamespaceResourcesErrorStatus(projectHelmChart, projectHelmChartStatus, err)
+		return nil, projectHelmChartStatus, errSkipApply
+	}
+
+	// ensure that the release namespace metadata provided can be applied
+	if err := h.validateReleaseNamespaceMetadata(projectHelmChart); err != nil {
+		projectHelmChartStatus = h.getReleaseNamespaceMetadataErrorStatus(projectHelmChart, projectHelmChartStatus, err)
+		return nil, projectHelmChartStatus, errSkipApply
 	}
 
 	ns, err := h.namespaceCache.Get(releaseNamespace)
@@ -287,20 +395,92 @@ func (h *handler) OnChange(projectHelmChart *v1alpha1.ProjectHelmChart, projectH
 	if err != nil {
 		return nil, projectHelmChartStatus, fmt.Errorf("unable to get release roles from project release namespace %s for %s/%s: %s", releaseNamespace, projectHelmChart.Namespace, projectHelmChart.Name, err)
 	}
-	k8sRolesToSubjects, err := h.getSubjectRoleToSubjectsFromBindings(projectHelmChart)
+	k8sRolesToSubjects, k8sRolesToSubjectSources, err := h.getSubjectRoleToSubjectsFromBindings(projectHelmChart, targetProjectNamespaces)
 	if err != nil {
 		return nil, projectHelmChartStatus, fmt.Errorf("unable to get rolebindings to default project operator roles from project registration namespace %s for %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Namespace, projectHelmChart.Name, err)
 	}
+	// get the default roles that need to be created in the release namespace for tiers that the chart does not provide roles for
+	objs = append(objs,
+		h.getDefaultReleaseRoles(projectID, k8sRolesToRoleRefs, projectHelmChart)...,
+	)
 	objs = append(objs,
 		h.getRoleBindings(projectID, k8sRolesToRoleRefs, k8sRolesToSubjects, projectHelmChart)...,
 	)
 
-	// append the helm chart and helm release
+	// get the report of the RBAC computed for this ProjectHelmChart
+	if h.opts.RBACReport {
+		rbacReport, err := h.getRBACReport(projectID, k8sRolesToRoleRefs, k8sRolesToSubjects, k8sRolesToSubjectSources, projectHelmChart)
+		if err != nil {
+			return nil, projectHelmChartStatus, fmt.Errorf("unable to get rbac report for %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+		}
+		if rbacReport != nil {
+			objs = append(objs, rbacReport)
+		}
+	}
+
+	// get the roles and rolebindings that need to be fanned out into each target project namespace
+	fanOutRoles, fanOutClusterRoles, err := h.getFanOutRoles(projectHelmChart)
+	if err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to get fan-out roles for %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
 	objs = append(objs,
-		h.getHelmChart(projectID, string(valuesContentBytes), projectHelmChart),
-		h.getHelmRelease(projectID, projectHelmChart),
+		h.getFanOutRoleBindings(projectID, targetProjectNamespaces, fanOutRoles, fanOutClusterRoles, k8sRolesToSubjects, projectHelmChart)...,
 	)
 
+	// get the Secrets and ConfigMaps that need to be synced from the registration namespace into the release namespace
+	syncedObjs, err := h.getSyncedSecretsAndConfigMaps(projectID, projectHelmChart)
+	if err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to get secrets and configmaps to sync into project release namespace %s for %s/%s: %s", releaseNamespace, projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+	objs = append(objs, syncedObjs...)
+
+	// get the ResourceQuota and LimitRange that govern the resources consumed in the release namespace
+	if h.isOperatorManagedReleaseNamespace(projectHelmChart) {
+		objs = append(objs,
+			h.getReleaseNamespaceResources(projectID, projectHelmChart)...,
+		)
+	}
+
+	// get the ServiceAccount that deploys the release and the permissions scoped to this project
+	if h.opts.ScopeReleasePermissions {
+		objs = append(objs,
+			h.getReleasePermissions(projectID, targetProjectNamespaces, projectHelmChart)...,
+		)
+	}
+
+	// append the objects required to deploy the release via the configured deployment backend (e.g. HelmChart and HelmRelease)
+	release := h.getRelease(projectID, string(valuesContentBytes), projectHelmChart)
+	releaseObjs, err := h.backend.Deploy(release)
+	if err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to deploy release %s/%s for ProjectHelmChart %s/%s: %s", releaseNamespace, releaseName, projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+	if err := addReleaseClaim(projectHelmChart, releaseObjs); err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to record claim on release %s/%s for ProjectHelmChart %s/%s: %s", releaseNamespace, releaseName, projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+	if err := h.addOriginalReleaseName(projectHelmChart, releaseObjs); err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to record original name of release %s/%s for ProjectHelmChart %s/%s: %s", releaseNamespace, releaseName, projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+	objs = append(objs, releaseObjs...)
+
+	// propagate the configured labels and annotations onto all generated resources
+	if err := h.addPropagatedLabelsAndAnnotations(projectHelmChart, objs); err != nil {
+		return nil, projectHelmChartStatus, fmt.Errorf("unable to propagate labels and annotations of ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+	}
+
+	// report on the status of the release if the deployment backend performs Helm operations itself
+	if statusReporter, ok := h.backend.(backend.StatusReporter); ok {
+		if releaseStatus, ok := statusReporter.GetStatus(release); ok {
+			switch releaseStatus.State {
+			case backend.ReleaseFailedState:
+				projectHelmChartStatus = h.getUnableToDeployReleaseStatus(projectHelmChart, projectHelmChartStatus, releaseStatus)
+				return objs, projectHelmChartStatus, nil
+			case backend.ReleasePendingState, backend.ReleaseDeployingState:
+				projectHelmChartStatus = h.getDeployingReleaseStatus(projectHelmChart, projectHelmChartStatus, releaseStatus)
+				return objs, projectHelmChartStatus, nil
+			}
+		}
+	}
+
 	// get dashboard values if available
 	dashboardValues, err := h.getDashboardValuesFromConfigmaps(projectHelmChart)
 	if err != nil {
@@ -312,6 +492,9 @@ func (h *handler) OnChange(projectHelmChart *v1alpha1.ProjectHelmChart, projectH
 		projectHelmChartStatus.DashboardValues = dashboardValues
 		projectHelmChartStatus = h.getDeployedStatus(projectHelmChart, projectHelmChartStatus)
 	}
+	if rolledBack {
+		projectHelmChartStatus = h.getRolledBackStatus(projectHelmChart, projectHelmChartStatus)
+	}
 	return objs, projectHelmChartStatus, nil
 }
 
@@ -320,6 +503,25 @@ func (h *handler) OnRemove(_ string, projectHelmChart *v1alpha1.ProjectHelmChart
 		return nil, nil
 	}
 
+	// only the owner of a release is allowed to remove it
+	releaseOwner, err := h.getReleaseOwner(projectHelmChart)
+	if err != nil {
+		return projectHelmChart, err
+	}
+	if getReleaseClaimant(releaseOwner) != getReleaseClaimant(projectHelmChart) {
+		return projectHelmChart, nil
+	}
+
+	// ensure that the release is removed by backends that do not rely on the apply to clean up releases
+	if err := h.removeRelease(projectHelmChart); err != nil {
+		return projectHelmChart, err
+	}
+
+	// allow another ProjectHelmChart tracking the same release to claim it
+	if err := h.enqueueOtherProjectHelmChartsTrackingRelease(projectHelmChart); err != nil {
+		return projectHelmChart, err
+	}
+
 	// get information about the projectHelmChart
 	projectID, err := h.getProjectID(projectHelmChart)
 	if err != nil {
@@ -328,7 +530,16 @@ func (h *handler) OnRemove(_ string, projectHelmChart *v1alpha1.ProjectHelmChart
 
 	// Get orphaned release namespace and apply it; if another ProjectHelmChart exists in this namespace, it will automatically remove
 	// the orphaned label on enqueuing the namespace since that will enqueue all ProjectHelmCharts associated with it
-	projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, true, projectHelmChart)
+	//
+	// Note: a shared release namespace is only orphaned once the last ProjectHelmChart using it across all operators is removed
+	isOrphaned, err := h.shouldOrphanProjectReleaseNamespace(projectHelmChart)
+	if err != nil {
+		return projectHelmChart, err
+	}
+	if h.isSharedProjectReleaseNamespace(projectHelmChart) {
+		return projectHelmChart, h.applySharedProjectReleaseNamespace(projectID, isOrphaned, projectHelmChart)
+	}
+	projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, isOrphaned, projectHelmChart)
 	if projectReleaseNamespace == nil {
 		// nothing to be done since this operator does not create project release namespaces
 		return projectHelmChart, nil
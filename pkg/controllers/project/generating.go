@@ -0,0 +1,80 @@
+package project
+
+import (
+	"context"
+	"errors"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/generic"
+	"github.com/rancher/wrangler/pkg/kv"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// errSkipApply is returned by the OnChange handler alongside the status of a ProjectHelmChart that failed validation to indicate that
+// the status should be updated but the objects previously applied on behalf of the ProjectHelmChart should be left untouched
+//
+// Note: returning no objects instead would prune the HelmChart and HelmRelease, which would uninstall a release that is still healthy
+var errSkipApply = errors.New("skipping apply of objects for ProjectHelmChart")
+
+// projectHelmChartGeneratingHandler is identical to the generating handler registered by
+// helmprojectcontroller.RegisterProjectHelmChartGeneratingHandler, except that it does not apply any objects if the
+// handler returns errSkipApply
+type projectHelmChartGeneratingHandler struct {
+	helmprojectcontroller.ProjectHelmChartGeneratingHandler
+	apply apply.Apply
+	opts  generic.GeneratingHandlerOptions
+	gvk   schema.GroupVersionKind
+	name  string
+}
+
+// registerProjectHelmChartGeneratingHandler registers a generating handler for ProjectHelmCharts that supports errSkipApply
+func registerProjectHelmChartGeneratingHandler(ctx context.Context, controller helmprojectcontroller.ProjectHelmChartController, apply apply.Apply,
+	name string, handler helmprojectcontroller.ProjectHelmChartGeneratingHandler, opts *generic.GeneratingHandlerOptions) {
+	generatingHandler := &projectHelmChartGeneratingHandler{
+		ProjectHelmChartGeneratingHandler: handler,
+		apply:                             apply,
+		name:                              name,
+		gvk:                               controller.GroupVersionKind(),
+	}
+	if opts != nil {
+		generatingHandler.opts = *opts
+	}
+	controller.OnChange(ctx, name, generatingHandler.Remove)
+	helmprojectcontroller.RegisterProjectHelmChartStatusHandler(ctx, controller, "", name, generatingHandler.Handle)
+}
+
+func (a *projectHelmChartGeneratingHandler) Remove(key string, obj *v1alpha1.ProjectHelmChart) (*v1alpha1.ProjectHelmChart, error) {
+	if obj != nil {
+		return obj, nil
+	}
+
+	obj = &v1alpha1.ProjectHelmChart{}
+	obj.Namespace, obj.Name = kv.RSplit(key, "/")
+	obj.SetGroupVersionKind(a.gvk)
+
+	return nil, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects()
+}
+
+func (a *projectHelmChartGeneratingHandler) Handle(obj *v1alpha1.ProjectHelmChart, status v1alpha1.ProjectHelmChartStatus) (v1alpha1.ProjectHelmChartStatus, error) {
+	if !obj.DeletionTimestamp.IsZero() {
+		return status, nil
+	}
+
+	objs, newStatus, err := a.ProjectHelmChartGeneratingHandler(obj, status)
+	if errors.Is(err, errSkipApply) {
+		return newStatus, nil
+	}
+	if err != nil {
+		return newStatus, err
+	}
+
+	return newStatus, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects(objs...)
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"errors"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/apply/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestProjectHelmChartGeneratingHandler(t *testing.T) {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+	}
+	releaseNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cattle-project-p-example-monitoring",
+		},
+	}
+	testCases := []struct {
+		name          string
+		objs          []runtime.Object
+		err           error
+		expectErr     bool
+		expectApplied bool
+	}{
+		{
+			name:          "objects are applied",
+			objs:          []runtime.Object{releaseNamespace},
+			expectApplied: true,
+		},
+		{
+			name: "skip apply",
+			err:  errSkipApply,
+		},
+		{
+			name:      "error",
+			err:       errors.New("unable to reconcile"),
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeApply := &fake.FakeApply{}
+			generatingHandler := &projectHelmChartGeneratingHandler{
+				ProjectHelmChartGeneratingHandler: func(_ *v1alpha1.ProjectHelmChart, status v1alpha1.ProjectHelmChartStatus) ([]runtime.Object, v1alpha1.ProjectHelmChartStatus, error) {
+					status.Status = "Updated"
+					return tc.objs, status, tc.err
+				},
+				apply: fakeApply,
+				name:  "project-helm-chart-registration",
+			}
+			status, err := generatingHandler.Handle(projectHelmChart, v1alpha1.ProjectHelmChartStatus{})
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+			if status.Status != "Updated" {
+				t.Errorf("expected status returned by the handler to be kept, found %q", status.Status)
+			}
+			if applied := len(fakeApply.Objects) > 0; applied != tc.expectApplied {
+				t.Errorf("expected objects to be applied: %t, found %d applies", tc.expectApplied, len(fakeApply.Objects))
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package project
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
@@ -13,6 +15,16 @@ import (
 const (
 	// ProjectHelmChartByReleaseName identifies a ProjectHelmChart by the underlying Helm release it is tied to
 	ProjectHelmChartByReleaseName = "helm.cattle.io/project-helm-chart-by-release-name"
+
+	// ProjectHelmChartByRBACAggregation identifies the set of ProjectHelmCharts whose RoleBindings in the Project Release Namespace
+	// are aggregated from bindings to the default ClusterRoles, which need to be re-enqueued on changes to ClusterRoleBindings.
+	// The value of this will be the name of each default ClusterRole that the ProjectHelmChart aggregates bindings from.
+	ProjectHelmChartByRBACAggregation = "helm.cattle.io/project-helm-chart-by-rbac-aggregation"
+
+	// ClusterRoleByReleaseNamespaceName identifies a ClusterRole marked with the Project Helm Chart Role labels and the fan-out label
+	// that needs to be bound in every target project namespace of a ProjectHelmChart.
+	// The value of this will be the namespace (from the Helm release namespace annotation) and name of the Helm release that it is for.
+	ClusterRoleByReleaseNamespaceName = "helm.cattle.io/cluster-role-by-release-namespace-name"
 )
 
 // Registration namespaces only
@@ -26,17 +38,27 @@ const (
 	ClusterRoleBindingByRoleRef = "helm.cattle.io/cluster-role-binding-by-role-ref"
 
 	// BindingReferencesDefaultOperatorRole is the value of the both of the above indices when a ClusterRoleBinding or RoleBinding
-	// is tied to a RoleRef that matches a default ClusterRole that is watched by the operator to create admin, edit, or view RoleBindings
+	// is tied to a RoleRef that matches a default ClusterRole that is watched by the operator to create admin, edit, view, or any other tier of RoleBindings
 	// in the Project Release Namespace
 	BindingReferencesDefaultOperatorRole = "bound-to-default-role"
 )
 
 // NamespacedBindingReferencesDefaultOperatorRole is the index used to mark a RoleBinding as one that targets
-// one of the default operator roles (supplied in RuntimeOptions under AdminClusterRole, EditClusterRole, and ViewClusterRole)
+// one of the default operator roles (supplied in RuntimeOptions under AdminClusterRole, EditClusterRole, ViewClusterRole, and ClusterRoleTiers)
 func NamespacedBindingReferencesDefaultOperatorRole(namespace string) string {
 	return fmt.Sprintf("%s/%s", namespace, BindingReferencesDefaultOperatorRole)
 }
 
+// Project namespaces only
+const (
+	// RoleBindingInProjectNamespaceByRoleRef identifies the set of RoleBindings in a project namespace (a namespace that is neither a
+	// registration namespace nor a system namespace) that are tied to specific ClusterRoles that need to be watched by the operator
+	//
+	// Note: this index is only populated if RuntimeOptions.AggregateProjectNamespaceRoleBindings is set. The value of this index
+	// is the same as the value of RoleBindingInRegistrationNamespaceByRoleRef (see NamespacedBindingReferencesDefaultOperatorRole)
+	RoleBindingInProjectNamespaceByRoleRef = "helm.cattle.io/role-binding-in-project-ns-by-role-ref"
+)
+
 // Release namespaces only
 const (
 	// RoleInReleaseNamespaceByReleaseNamespaceName identifies a Role in a release namespace that needs to have RBAC synced
@@ -55,10 +77,18 @@ const (
 func (h *handler) initIndexers() {
 	h.projectHelmChartCache.AddIndexer(ProjectHelmChartByReleaseName, h.projectHelmChartToReleaseName)
 
+	h.projectHelmChartCache.AddIndexer(ProjectHelmChartByRBACAggregation, h.projectHelmChartToRBACAggregation)
+
 	h.rolebindingCache.AddIndexer(RoleBindingInRegistrationNamespaceByRoleRef, h.roleBindingInRegistrationNamespaceToRoleRef)
 
+	if h.opts.AggregateProjectNamespaceRoleBindings {
+		h.rolebindingCache.AddIndexer(RoleBindingInProjectNamespaceByRoleRef, h.roleBindingInProjectNamespaceToRoleRef)
+	}
+
 	h.clusterrolebindingCache.AddIndexer(ClusterRoleBindingByRoleRef, h.clusterRoleBindingToRoleRef)
 
+	h.clusterroleCache.AddIndexer(ClusterRoleByReleaseNamespaceName, h.clusterRoleToReleaseNamespaceName)
+
 	h.roleCache.AddIndexer(RoleInReleaseNamespaceByReleaseNamespaceName, h.roleInReleaseNamespaceToReleaseNamespaceName)
 
 	h.configmapCache.AddIndexer(ConfigMapInReleaseNamespaceByReleaseNamespaceName, h.configMapInReleaseNamespaceToReleaseNamespaceName)
@@ -73,6 +103,27 @@ func (h *handler) projectHelmChartToReleaseName(projectHelmChart *v1alpha1.Proje
 	return []string{releaseName}, nil
 }
 
+func (h *handler) projectHelmChartToRBACAggregation(projectHelmChart *v1alpha1.ProjectHelmChart) ([]string, error) {
+	if projectHelmChart == nil {
+		return nil, nil
+	}
+	// Note: whether the ProjectHelmChart lives in a Project Registration Namespace is intentionally not checked here since that
+	// depends on the namespace, which can change without the ProjectHelmChart changing; resolvers must check shouldManage instead
+	if projectHelmChart.Spec.HelmAPIVersion != h.opts.HelmAPIVersion {
+		return nil, nil
+	}
+	var clusterRoles []string
+	for _, clusterRole := range common.GetDefaultClusterRoles(h.opts) {
+		if slices.Contains(clusterRoles, clusterRole) {
+			// multiple tiers can be aggregated from the same ClusterRole
+			continue
+		}
+		clusterRoles = append(clusterRoles, clusterRole)
+	}
+	sort.Strings(clusterRoles)
+	return clusterRoles, nil
+}
+
 func (h *handler) roleBindingInRegistrationNamespaceToRoleRef(rb *rbacv1.RoleBinding) ([]string, error) {
 	if rb == nil {
 		return nil, nil
@@ -100,6 +151,30 @@ func (h *handler) roleBindingInRegistrationNamespaceToRoleRef(rb *rbacv1.RoleBin
 	return []string{NamespacedBindingReferencesDefaultOperatorRole(rb.Namespace)}, nil
 }
 
+func (h *handler) roleBindingInProjectNamespaceToRoleRef(rb *rbacv1.RoleBinding) ([]string, error) {
+	if rb == nil {
+		return nil, nil
+	}
+	if rb.RoleRef.Kind != "ClusterRole" {
+		// RoleBindings in a project namespace can point to a Role with the same name as a default ClusterRole
+		return nil, nil
+	}
+	namespace, err := h.namespaceCache.Get(rb.Namespace)
+	if err != nil {
+		// see note in roleBindingInRegistrationNamespaceToRoleRef
+		return nil, nil
+	}
+	if h.projectGetter.IsProjectRegistrationNamespace(namespace) || h.projectGetter.IsSystemNamespace(namespace) {
+		return nil, nil
+	}
+	_, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, rb.RoleRef.Name)
+	if !isDefaultRoleRef {
+		return nil, nil
+	}
+	// keep track of this rolebinding in the index so we can grab it later
+	return []string{NamespacedBindingReferencesDefaultOperatorRole(rb.Namespace)}, nil
+}
+
 func (h *handler) clusterRoleBindingToRoleRef(crb *rbacv1.ClusterRoleBinding) ([]string, error) {
 	if crb == nil {
 		return nil, nil
@@ -114,6 +189,23 @@ func (h *handler) clusterRoleBindingToRoleRef(crb *rbacv1.ClusterRoleBinding) ([
 	return []string{BindingReferencesDefaultOperatorRole}, nil
 }
 
+func (h *handler) clusterRoleToReleaseNamespaceName(clusterRole *rbacv1.ClusterRole) ([]string, error) {
+	if clusterRole == nil {
+		return nil, nil
+	}
+	if !common.HasFanOutLabel(clusterRole.Labels) {
+		// only ClusterRoles that are fanned out are tied to a release
+		return nil, nil
+	}
+	// ClusterRoles are not namespaced, so the release namespace is identified by the annotation added by Helm to ensure that
+	// releases with the same name in different release namespaces are not tied to each other's ClusterRoles
+	releaseNamespace, ok := clusterRole.Annotations[common.HelmReleaseNamespaceAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	return h.getReleaseIndexFromNamespaceAndLabels(releaseNamespace, clusterRole.Labels, common.HelmProjectOperatorProjectHelmChartRoleLabel)
+}
+
 func (h *handler) roleInReleaseNamespaceToReleaseNamespaceName(role *rbacv1.Role) ([]string, error) {
 	if role == nil {
 		return nil, nil
@@ -0,0 +1,130 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// getPropagatedLabelsAndAnnotations returns the labels and annotations that should be propagated onto resources generated for this ProjectHelmChart
+// from the Project Registration Namespace and, if includeProjectHelmChart is set, the ProjectHelmChart itself
+func (h *handler) getPropagatedLabelsAndAnnotations(projectHelmChart *v1alpha1.ProjectHelmChart, includeProjectHelmChart bool) (map[string]string, map[string]string) {
+	labels := map[string]string{}
+	annotations := map[string]string{}
+	if len(h.opts.PropagatedLabels) == 0 && len(h.opts.PropagatedAnnotations) == 0 {
+		return labels, annotations
+	}
+	if h.opts.ShouldPropagateMetadataFromProjectRegistrationNamespace() {
+		// the Project Registration Namespace may not be in the cache yet, in which case it will be propagated on the next enqueue
+		if projectRegistrationNamespace, err := h.namespaceCache.Get(projectHelmChart.Namespace); err == nil {
+			for k, v := range common.GetPropagatedMetadata(projectRegistrationNamespace.Labels, h.opts.PropagatedLabels) {
+				labels[k] = v
+			}
+			for k, v := range common.GetPropagatedMetadata(projectRegistrationNamespace.Annotations, h.opts.PropagatedAnnotations) {
+				annotations[k] = v
+			}
+		}
+	}
+	if includeProjectHelmChart && h.opts.ShouldPropagateMetadataFromProjectHelmChart() {
+		// values on the ProjectHelmChart take precedence over the values on the Project Registration Namespace
+		for k, v := range common.GetPropagatedMetadata(projectHelmChart.Labels, h.opts.PropagatedLabels) {
+			labels[k] = v
+		}
+		for k, v := range common.GetPropagatedMetadata(projectHelmChart.Annotations, h.opts.PropagatedAnnotations) {
+			annotations[k] = v
+		}
+	}
+	return labels, annotations
+}
+
+// addPropagatedLabelsAndAnnotations adds the propagated labels and annotations of this ProjectHelmChart onto the provided objects
+// without overriding any labels or annotations that are already set on them (e.g. those managed by the operator)
+//
+// Note: namespaces are skipped since the Project Release Namespace already receives propagated metadata in getProjectReleaseNamespace
+func (h *handler) addPropagatedLabelsAndAnnotations(projectHelmChart *v1alpha1.ProjectHelmChart, objs []runtime.Object) error {
+	labels, annotations := h.getPropagatedLabelsAndAnnotations(projectHelmChart, true)
+	if len(labels) == 0 && len(annotations) == 0 {
+		return nil
+	}
+	for _, obj := range objs {
+		if _, ok := obj.(*corev1.Namespace); ok {
+			continue
+		}
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		objMeta.SetLabels(addMissing(objMeta.GetLabels(), labels))
+		objMeta.SetAnnotations(addMissing(objMeta.GetAnnotations(), annotations))
+	}
+	return nil
+}
+
+// addMissing adds all entries from toAdd that do not already exist in current
+func addMissing(current map[string]string, toAdd map[string]string) map[string]string {
+	if len(toAdd) == 0 {
+		return current
+	}
+	if current == nil {
+		current = map[string]string{}
+	}
+	for k, v := range toAdd {
+		if _, ok := current[k]; ok {
+			continue
+		}
+		current[k] = v
+	}
+	return current
+}
+
+// validateReleaseNamespaceMetadata validates that the spec.releaseNamespaceMetadata provided on the ProjectHelmChart, if any, can be applied
+func (h *handler) validateReleaseNamespaceMetadata(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	releaseNamespaceMetadata := projectHelmChart.Spec.ReleaseNamespaceMetadata
+	if releaseNamespaceMetadata == nil || (len(releaseNamespaceMetadata.Labels) == 0 && len(releaseNamespaceMetadata.Annotations) == 0) {
+		return nil
+	}
+	if !h.isOperatorManagedReleaseNamespace(projectHelmChart) {
+		return fmt.Errorf("metadata can only be provided for dedicated project release namespaces created by the operator")
+	}
+	if h.opts.SharedProjectReleaseNamespace {
+		return fmt.Errorf("metadata cannot be provided for a project release namespace that is shared by all ProjectHelmCharts in the project")
+	}
+	for k, v := range releaseNamespaceMetadata.Labels {
+		if h.isReservedReleaseNamespaceMetadataKey(k) {
+			return fmt.Errorf("label %s is reserved and cannot be provided", k)
+		}
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %s: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("invalid value for label %s: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	for k := range releaseNamespaceMetadata.Annotations {
+		if h.isReservedReleaseNamespaceMetadataKey(k) {
+			return fmt.Errorf("annotation %s is reserved and cannot be provided", k)
+		}
+		if errs := validation.IsQualifiedName(strings.ToLower(k)); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %s: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// isReservedReleaseNamespaceMetadataKey returns whether a label or annotation key cannot be provided in spec.releaseNamespaceMetadata since
+// it is managed by the operator, identifies the project of the namespace, or is in a domain reserved by Kubernetes (e.g. Pod Security labels)
+func (h *handler) isReservedReleaseNamespaceMetadataKey(key string) bool {
+	if domain, _, ok := strings.Cut(key, "/"); ok {
+		if domain == "helm.cattle.io" || domain == "kubernetes.io" || strings.HasSuffix(domain, ".kubernetes.io") {
+			return true
+		}
+	}
+	return slices.Contains(h.opts.GetProjectLabels(), key)
+}
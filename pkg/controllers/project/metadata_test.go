@@ -0,0 +1,91 @@
+package project
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateReleaseNamespaceMetadata(t *testing.T) {
+	h := &handler{
+		opts: common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				ProjectLabel:             "field.cattle.io/projectId",
+				ParentProjectLabels:      []string{"example.io/parent-project"},
+				ProjectReleaseLabelValue: "p-release",
+			},
+		},
+	}
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expectErr   bool
+	}{
+		{
+			name:        "user metadata",
+			labels:      map[string]string{"team": "payments", "example.io/cost-center": "1234"},
+			annotations: map[string]string{"example.io/owner": "payments@example.io"},
+		},
+		{
+			name:      "invalid label value",
+			labels:    map[string]string{"team": "payments team"},
+			expectErr: true,
+		},
+		{
+			name:      "operator label",
+			labels:    map[string]string{common.HelmProjectOperatedNamespaceOrphanedLabel: "true"},
+			expectErr: true,
+		},
+		{
+			name:        "operator annotation",
+			annotations: map[string]string{"helm.cattle.io/any": "value"},
+			expectErr:   true,
+		},
+		{
+			name:      "project label",
+			labels:    map[string]string{"field.cattle.io/projectId": "p-other"},
+			expectErr: true,
+		},
+		{
+			name:        "parent project annotation",
+			annotations: map[string]string{"example.io/parent-project": "p-other"},
+			expectErr:   true,
+		},
+		{
+			name:      "kubernetes label",
+			labels:    map[string]string{"kubernetes.io/metadata.name": "other"},
+			expectErr: true,
+		},
+		{
+			name:      "kubernetes subdomain label",
+			labels:    map[string]string{"pod-security.kubernetes.io/enforce": "privileged"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectHelmChart := &v1alpha1.ProjectHelmChart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project-monitoring",
+					Namespace: "cattle-project-p-example",
+				},
+				Spec: v1alpha1.ProjectHelmChartSpec{
+					ReleaseNamespaceMetadata: &v1alpha1.ReleaseNamespaceMetadata{
+						Labels:      tc.labels,
+						Annotations: tc.annotations,
+					},
+				},
+			}
+			err := h.validateReleaseNamespaceMetadata(projectHelmChart)
+			if tc.expectErr && err == nil {
+				t.Fatalf("expected release namespace metadata to be rejected")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("expected release namespace metadata to be valid: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// getReleaseClaimant returns the ProjectHelmChart identifier that should be recorded as the owner of a release
+func getReleaseClaimant(projectHelmChart *v1alpha1.ProjectHelmChart) string {
+	return fmt.Sprintf("%s/%s", projectHelmChart.Namespace, projectHelmChart.Name)
+}
+
+// getProjectHelmChartsTrackingRelease returns all ProjectHelmCharts that would deploy the same release as this ProjectHelmChart
+func (h *handler) getProjectHelmChartsTrackingRelease(projectHelmChart *v1alpha1.ProjectHelmChart) ([]*v1alpha1.ProjectHelmChart, error) {
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	projectHelmCharts, err := h.projectHelmChartCache.GetByIndex(ProjectHelmChartByReleaseName, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	var trackingProjectHelmCharts []*v1alpha1.ProjectHelmChart
+	for _, trackingProjectHelmChart := range projectHelmCharts {
+		if trackingProjectHelmChart == nil {
+			continue
+		}
+		if trackingProjectHelmChart.DeletionTimestamp != nil && getReleaseClaimant(trackingProjectHelmChart) != getReleaseClaimant(projectHelmChart) {
+			// a ProjectHelmChart that is being deleted gives up its claim on the release
+			continue
+		}
+		trackingProjectHelmCharts = append(trackingProjectHelmCharts, trackingProjectHelmChart)
+	}
+	return trackingProjectHelmCharts, nil
+}
+
+// getReleaseOwner returns the ProjectHelmChart that owns the release that this ProjectHelmChart would deploy
+//
+// Ownership is determined in the following order, which ensures that the owner of a release does not depend on the order in which
+// ProjectHelmCharts are processed or on the contents of their statuses:
+// 1. If any ProjectHelmChart tracking the release has the take-over-release annotation, the current claimant keeps the release if it
+// has the annotation as well; otherwise, the oldest ProjectHelmChart with the annotation takes over the release
+// 2. The ProjectHelmChart recorded in the claim annotation of the HelmRelease keeps the release as long as it exists
+// 3. The oldest ProjectHelmChart (by creationTimestamp) claims the release
+func (h *handler) getReleaseOwner(projectHelmChart *v1alpha1.ProjectHelmChart) (*v1alpha1.ProjectHelmChart, error) {
+	projectHelmCharts, err := h.getProjectHelmChartsTrackingRelease(projectHelmChart)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ProjectHelmCharts to verify if release is already tracked: %s", err)
+	}
+	found := false
+	for _, trackingProjectHelmChart := range projectHelmCharts {
+		if getReleaseClaimant(trackingProjectHelmChart) == getReleaseClaimant(projectHelmChart) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		// the cache may not have indexed this ProjectHelmChart yet
+		projectHelmCharts = append(projectHelmCharts, projectHelmChart)
+	}
+	sort.Slice(projectHelmCharts, func(i, j int) bool {
+		iTimestamp, jTimestamp := projectHelmCharts[i].CreationTimestamp, projectHelmCharts[j].CreationTimestamp
+		if !iTimestamp.Equal(&jTimestamp) {
+			return iTimestamp.Before(&jTimestamp)
+		}
+		return getReleaseClaimant(projectHelmCharts[i]) < getReleaseClaimant(projectHelmCharts[j])
+	})
+
+	claimant, err := h.getCurrentReleaseClaimant(projectHelmChart)
+	if err != nil {
+		return nil, err
+	}
+	var currentOwner, oldestTakeover *v1alpha1.ProjectHelmChart
+	for _, trackingProjectHelmChart := range projectHelmCharts {
+		if len(claimant) > 0 && getReleaseClaimant(trackingProjectHelmChart) == claimant {
+			currentOwner = trackingProjectHelmChart
+		}
+		if oldestTakeover == nil && common.HasTakeOverReleaseAnnotation(trackingProjectHelmChart) {
+			oldestTakeover = trackingProjectHelmChart
+		}
+	}
+	switch {
+	case currentOwner != nil && common.HasTakeOverReleaseAnnotation(currentOwner):
+		return currentOwner, nil
+	case oldestTakeover != nil:
+		return oldestTakeover, nil
+	case currentOwner != nil:
+		return currentOwner, nil
+	default:
+		return projectHelmCharts[0], nil
+	}
+}
+
+// getCurrentReleaseClaimant returns the ProjectHelmChart recorded as the owner of the release on the HelmRelease, if it exists
+//
+// Note: a HelmRelease is always created in the system namespace for every release, regardless of the deployment backend used
+func (h *handler) getCurrentReleaseClaimant(projectHelmChart *v1alpha1.ProjectHelmChart) (string, error) {
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	helmRelease, err := h.helmReleaseCache.Get(h.systemNamespace, releaseName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to get HelmRelease %s/%s to identify the owner of the release: %s", h.systemNamespace, releaseName, err)
+	}
+	if helmRelease.Annotations == nil {
+		return "", nil
+	}
+	return helmRelease.Annotations[common.HelmProjectOperatorReleaseClaimAnnotation], nil
+}
+
+// enqueueOtherProjectHelmChartsTrackingRelease re-enqueues all other ProjectHelmCharts that would deploy the same release as this
+// ProjectHelmChart to ensure that their statuses reflect the current owner of the release
+func (h *handler) enqueueOtherProjectHelmChartsTrackingRelease(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	projectHelmCharts, err := h.getProjectHelmChartsTrackingRelease(projectHelmChart)
+	if err != nil {
+		return fmt.Errorf("unable to get ProjectHelmCharts tracking the same release: %s", err)
+	}
+	for _, trackingProjectHelmChart := range projectHelmCharts {
+		if getReleaseClaimant(trackingProjectHelmChart) == getReleaseClaimant(projectHelmChart) {
+			continue
+		}
+		h.projectHelmCharts.Enqueue(trackingProjectHelmChart.Namespace, trackingProjectHelmChart.Name)
+	}
+	return nil
+}
+
+// enqueueOtherProjectHelmChartsOnClaim re-enqueues all other ProjectHelmCharts that would deploy the same release as this ProjectHelmChart
+// only if this ProjectHelmChart is not already recorded as the owner of the release, i.e. if the owner of the release is changing
+//
+// Note: once the claim of this ProjectHelmChart is recorded on the HelmRelease, other ProjectHelmCharts tracking the release are no
+// longer re-enqueued on every reconcile, which would otherwise cause the owner and the other ProjectHelmCharts to be processed endlessly
+func (h *handler) enqueueOtherProjectHelmChartsOnClaim(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	claimant, err := h.getCurrentReleaseClaimant(projectHelmChart)
+	if err != nil {
+		return err
+	}
+	if claimant == getReleaseClaimant(projectHelmChart) {
+		return nil
+	}
+	return h.enqueueOtherProjectHelmChartsTrackingRelease(projectHelmChart)
+}
+
+// addReleaseClaim records the ProjectHelmChart as the owner of the release on all objects created by the deployment backend
+func addReleaseClaim(projectHelmChart *v1alpha1.ProjectHelmChart, objs []runtime.Object) error {
+	for _, obj := range objs {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		annotations := objMeta.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[common.HelmProjectOperatorReleaseClaimAnnotation] = getReleaseClaimant(projectHelmChart)
+		objMeta.SetAnnotations(annotations)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package project
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmlockerv1alpha1 "github.com/rancher/helm-project-operator/pkg/helm-locker/apis/helm.cattle.io/v1alpha1"
+	helmlockercontroller "github.com/rancher/helm-project-operator/pkg/helm-locker/generated/controllers/helm.cattle.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeHelmReleaseCache is a HelmReleaseCache backed by the provided HelmReleases
+type fakeHelmReleaseCache struct {
+	helmlockercontroller.HelmReleaseCache
+
+	helmReleases []*helmlockerv1alpha1.HelmRelease
+}
+
+func (c *fakeHelmReleaseCache) Get(namespace, name string) (*helmlockerv1alpha1.HelmRelease, error) {
+	for _, helmRelease := range c.helmReleases {
+		if helmRelease.Namespace == namespace && helmRelease.Name == name {
+			return helmRelease, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: "helm.cattle.io", Resource: "helmreleases"}, name)
+}
+
+// newOwnershipTestHandler returns a handler where the provided ProjectHelmCharts all track the same release, which is claimed by the
+// provided claimant on its HelmRelease if a claimant is provided
+func newOwnershipTestHandler(projectHelmCharts []*v1alpha1.ProjectHelmChart, claimant string) *handler {
+	systemNamespace := "cattle-helm-system"
+	var registrationNamespaces []*corev1.Namespace
+	var registrationNamespaceNames []string
+	for _, projectHelmChart := range projectHelmCharts {
+		registrationNamespaces = append(registrationNamespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: projectHelmChart.Namespace}})
+		registrationNamespaceNames = append(registrationNamespaceNames, projectHelmChart.Namespace)
+	}
+	h := &handler{
+		systemNamespace: systemNamespace,
+		opts: common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				DeploymentBackend: "helm-sdk",
+			},
+		},
+		namespaceCache: &fakeNamespaceCache{namespaces: registrationNamespaces},
+		projectGetter:  &fakeProjectGetter{registrationNamespaces: registrationNamespaceNames},
+	}
+	h.projectHelmChartCache = &fakeProjectHelmChartCache{
+		projectHelmCharts: projectHelmCharts,
+		indexer:           h.projectHelmChartToReleaseName,
+	}
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmCharts[0])
+	helmReleaseCache := &fakeHelmReleaseCache{}
+	if len(claimant) > 0 {
+		helmReleaseCache.helmReleases = append(helmReleaseCache.helmReleases, &helmlockerv1alpha1.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      releaseName,
+				Namespace: systemNamespace,
+				Annotations: map[string]string{
+					common.HelmProjectOperatorReleaseClaimAnnotation: claimant,
+				},
+			},
+		})
+	}
+	h.helmReleaseCache = helmReleaseCache
+	return h
+}
+
+// newTrackingProjectHelmChart returns a ProjectHelmChart in its own registration namespace that was created at the provided offset
+func newTrackingProjectHelmChart(namespace string, createdAfter time.Duration, takeOver bool) *v1alpha1.ProjectHelmChart {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "project-monitoring",
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(createdAfter)),
+		},
+	}
+	if takeOver {
+		projectHelmChart.Annotations = map[string]string{
+			common.HelmProjectOperatorTakeOverReleaseAnnotation: "true",
+		}
+	}
+	return projectHelmChart
+}
+
+func TestGetReleaseOwner(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	deleted := func(projectHelmChart *v1alpha1.ProjectHelmChart) *v1alpha1.ProjectHelmChart {
+		projectHelmChart.DeletionTimestamp = &deletionTimestamp
+		return projectHelmChart
+	}
+	testCases := []struct {
+		name              string
+		projectHelmCharts []*v1alpha1.ProjectHelmChart
+		claimant          string
+		expectedOwner     string
+	}{
+		{
+			name: "recorded claim keeps the release over an older ProjectHelmChart",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-older", 0, false),
+				newTrackingProjectHelmChart("cattle-project-p-claimant", time.Hour, false),
+			},
+			claimant:      "cattle-project-p-claimant/project-monitoring",
+			expectedOwner: "cattle-project-p-claimant/project-monitoring",
+		},
+		{
+			name: "oldest ProjectHelmChart claims an unclaimed release",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-newer", time.Hour, false),
+				newTrackingProjectHelmChart("cattle-project-p-oldest", 0, false),
+			},
+			expectedOwner: "cattle-project-p-oldest/project-monitoring",
+		},
+		{
+			name: "ProjectHelmCharts created at the same time are ordered by namespace",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-b", 0, false),
+				newTrackingProjectHelmChart("cattle-project-p-a", 0, false),
+			},
+			expectedOwner: "cattle-project-p-a/project-monitoring",
+		},
+		{
+			name: "recorded claim for a ProjectHelmChart that no longer exists falls back to the oldest",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-newer", time.Hour, false),
+				newTrackingProjectHelmChart("cattle-project-p-oldest", 0, false),
+			},
+			claimant:      "cattle-project-p-removed/project-monitoring",
+			expectedOwner: "cattle-project-p-oldest/project-monitoring",
+		},
+		{
+			name: "take-over annotation takes over a claimed release",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-claimant", 0, false),
+				newTrackingProjectHelmChart("cattle-project-p-takeover", time.Hour, true),
+			},
+			claimant:      "cattle-project-p-claimant/project-monitoring",
+			expectedOwner: "cattle-project-p-takeover/project-monitoring",
+		},
+		{
+			name: "claimant with the take-over annotation keeps the release over an older take-over",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-older-takeover", 0, true),
+				newTrackingProjectHelmChart("cattle-project-p-claimant", time.Hour, true),
+			},
+			claimant:      "cattle-project-p-claimant/project-monitoring",
+			expectedOwner: "cattle-project-p-claimant/project-monitoring",
+		},
+		{
+			name: "oldest take-over claims the release",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				newTrackingProjectHelmChart("cattle-project-p-oldest", 0, false),
+				newTrackingProjectHelmChart("cattle-project-p-newer-takeover", 2*time.Hour, true),
+				newTrackingProjectHelmChart("cattle-project-p-older-takeover", time.Hour, true),
+			},
+			expectedOwner: "cattle-project-p-older-takeover/project-monitoring",
+		},
+		{
+			name: "deleted owner gives up its claim",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{
+				deleted(newTrackingProjectHelmChart("cattle-project-p-claimant", 0, false)),
+				newTrackingProjectHelmChart("cattle-project-p-newer", 2*time.Hour, false),
+				newTrackingProjectHelmChart("cattle-project-p-older", time.Hour, false),
+			},
+			claimant:      "cattle-project-p-claimant/project-monitoring",
+			expectedOwner: "cattle-project-p-older/project-monitoring",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newOwnershipTestHandler(tc.projectHelmCharts, tc.claimant)
+			for _, projectHelmChart := range tc.projectHelmCharts {
+				if projectHelmChart.DeletionTimestamp != nil {
+					continue
+				}
+				// the owner must not depend on which ProjectHelmChart is processed
+				owner, err := h.getReleaseOwner(projectHelmChart)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if getReleaseClaimant(owner) != tc.expectedOwner {
+					t.Errorf("expected %s to be the owner when processing %s, found %s", tc.expectedOwner, getReleaseClaimant(projectHelmChart), getReleaseClaimant(owner))
+				}
+			}
+		})
+	}
+}
+
+func TestGetReleaseOwnerForDeletedOwner(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	owner := newTrackingProjectHelmChart("cattle-project-p-claimant", 0, false)
+	owner.DeletionTimestamp = &deletionTimestamp
+	projectHelmCharts := []*v1alpha1.ProjectHelmChart{
+		owner,
+		newTrackingProjectHelmChart("cattle-project-p-other", time.Hour, false),
+	}
+	h := newOwnershipTestHandler(projectHelmCharts, getReleaseClaimant(owner))
+	// the owner that is being deleted is still allowed to remove its release
+	releaseOwner, err := h.getReleaseOwner(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getReleaseClaimant(releaseOwner) != getReleaseClaimant(owner) {
+		t.Errorf("expected %s to remain the owner while it is removed, found %s", getReleaseClaimant(owner), getReleaseClaimant(releaseOwner))
+	}
+}
+
+func TestEnqueueOtherProjectHelmChartsOnClaim(t *testing.T) {
+	owner := newTrackingProjectHelmChart("cattle-project-p-owner", 0, false)
+	other := newTrackingProjectHelmChart("cattle-project-p-other", time.Hour, false)
+	testCases := []struct {
+		name             string
+		claimant         string
+		expectedEnqueued []string
+	}{
+		{
+			name:             "release is not claimed yet",
+			expectedEnqueued: []string{getReleaseClaimant(other)},
+		},
+		{
+			name:             "release is claimed by another ProjectHelmChart",
+			claimant:         getReleaseClaimant(other),
+			expectedEnqueued: []string{getReleaseClaimant(other)},
+		},
+		{
+			name:     "release is already claimed by this ProjectHelmChart",
+			claimant: getReleaseClaimant(owner),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newOwnershipTestHandler([]*v1alpha1.ProjectHelmChart{owner, other}, tc.claimant)
+			projectHelmCharts := &fakeProjectHelmChartController{}
+			h.projectHelmCharts = projectHelmCharts
+			if err := h.enqueueOtherProjectHelmChartsOnClaim(owner); err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(projectHelmCharts.enqueued)
+			if !reflect.DeepEqual(projectHelmCharts.enqueued, tc.expectedEnqueued) {
+				t.Errorf("expected %v to be enqueued, found %v", tc.expectedEnqueued, projectHelmCharts.enqueued)
+			}
+		})
+	}
+}
@@ -5,68 +5,94 @@ import (
 
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/helm-project-operator/pkg/naming"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Note: each resource created here should have a resolver set in resolvers.go
 
+const (
+	// helmReleaseSecretType is the type of the Secrets that Helm stores releases in, which are never synced since they could be
+	// used to modify the history of releases deployed in the Project Release Namespace
+	helmReleaseSecretType corev1.SecretType = "helm.sh/release.v1"
+)
+
 // getSubjectRoleToSubjectsFromBindings gets all RoleBindings in the Project Registration Namespace that need to be synced to assign the corresponding
 // permission in the Project Release Namespace. See pkg/controllers/project/resources.go for more information on how this is used
-func (h *handler) getSubjectRoleToSubjectsFromBindings(projectHelmChart *v1alpha1.ProjectHelmChart) (map[string][]rbacv1.Subject, error) {
+//
+// Note: if AggregateProjectNamespaceRoleBindings is set, RoleBindings in the provided target namespaces (excluding the Project Release Namespace)
+// are also synced
+//
+// Note: ServiceAccount subjects are only included for tiers in ServiceAccountSubjectTiers and only if they live in the Project Registration Namespace
+// or in one of the provided target namespaces (excluding the Project Release Namespace)
+func (h *handler) getSubjectRoleToSubjectsFromBindings(projectHelmChart *v1alpha1.ProjectHelmChart, targetNamespaces []string) (map[string][]rbacv1.Subject, subjectSources, error) {
 	defaultClusterRoles := common.GetDefaultClusterRoles(h.opts)
 	subjectRoleToSubjects := make(map[string][]rbacv1.Subject)
 	subjectRoleToSubjectMap := make(map[string]map[string]rbacv1.Subject)
+	sources := make(subjectSources)
 	if len(defaultClusterRoles) == 0 {
 		// no roles to get get subjects for
-		return subjectRoleToSubjects, nil
+		return subjectRoleToSubjects, sources, nil
 	}
 	for subjectRole := range defaultClusterRoles {
 		subjectRoleToSubjectMap[subjectRole] = make(map[string]rbacv1.Subject)
 	}
+	serviceAccountNamespaces := h.getServiceAccountSubjectNamespaces(projectHelmChart, targetNamespaces)
 	roleBindings, err := h.rolebindingCache.GetByIndex(
 		RoleBindingInRegistrationNamespaceByRoleRef,
 		NamespacedBindingReferencesDefaultOperatorRole(projectHelmChart.Namespace),
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, rb := range roleBindings {
 		if rb == nil {
 			continue
 		}
-		subjectRole, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, rb.RoleRef.Name)
+		subjectRoles, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, rb.RoleRef.Name)
 		if !isDefaultRoleRef {
-			logrus.Debugf("Role %s is not a default role for %s", subjectRole, projectHelmChart.Namespace)
+			logrus.Debugf("Role %s is not a default role for %s", rb.RoleRef.Name, projectHelmChart.Namespace)
 			continue
 		}
-		filteredSubjects := common.FilterToUsersAndGroups(rb.Subjects)
-		currSubjects := subjectRoleToSubjectMap[subjectRole]
-		for _, filteredSubject := range filteredSubjects {
-			// collect into a map to avoid putting duplicates of the same subject
-			// we use an index of kind and name since a Group can have the same name as a User, but should be considered separate
-			currSubjects[fmt.Sprintf("%s-%s", filteredSubject.Kind, filteredSubject.Name)] = filteredSubject
+		source := fmt.Sprintf("RoleBinding %s/%s", rb.Namespace, rb.Name)
+		addSubjectsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToUsersAndGroups(rb.Subjects))
+		h.addServiceAccountsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToServiceAccounts(rb.Subjects, rb.Namespace, serviceAccountNamespaces))
+	}
+	projectRoleBindings, err := h.getRoleBindingsInProjectNamespaces(projectHelmChart, targetNamespaces)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, rb := range projectRoleBindings {
+		subjectRoles, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, rb.RoleRef.Name)
+		if !isDefaultRoleRef {
+			continue
 		}
+		source := fmt.Sprintf("RoleBinding %s/%s", rb.Namespace, rb.Name)
+		addSubjectsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToUsersAndGroups(rb.Subjects))
+		h.addServiceAccountsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToServiceAccounts(rb.Subjects, rb.Namespace, serviceAccountNamespaces))
 	}
 	clusterRoleBindings, err := h.clusterrolebindingCache.GetByIndex(ClusterRoleBindingByRoleRef, BindingReferencesDefaultOperatorRole)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, crb := range clusterRoleBindings {
 		if crb == nil {
 			continue
 		}
-		subjectRole, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, crb.RoleRef.Name)
+		subjectRoles, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, crb.RoleRef.Name)
 		if !isDefaultRoleRef {
 			continue
 		}
-		filteredSubjects := common.FilterToUsersAndGroups(crb.Subjects)
-		currSubjects := subjectRoleToSubjectMap[subjectRole]
-		for _, filteredSubject := range filteredSubjects {
-			// collect into a map to avoid putting duplicates of the same subject
-			// we use an index of kind and name since a Group can have the same name as a User, but should be considered separate
-			currSubjects[fmt.Sprintf("%s-%s", filteredSubject.Kind, filteredSubject.Name)] = filteredSubject
-		}
+		source := fmt.Sprintf("ClusterRoleBinding %s", crb.Name)
+		addSubjectsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToUsersAndGroups(crb.Subjects))
+		h.addServiceAccountsToSubjectRoles(subjectRoleToSubjectMap, sources, source, subjectRoles, common.FilterToServiceAccounts(crb.Subjects, "", serviceAccountNamespaces))
 	}
 	// convert back into list so that no duplicates are created
 	for subjectRole := range defaultClusterRoles {
@@ -76,5 +102,194 @@ func (h *handler) getSubjectRoleToSubjectsFromBindings(projectHelmChart *v1alpha
 		}
 		subjectRoleToSubjects[subjectRole] = subjects
 	}
-	return subjectRoleToSubjects, nil
+	return subjectRoleToSubjects, sources, nil
+}
+
+// subjectSources tracks the bindings that each subject was collected from, indexed by subject role and then by subject key (see getSubjectKey)
+type subjectSources map[string]map[string][]string
+
+// getSubjectKey returns the key used to identify a unique subject
+// we use an index of kind and name since a Group can have the same name as a User, but should be considered separate
+// ServiceAccounts are additionally indexed by namespace since ServiceAccounts in different namespaces are separate
+func getSubjectKey(subject rbacv1.Subject) string {
+	return fmt.Sprintf("%s-%s-%s", subject.Kind, subject.Namespace, subject.Name)
+}
+
+// addSubjectsToSubjectRoles adds the provided subjects to the subjects collected for each of the provided subject roles
+// and records the provided source binding as one of the sources of each subject
+func addSubjectsToSubjectRoles(subjectRoleToSubjectMap map[string]map[string]rbacv1.Subject, sources subjectSources, source string, subjectRoles []string, subjects []rbacv1.Subject) {
+	for _, subjectRole := range subjectRoles {
+		currSubjects, ok := subjectRoleToSubjectMap[subjectRole]
+		if !ok {
+			continue
+		}
+		if _, ok := sources[subjectRole]; !ok {
+			sources[subjectRole] = make(map[string][]string)
+		}
+		for _, subject := range subjects {
+			// collect into a map to avoid putting duplicates of the same subject
+			subjectKey := getSubjectKey(subject)
+			currSubjects[subjectKey] = subject
+			sources[subjectRole][subjectKey] = append(sources[subjectRole][subjectKey], source)
+		}
+	}
+}
+
+// addServiceAccountsToSubjectRoles adds the provided ServiceAccount subjects to the subjects collected for each of the provided subject roles
+// that should aggregate ServiceAccounts
+func (h *handler) addServiceAccountsToSubjectRoles(subjectRoleToSubjectMap map[string]map[string]rbacv1.Subject, sources subjectSources, source string, subjectRoles []string, serviceAccounts []rbacv1.Subject) {
+	if len(serviceAccounts) == 0 {
+		return
+	}
+	var serviceAccountSubjectRoles []string
+	for _, subjectRole := range subjectRoles {
+		if common.ShouldAggregateServiceAccounts(h.opts, subjectRole) {
+			serviceAccountSubjectRoles = append(serviceAccountSubjectRoles, subjectRole)
+		}
+	}
+	addSubjectsToSubjectRoles(subjectRoleToSubjectMap, sources, source, serviceAccountSubjectRoles, serviceAccounts)
+}
+
+// getRoleBindingsInProjectNamespaces returns all RoleBindings to default ClusterRoles in the provided target namespaces of this ProjectHelmChart,
+// excluding the Project Release Namespace, if RoleBindings in project namespaces should be aggregated
+func (h *handler) getRoleBindingsInProjectNamespaces(projectHelmChart *v1alpha1.ProjectHelmChart, targetNamespaces []string) ([]*rbacv1.RoleBinding, error) {
+	if !h.opts.AggregateProjectNamespaceRoleBindings {
+		return nil, nil
+	}
+	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+	var roleBindings []*rbacv1.RoleBinding
+	for _, namespace := range targetNamespaces {
+		if namespace == releaseNamespace && h.isOperatorManagedReleaseNamespace(projectHelmChart) {
+			// the RoleBindings in the Project Release Namespace are created from these RoleBindings
+			continue
+		}
+		namespaceRoleBindings, err := h.rolebindingCache.GetByIndex(
+			RoleBindingInProjectNamespaceByRoleRef,
+			NamespacedBindingReferencesDefaultOperatorRole(namespace),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, rb := range namespaceRoleBindings {
+			if rb == nil {
+				continue
+			}
+			roleBindings = append(roleBindings, rb)
+		}
+	}
+	return roleBindings, nil
+}
+
+// getServiceAccountSubjectNamespaces returns the namespaces whose ServiceAccounts can be bound in the Project Release Namespace of this ProjectHelmChart
+func (h *handler) getServiceAccountSubjectNamespaces(projectHelmChart *v1alpha1.ProjectHelmChart, targetNamespaces []string) map[string]bool {
+	serviceAccountNamespaces := map[string]bool{}
+	if len(h.opts.ServiceAccountSubjectTiers) == 0 {
+		return serviceAccountNamespaces
+	}
+	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+	for _, namespace := range append([]string{projectHelmChart.Namespace}, targetNamespaces...) {
+		if namespace == releaseNamespace && h.isOperatorManagedReleaseNamespace(projectHelmChart) {
+			// the Project Release Namespace is not part of the project
+			continue
+		}
+		if namespace == h.systemNamespace {
+			// ServiceAccounts in the system namespace are never part of the project
+			continue
+		}
+		serviceAccountNamespaces[namespace] = true
+	}
+	return serviceAccountNamespaces
+}
+
+// getSyncedSecretsAndConfigMaps returns copies of all Secrets and ConfigMaps in the Project Registration Namespace with the label
+// helm.cattle.io/project-helm-chart-sync: {{ .Release.Name }} that should be created in the Project Release Namespace.
+//
+// Generally, these are used to provide charts with data managed by project owners (e.g. image pull secrets, TLS certificates, or
+// CA bundles) since project owners are not expected to have permissions to create resources in the Project Release Namespace.
+//
+// Note: since the Project Registration Namespace is writable by project owners, copies are always named synced-<release-name>-<name>
+// and are never allowed to replace an existing object in the Project Release Namespace that was not synced from the same object, to
+// ensure that project owners cannot use this to modify resources deployed by the release (e.g. the Secrets that store Helm releases)
+func (h *handler) getSyncedSecretsAndConfigMaps(projectID string, projectHelmChart *v1alpha1.ProjectHelmChart) ([]runtime.Object, error) {
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	if releaseNamespace == projectHelmChart.Namespace {
+		// the objects already exist in the release namespace
+		return nil, nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{
+		common.HelmProjectOperatorProjectHelmChartSyncLabel: releaseName,
+	})
+	var objs []runtime.Object
+	secrets, err := h.secretCache.List(projectHelmChart.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list secrets to sync from %s: %s", projectHelmChart.Namespace, err)
+	}
+	for _, secret := range secrets {
+		if secret == nil || secret.DeletionTimestamp != nil {
+			continue
+		}
+		if secret.Type == corev1.SecretTypeServiceAccountToken || secret.Type == helmReleaseSecretType {
+			logrus.Warnf("Unable to sync secret %s/%s into release namespace %s: secrets of type %s cannot be copied", secret.Namespace, secret.Name, releaseNamespace, secret.Type)
+			continue
+		}
+		name := getSyncedName(releaseName, secret.Name)
+		syncedFrom := fmt.Sprintf("%s/%s", secret.Namespace, secret.Name)
+		existing, err := h.secretCache.Get(releaseNamespace, name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && existing.Annotations[common.HelmProjectOperatorSyncedFromAnnotation] != syncedFrom {
+			logrus.Warnf("Unable to sync secret %s into release namespace %s: secret %s already exists and was not synced from it", syncedFrom, releaseNamespace, name)
+			continue
+		}
+		objs = append(objs, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: releaseNamespace,
+				Labels:    common.GetSyncedLabels(projectID, secret.Labels),
+				Annotations: map[string]string{
+					common.HelmProjectOperatorSyncedFromAnnotation: syncedFrom,
+				},
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		})
+	}
+	configMaps, err := h.configmapCache.List(projectHelmChart.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list configmaps to sync from %s: %s", projectHelmChart.Namespace, err)
+	}
+	for _, configMap := range configMaps {
+		if configMap == nil || configMap.DeletionTimestamp != nil {
+			continue
+		}
+		name := getSyncedName(releaseName, configMap.Name)
+		syncedFrom := fmt.Sprintf("%s/%s", configMap.Namespace, configMap.Name)
+		existing, err := h.configmapCache.Get(releaseNamespace, name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && existing.Annotations[common.HelmProjectOperatorSyncedFromAnnotation] != syncedFrom {
+			logrus.Warnf("Unable to sync configmap %s into release namespace %s: configmap %s already exists and was not synced from it", syncedFrom, releaseNamespace, name)
+			continue
+		}
+		objs = append(objs, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: releaseNamespace,
+				Labels:    common.GetSyncedLabels(projectID, configMap.Labels),
+				Annotations: map[string]string{
+					common.HelmProjectOperatorSyncedFromAnnotation: syncedFrom,
+				},
+			},
+			Data:       configMap.Data,
+			BinaryData: configMap.BinaryData,
+		})
+	}
+	return objs, nil
+}
+
+// getSyncedName returns the name of the copy of a Secret or ConfigMap synced into the Project Release Namespace of a release
+func getSyncedName(releaseName, name string) string {
+	return naming.SafeName(fmt.Sprintf("synced-%s-%s", releaseName, name), validation.DNS1123LabelMaxLength)
 }
@@ -0,0 +1,577 @@
+package project
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	rbaccontroller "github.com/rancher/wrangler/pkg/generated/controllers/rbac/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeSecretCache is a SecretCache backed by the provided Secrets
+type fakeSecretCache struct {
+	corecontroller.SecretCache
+
+	secrets []*corev1.Secret
+}
+
+func (c *fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	for _, secret := range c.secrets {
+		if secret.Namespace == namespace && secret.Name == name {
+			return secret, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func (c *fakeSecretCache) List(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	var secrets []*corev1.Secret
+	for _, secret := range c.secrets {
+		if secret.Namespace == namespace && selector.Matches(labels.Set(secret.Labels)) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+// fakeConfigMapCache is a ConfigMapCache backed by the provided ConfigMaps
+type fakeConfigMapCache struct {
+	corecontroller.ConfigMapCache
+
+	configMaps []*corev1.ConfigMap
+}
+
+func (c *fakeConfigMapCache) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	for _, configMap := range c.configMaps {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return configMap, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+func (c *fakeConfigMapCache) List(namespace string, selector labels.Selector) ([]*corev1.ConfigMap, error) {
+	var configMaps []*corev1.ConfigMap
+	for _, configMap := range c.configMaps {
+		if configMap.Namespace == namespace && selector.Matches(labels.Set(configMap.Labels)) {
+			configMaps = append(configMaps, configMap)
+		}
+	}
+	return configMaps, nil
+}
+
+// fakeRoleBindingCache is a RoleBindingCache backed by the provided RoleBindings that supports the provided indexers
+type fakeRoleBindingCache struct {
+	rbaccontroller.RoleBindingCache
+
+	roleBindings []*rbacv1.RoleBinding
+	indexers     map[string]func(rb *rbacv1.RoleBinding) ([]string, error)
+}
+
+func (c *fakeRoleBindingCache) GetByIndex(indexName, key string) ([]*rbacv1.RoleBinding, error) {
+	indexer, ok := c.indexers[indexName]
+	if !ok {
+		return nil, fmt.Errorf("indexer %s does not exist", indexName)
+	}
+	var roleBindings []*rbacv1.RoleBinding
+	for _, rb := range c.roleBindings {
+		indices, err := indexer(rb)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index == key {
+				roleBindings = append(roleBindings, rb)
+				break
+			}
+		}
+	}
+	return roleBindings, nil
+}
+
+// fakeClusterRoleBindingCache is a ClusterRoleBindingCache backed by the provided ClusterRoleBindings that supports a single indexer
+type fakeClusterRoleBindingCache struct {
+	rbaccontroller.ClusterRoleBindingCache
+
+	clusterRoleBindings []*rbacv1.ClusterRoleBinding
+	indexer             func(crb *rbacv1.ClusterRoleBinding) ([]string, error)
+}
+
+func (c *fakeClusterRoleBindingCache) GetByIndex(_, key string) ([]*rbacv1.ClusterRoleBinding, error) {
+	var clusterRoleBindings []*rbacv1.ClusterRoleBinding
+	for _, crb := range c.clusterRoleBindings {
+		indices, err := c.indexer(crb)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index == key {
+				clusterRoleBindings = append(clusterRoleBindings, crb)
+				break
+			}
+		}
+	}
+	return clusterRoleBindings, nil
+}
+
+// newSubjectsTestHandler returns a handler whose RoleBinding and ClusterRoleBinding caches are indexed like the real caches
+func newSubjectsTestHandler(opts common.Options, registrationNamespaces, systemNamespaces, namespaces []string, roleBindings []*rbacv1.RoleBinding, clusterRoleBindings []*rbacv1.ClusterRoleBinding) *handler {
+	namespaceCache := &fakeNamespaceCache{}
+	for _, namespace := range append(append(append([]string{}, registrationNamespaces...), systemNamespaces...), namespaces...) {
+		namespaceCache.namespaces = append(namespaceCache.namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+	}
+	h := &handler{
+		systemNamespace: "cattle-helm-system",
+		opts:            opts,
+		namespaceCache:  namespaceCache,
+		projectGetter: &fakeProjectGetter{
+			registrationNamespaces: registrationNamespaces,
+			systemNamespaces:       systemNamespaces,
+		},
+	}
+	h.rolebindingCache = &fakeRoleBindingCache{
+		roleBindings: roleBindings,
+		indexers: map[string]func(rb *rbacv1.RoleBinding) ([]string, error){
+			RoleBindingInRegistrationNamespaceByRoleRef: h.roleBindingInRegistrationNamespaceToRoleRef,
+			RoleBindingInProjectNamespaceByRoleRef:      h.roleBindingInProjectNamespaceToRoleRef,
+		},
+	}
+	h.clusterrolebindingCache = &fakeClusterRoleBindingCache{
+		clusterRoleBindings: clusterRoleBindings,
+		indexer:             h.clusterRoleBindingToRoleRef,
+	}
+	return h
+}
+
+func newRoleBinding(namespace, name, clusterRole string, subjects ...rbacv1.Subject) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     clusterRole,
+		},
+		Subjects: subjects,
+	}
+}
+
+func newUserSubject(name string) rbacv1.Subject {
+	return rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: name}
+}
+
+func newServiceAccountSubject(namespace, name string) rbacv1.Subject {
+	return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}
+}
+
+// getSortedSubjectKeys returns the keys of the subjects of each subject role in a stable order
+func getSortedSubjectKeys(subjectRoleToSubjects map[string][]rbacv1.Subject) map[string][]string {
+	subjectKeys := make(map[string][]string, len(subjectRoleToSubjects))
+	for subjectRole, subjects := range subjectRoleToSubjects {
+		keys := []string{}
+		for _, subject := range subjects {
+			keys = append(keys, getSubjectKey(subject))
+		}
+		sort.Strings(keys)
+		subjectKeys[subjectRole] = keys
+	}
+	return subjectKeys
+}
+
+func TestGetSubjectRoleToSubjectsFromBindingsWithTiers(t *testing.T) {
+	registrationNamespace := "cattle-project-p-example"
+	opts := common.Options{
+		RuntimeOptions: common.RuntimeOptions{
+			DeploymentBackend: "helm-sdk",
+			AdminClusterRole:  "admin",
+			EditClusterRole:   "edit",
+			ViewClusterRole:   "view",
+			ClusterRoleTiers: map[string]string{
+				"monitoring-admin": "monitoring-admin",
+				// a tier can share the ClusterRole of another tier
+				"auditor": "view",
+			},
+		},
+	}
+	group := rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "sre"}
+	h := newSubjectsTestHandler(opts,
+		[]string{registrationNamespace, "cattle-project-p-other"}, nil, nil,
+		[]*rbacv1.RoleBinding{
+			newRoleBinding(registrationNamespace, "alice-admin", "admin", newUserSubject("alice")),
+			newRoleBinding(registrationNamespace, "sre-monitoring-admin", "monitoring-admin", group),
+			newRoleBinding(registrationNamespace, "bob-view", "view", newUserSubject("bob"), newServiceAccountSubject(registrationNamespace, "ci")),
+			newRoleBinding(registrationNamespace, "eve-other", "cluster-admin", newUserSubject("eve")),
+			newRoleBinding("cattle-project-p-other", "mallory-admin", "admin", newUserSubject("mallory")),
+		},
+		[]*rbacv1.ClusterRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "carol-edit"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+				Subjects:   []rbacv1.Subject{newUserSubject("carol"), newUserSubject("alice")},
+			},
+		},
+	)
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: registrationNamespace,
+		},
+	}
+	subjectRoleToSubjects, sources, err := h.getSubjectRoleToSubjectsFromBindings(projectHelmChart, []string{"p-example-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"admin":            {getSubjectKey(newUserSubject("alice"))},
+		"edit":             {getSubjectKey(newUserSubject("alice")), getSubjectKey(newUserSubject("carol"))},
+		"view":             {getSubjectKey(newUserSubject("bob"))},
+		"auditor":          {getSubjectKey(newUserSubject("bob"))},
+		"monitoring-admin": {getSubjectKey(group)},
+	}
+	if subjectKeys := getSortedSubjectKeys(subjectRoleToSubjects); !reflect.DeepEqual(subjectKeys, expected) {
+		t.Errorf("expected subjects %v, found %v", expected, subjectKeys)
+	}
+	expectedSources := []string{fmt.Sprintf("RoleBinding %s/bob-view", registrationNamespace)}
+	for _, subjectRole := range []string{"view", "auditor"} {
+		if subjectSources := sources[subjectRole][getSubjectKey(newUserSubject("bob"))]; !reflect.DeepEqual(subjectSources, expectedSources) {
+			t.Errorf("expected sources of bob for tier %s to be %v, found %v", subjectRole, expectedSources, subjectSources)
+		}
+	}
+	if subjectSources := sources["edit"][getSubjectKey(newUserSubject("alice"))]; !reflect.DeepEqual(subjectSources, []string{"ClusterRoleBinding carol-edit"}) {
+		t.Errorf("expected sources of alice for tier edit to be the ClusterRoleBinding, found %v", subjectSources)
+	}
+}
+
+func TestGetSyncedSecretsAndConfigMaps(t *testing.T) {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+		Spec: v1alpha1.ProjectHelmChartSpec{
+			ReleaseNamespace: "p-example-release",
+		},
+	}
+	h := &handler{
+		opts: common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				DeploymentBackend: "helm-sdk",
+			},
+		},
+	}
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	syncLabels := map[string]string{
+		common.HelmProjectOperatorProjectHelmChartSyncLabel: releaseName,
+		"meta.helm.sh/release-name":                         "other",
+	}
+	newSecret := func(namespace, name string, secretType corev1.SecretType, annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      syncLabels,
+				Annotations: annotations,
+			},
+			Type: secretType,
+		}
+	}
+	newConfigMap := func(namespace, name string, annotations map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      syncLabels,
+				Annotations: annotations,
+			},
+		}
+	}
+	h.secretCache = &fakeSecretCache{
+		secrets: []*corev1.Secret{
+			newSecret(projectHelmChart.Namespace, "tls", corev1.SecretTypeTLS, nil),
+			newSecret(projectHelmChart.Namespace, "release", "helm.sh/release.v1", nil),
+			newSecret(projectHelmChart.Namespace, "token", corev1.SecretTypeServiceAccountToken, nil),
+			newSecret(projectHelmChart.Namespace, "taken", corev1.SecretTypeOpaque, nil),
+			// an object deployed by the release that has the name that the copy would have
+			newSecret("p-example-release", getSyncedName(releaseName, "taken"), corev1.SecretTypeOpaque, nil),
+		},
+	}
+	h.configmapCache = &fakeConfigMapCache{
+		configMaps: []*corev1.ConfigMap{
+			newConfigMap(projectHelmChart.Namespace, "ca", nil),
+			// a copy that was previously synced
+			newConfigMap("p-example-release", getSyncedName(releaseName, "ca"), map[string]string{
+				common.HelmProjectOperatorSyncedFromAnnotation: projectHelmChart.Namespace + "/ca",
+			}),
+		},
+	}
+
+	objs, err := h.getSyncedSecretsAndConfigMaps("p-example", projectHelmChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected only the tls Secret and the ca ConfigMap to be synced, found %d objects", len(objs))
+	}
+	secret, ok := objs[0].(*corev1.Secret)
+	if !ok {
+		t.Fatalf("expected first object to be a Secret, found %T", objs[0])
+	}
+	if secret.Namespace != "p-example-release" || secret.Name != "synced-"+releaseName+"-tls" {
+		t.Errorf("unexpected synced Secret %s/%s", secret.Namespace, secret.Name)
+	}
+	if _, ok := secret.Labels["meta.helm.sh/release-name"]; ok {
+		t.Errorf("expected Helm labels to be stripped from synced Secret, found %v", secret.Labels)
+	}
+	if _, ok := secret.Labels[common.HelmProjectOperatorProjectHelmChartSyncLabel]; ok {
+		t.Errorf("expected sync label to be stripped from synced Secret, found %v", secret.Labels)
+	}
+	configMap, ok := objs[1].(*corev1.ConfigMap)
+	if !ok {
+		t.Fatalf("expected second object to be a ConfigMap, found %T", objs[1])
+	}
+	if configMap.Name != "synced-"+releaseName+"-ca" || configMap.Annotations[common.HelmProjectOperatorSyncedFromAnnotation] != projectHelmChart.Namespace+"/ca" {
+		t.Errorf("unexpected synced ConfigMap %s/%s with annotations %v", configMap.Namespace, configMap.Name, configMap.Annotations)
+	}
+}
+
+func TestGetSyncedName(t *testing.T) {
+	if name := getSyncedName("project-monitoring", "tls"); name != "synced-project-monitoring-tls" {
+		t.Errorf("unexpected synced name %s", name)
+	}
+	longName := getSyncedName("project-monitoring", "a-very-long-name-for-a-secret-that-exceeds-the-limit-of-a-label")
+	if len(longName) > 63 {
+		t.Errorf("expected synced name to be truncated to 63 characters, found %s", longName)
+	}
+}
+
+func TestGetSubjectRoleToSubjectsFromBindingsWithServiceAccounts(t *testing.T) {
+	registrationNamespace := "cattle-project-p-example"
+	opts := common.Options{
+		RuntimeOptions: common.RuntimeOptions{
+			DeploymentBackend:          "helm-sdk",
+			ProjectLabel:               "field.cattle.io/projectId",
+			ProjectReleaseLabelValue:   "p-system",
+			AdminClusterRole:           "admin",
+			EditClusterRole:            "edit",
+			ServiceAccountSubjectTiers: []string{"edit"},
+		},
+	}
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: registrationNamespace,
+		},
+	}
+	releaseNamespace, _ := (&handler{opts: opts}).getReleaseNamespaceAndName(projectHelmChart)
+	h := newSubjectsTestHandler(opts,
+		[]string{registrationNamespace}, []string{"cattle-helm-system"}, []string{"p-example-app", "p-other-app", releaseNamespace},
+		[]*rbacv1.RoleBinding{
+			newRoleBinding(registrationNamespace, "ci-edit", "edit",
+				newUserSubject("alice"),
+				newServiceAccountSubject(registrationNamespace, "ci"),
+				newServiceAccountSubject("", "default-ns"),
+				newServiceAccountSubject("p-example-app", "deployer"),
+				newServiceAccountSubject(releaseNamespace, "chart"),
+				newServiceAccountSubject("p-other-app", "other"),
+				newServiceAccountSubject("cattle-helm-system", "operator"),
+			),
+			newRoleBinding(registrationNamespace, "ci-admin", "admin", newServiceAccountSubject(registrationNamespace, "ci")),
+		},
+		[]*rbacv1.ClusterRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "crb-edit"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+				Subjects: []rbacv1.Subject{
+					newServiceAccountSubject("p-example-app", "monitor"),
+					newServiceAccountSubject("p-other-app", "monitor"),
+					newServiceAccountSubject("", "unnamespaced"),
+				},
+			},
+		},
+	)
+	// note: the Project Release Namespace is always added to the target namespaces
+	subjectRoleToSubjects, _, err := h.getSubjectRoleToSubjectsFromBindings(projectHelmChart, []string{"p-example-app", releaseNamespace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"admin": {},
+		"edit": {
+			getSubjectKey(newServiceAccountSubject(registrationNamespace, "ci")),
+			getSubjectKey(newServiceAccountSubject(registrationNamespace, "default-ns")),
+			getSubjectKey(newServiceAccountSubject("p-example-app", "deployer")),
+			getSubjectKey(newServiceAccountSubject("p-example-app", "monitor")),
+			getSubjectKey(newUserSubject("alice")),
+		},
+	}
+	sort.Strings(expected["edit"])
+	if subjectKeys := getSortedSubjectKeys(subjectRoleToSubjects); !reflect.DeepEqual(subjectKeys, expected) {
+		t.Errorf("expected subjects %v, found %v", expected, subjectKeys)
+	}
+}
+
+func TestGetServiceAccountSubjectNamespaces(t *testing.T) {
+	registrationNamespace := "cattle-project-p-example"
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: registrationNamespace,
+		},
+	}
+	testCases := []struct {
+		name                       string
+		releaseNamespace           string
+		serviceAccountSubjectTiers []string
+		expected                   map[string]bool
+	}{
+		{
+			name:     "ServiceAccounts are not bound",
+			expected: map[string]bool{},
+		},
+		{
+			name:                       "Project Release Namespace is excluded",
+			serviceAccountSubjectTiers: []string{"*"},
+			expected: map[string]bool{
+				registrationNamespace: true,
+				"p-example-app":       true,
+			},
+		},
+		{
+			name:                       "release namespace provided by the user is part of the project",
+			releaseNamespace:           "p-example-app",
+			serviceAccountSubjectTiers: []string{"*"},
+			expected: map[string]bool{
+				registrationNamespace: true,
+				"p-example-app":       true,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				systemNamespace: "cattle-helm-system",
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						DeploymentBackend:          "helm-sdk",
+						ProjectLabel:               "field.cattle.io/projectId",
+						ProjectReleaseLabelValue:   "p-system",
+						ServiceAccountSubjectTiers: tc.serviceAccountSubjectTiers,
+					},
+				},
+				namespaceCache: &fakeNamespaceCache{},
+			}
+			projectHelmChart := projectHelmChart.DeepCopy()
+			projectHelmChart.Spec.ReleaseNamespace = tc.releaseNamespace
+			releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+			namespaces := h.getServiceAccountSubjectNamespaces(projectHelmChart, []string{"p-example-app", releaseNamespace, h.systemNamespace})
+			if !reflect.DeepEqual(namespaces, tc.expected) {
+				t.Errorf("expected ServiceAccounts from %v to be bound, found %v", tc.expected, namespaces)
+			}
+		})
+	}
+}
+
+func TestGetSubjectRoleToSubjectsFromProjectNamespaceBindings(t *testing.T) {
+	registrationNamespace := "cattle-project-p-example"
+	systemNamespace := "cattle-helm-system"
+	newOpts := func(aggregateProjectNamespaceRoleBindings bool) common.Options {
+		return common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				DeploymentBackend:                     "helm-sdk",
+				ProjectLabel:                          "field.cattle.io/projectId",
+				ProjectReleaseLabelValue:              "p-system",
+				EditClusterRole:                       "edit",
+				AggregateProjectNamespaceRoleBindings: aggregateProjectNamespaceRoleBindings,
+			},
+		}
+	}
+	newProjectHelmChart := func(releaseNamespace string) *v1alpha1.ProjectHelmChart {
+		return &v1alpha1.ProjectHelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "project-monitoring",
+				Namespace: registrationNamespace,
+			},
+			Spec: v1alpha1.ProjectHelmChartSpec{
+				ReleaseNamespace: releaseNamespace,
+			},
+		}
+	}
+	projectReleaseNamespace, _ := (&handler{opts: newOpts(true)}).getReleaseNamespaceAndName(newProjectHelmChart(""))
+	roleBindingToRole := newRoleBinding("p-example-app", "role-edit", "edit", newUserSubject("mallory"))
+	roleBindingToRole.RoleRef.Kind = "Role"
+	roleBindings := []*rbacv1.RoleBinding{
+		newRoleBinding(registrationNamespace, "alice-edit", "edit", newUserSubject("alice")),
+		newRoleBinding("p-example-app", "dave-edit", "edit", newUserSubject("dave")),
+		newRoleBinding("p-example-data", "erin-edit", "edit", newUserSubject("erin")),
+		roleBindingToRole,
+		newRoleBinding("p-example-app", "frank-admin", "admin", newUserSubject("frank")),
+		newRoleBinding(projectReleaseNamespace, "generated-edit", "edit", newUserSubject("alice"), newUserSubject("generated")),
+		newRoleBinding("p-other-app", "oscar-edit", "edit", newUserSubject("oscar")),
+		newRoleBinding(systemNamespace, "sys-edit", "edit", newUserSubject("sys")),
+	}
+
+	testCases := []struct {
+		name                                  string
+		aggregateProjectNamespaceRoleBindings bool
+		releaseNamespace                      string
+		targetNamespaces                      []string
+		expected                              []string
+	}{
+		{
+			name:             "project namespaces are not aggregated",
+			targetNamespaces: []string{"p-example-app", "p-example-data", projectReleaseNamespace},
+			expected:         []string{"alice"},
+		},
+		{
+			name:                                  "project namespaces are aggregated",
+			aggregateProjectNamespaceRoleBindings: true,
+			targetNamespaces:                      []string{"p-example-app", "p-example-data", projectReleaseNamespace},
+			expected:                              []string{"alice", "dave", "erin"},
+		},
+		{
+			name:                                  "release namespace provided by the user is aggregated",
+			aggregateProjectNamespaceRoleBindings: true,
+			releaseNamespace:                      "p-example-data",
+			targetNamespaces:                      []string{"p-example-app", "p-example-data"},
+			expected:                              []string{"alice", "dave", "erin"},
+		},
+		{
+			name:                                  "only target namespaces are aggregated",
+			aggregateProjectNamespaceRoleBindings: true,
+			targetNamespaces:                      []string{"p-example-data", projectReleaseNamespace},
+			expected:                              []string{"alice", "erin"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newSubjectsTestHandler(newOpts(tc.aggregateProjectNamespaceRoleBindings),
+				[]string{registrationNamespace}, []string{systemNamespace},
+				[]string{"p-example-app", "p-example-data", "p-other-app", projectReleaseNamespace},
+				roleBindings, nil,
+			)
+			subjectRoleToSubjects, _, err := h.getSubjectRoleToSubjectsFromBindings(newProjectHelmChart(tc.releaseNamespace), tc.targetNamespaces)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expected []string
+			for _, name := range tc.expected {
+				expected = append(expected, getSubjectKey(newUserSubject(name)))
+			}
+			if subjectKeys := getSortedSubjectKeys(subjectRoleToSubjects)["edit"]; !reflect.DeepEqual(subjectKeys, expected) {
+				t.Errorf("expected subjects %v, found %v", expected, subjectKeys)
+			}
+		})
+	}
+}
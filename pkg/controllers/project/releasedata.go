@@ -101,6 +101,55 @@ func (h *handler) getSubjectRoleToRoleRefsFromRoles(projectHelmChart *v1alpha1.P
 	return subjectRoleToRoleRefs, nil
 }
 
+// getFanOutRoles gets all Roles in the Project Release Namespace and all ClusterRoles that are marked to be fanned out into every target project namespace
+// of this ProjectHelmChart. See pkg/controllers/project/resources.go for more information on how this is used
+func (h *handler) getFanOutRoles(projectHelmChart *v1alpha1.ProjectHelmChart) ([]*rbacv1.Role, []*rbacv1.ClusterRole, error) {
+	defaultClusterRoles := common.GetDefaultClusterRoles(h.opts)
+	if len(defaultClusterRoles) == 0 {
+		// no roles were defined to be auto-aggregated
+		return nil, nil, nil
+	}
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	exists, err := h.verifyReleaseNamespaceExists(releaseNamespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, nil
+	}
+	isFanOutRole := func(labels map[string]string) bool {
+		if !common.HasFanOutLabel(labels) {
+			return false
+		}
+		// label value must point to default subject role name
+		_, ok := defaultClusterRoles[labels[common.HelmProjectOperatorProjectHelmChartRoleAggregateFromLabel]]
+		return ok
+	}
+	var fanOutRoles []*rbacv1.Role
+	roles, err := h.roleCache.GetByIndex(RoleInReleaseNamespaceByReleaseNamespaceName, fmt.Sprintf("%s/%s", releaseNamespace, releaseName))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, role := range roles {
+		if role == nil || !isFanOutRole(role.Labels) {
+			continue
+		}
+		fanOutRoles = append(fanOutRoles, role)
+	}
+	var fanOutClusterRoles []*rbacv1.ClusterRole
+	clusterRoles, err := h.clusterroleCache.GetByIndex(ClusterRoleByReleaseNamespaceName, fmt.Sprintf("%s/%s", releaseNamespace, releaseName))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, clusterRole := range clusterRoles {
+		if clusterRole == nil || !isFanOutRole(clusterRole.Labels) {
+			continue
+		}
+		fanOutClusterRoles = append(fanOutClusterRoles, clusterRole)
+	}
+	return fanOutRoles, fanOutClusterRoles, nil
+}
+
 func (h *handler) verifyReleaseNamespaceExists(releaseNamespace string) (bool, error) {
 	_, err := h.namespaceCache.Get(releaseNamespace)
 	if err != nil {
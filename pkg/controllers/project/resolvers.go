@@ -2,8 +2,11 @@ package project
 
 import (
 	"context"
+	"slices"
 
 	helmcontrollerv1 "github.com/k3s-io/helm-controller/pkg/apis/helm.cattle.io/v1"
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 	helmlockerv1alpha1 "github.com/rancher/helm-project-operator/pkg/helm-locker/apis/helm.cattle.io/v1alpha1"
 	"github.com/rancher/wrangler/pkg/apply"
@@ -11,6 +14,9 @@ import (
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -40,8 +46,136 @@ func (h *handler) initResolvers(ctx context.Context) {
 
 	relatedresource.Watch(
 		ctx, "watch-project-release-chart-data", h.resolveProjectReleaseNamespaceData, h.projectHelmCharts,
-		h.rolebindings, h.configmaps, h.roles,
+		h.rolebindings, h.configmaps, h.roles, h.clusterroles,
 	)
+
+	relatedresource.Watch(
+		ctx, "watch-synced-secrets-and-configmaps", h.resolveSyncedSecretsAndConfigMaps, h.projectHelmCharts,
+		h.secrets, h.configmaps,
+	)
+
+	relatedresource.Watch(
+		ctx, "watch-release-namespace-resources", h.resolveReleaseNamespaceResources, h.projectHelmCharts,
+		h.resourceQuotas, h.limitRanges,
+	)
+
+	if h.opts.ValuesHistoryLimit > 0 {
+		// Only trigger watching values revisions if they are recorded by the operator
+		relatedresource.Watch(
+			ctx, "watch-values-revisions", h.resolveValuesRevision, h.projectHelmCharts,
+			h.secrets,
+		)
+	}
+
+	if h.opts.ScopeReleasePermissions {
+		// Only trigger watching release permissions if they are created by the operator
+		relatedresource.Watch(
+			ctx, "watch-release-permissions", h.resolveReleasePermissions, h.projectHelmCharts,
+			h.serviceaccounts, h.roles, h.rolebindings, h.clusterroles, h.clusterrolebindings,
+		)
+	}
+
+	if statusReporter, ok := h.backend.(backend.StatusReporter); ok {
+		// Only trigger watching release statuses if the deployment backend performs Helm operations itself
+		statusReporter.OnStatusChange(h.resolveReleaseStatusChange)
+	}
+}
+
+// Deployment Backend
+
+func (h *handler) resolveReleaseStatusChange(release backend.Release) {
+	projectHelmCharts, err := h.projectHelmChartCache.GetByIndex(ProjectHelmChartByReleaseName, release.Name)
+	if err != nil {
+		logrus.Errorf("unable to get ProjectHelmCharts tracking release %s/%s: %s", release.Namespace, release.Name, err)
+		return
+	}
+	for _, projectHelmChart := range projectHelmCharts {
+		if projectHelmChart == nil {
+			continue
+		}
+		releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+		if releaseNamespace != release.Namespace || releaseName != release.Name {
+			continue
+		}
+		h.projectHelmCharts.Enqueue(projectHelmChart.Namespace, projectHelmChart.Name)
+	}
+}
+
+// Synced Secrets and ConfigMaps
+
+func (h *handler) resolveSyncedSecretsAndConfigMaps(_, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil
+	}
+	if _, ok := objMeta.GetAnnotations()[common.HelmProjectOperatorSyncedFromAnnotation]; ok {
+		// since the copy in the release namespace will be created and owned by the ProjectHelmChart,
+		// we can simply leverage is annotations to identify what we should resolve to.
+		return h.resolveProjectHelmChartOwned(objMeta.GetAnnotations())
+	}
+	keys, err := h.resolveByProjectReleaseLabelValue(objMeta.GetLabels(), common.HelmProjectOperatorProjectHelmChartSyncLabel)
+	if err != nil {
+		return nil, err
+	}
+	// only sync objects from the Project Registration Namespace of the ProjectHelmChart
+	var registrationNamespaceKeys []relatedresource.Key
+	for _, key := range keys {
+		if key.Namespace == objMeta.GetNamespace() {
+			registrationNamespaceKeys = append(registrationNamespaceKeys, key)
+		}
+	}
+	return registrationNamespaceKeys, nil
+}
+
+// Release Namespace Resources
+
+func (h *handler) resolveReleaseNamespaceResources(_, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	// since the ResourceQuota and LimitRange will be created and owned by the ProjectHelmChart,
+	// we can simply leverage is annotations to identify what we should resolve to.
+	if resourceQuota, ok := obj.(*corev1.ResourceQuota); ok {
+		return h.resolveProjectHelmChartOwned(resourceQuota.Annotations)
+	}
+	if limitRange, ok := obj.(*corev1.LimitRange); ok {
+		return h.resolveProjectHelmChartOwned(limitRange.Annotations)
+	}
+	return nil, nil
+}
+
+// Values Revisions
+
+func (h *handler) resolveValuesRevision(namespace, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if namespace != h.systemNamespace {
+		return nil, nil
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return nil, nil
+	}
+	return h.resolveByProjectReleaseLabelValue(secret.Labels, common.HelmProjectOperatorValuesRevisionLabel)
+}
+
+// Release Permissions
+
+func (h *handler) resolveReleasePermissions(_, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	// since the release permissions will be created and owned by the ProjectHelmChart,
+	// we can simply leverage is annotations to identify what we should resolve to.
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil
+	}
+	return h.resolveProjectHelmChartOwned(objMeta.GetAnnotations())
 }
 
 // Project Release Namespace
@@ -89,6 +223,12 @@ func (h *handler) resolveProjectRegistrationNamespaceData(namespace, name string
 		return nil, nil
 	}
 	if rb, ok := obj.(*rbacv1.RoleBinding); ok {
+		if h.opts.AggregateProjectNamespaceRoleBindings {
+			keys, err := h.resolveProjectNamespaceRoleBinding(namespace, name, rb)
+			if err != nil || len(keys) > 0 {
+				return keys, err
+			}
+		}
 		logrus.Debugf("Resolving project registration namespace rolebindings for %s", namespace)
 		return h.resolveProjectRegistrationNamespaceRoleBinding(namespace, name, rb)
 	}
@@ -134,42 +274,103 @@ func (h *handler) resolveProjectRegistrationNamespaceRoleBinding(namespace, _ st
 	return keys, nil
 }
 
-func (h *handler) resolveClusterRoleBinding(_, _ string, crb *rbacv1.ClusterRoleBinding) ([]relatedresource.Key, error) {
+func (h *handler) resolveProjectNamespaceRoleBinding(namespace, _ string, rb *rbacv1.RoleBinding) ([]relatedresource.Key, error) {
+	if rb.RoleRef.Kind != "ClusterRole" {
+		return nil, nil
+	}
 	// we want to re-enqueue the ProjectHelmChart if the rolebinding's ref points to one of the operator default roles
-	_, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, crb.RoleRef.Name)
+	_, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, rb.RoleRef.Name)
 	if !isDefaultRoleRef {
 		return nil, nil
 	}
-	// re-enqueue all HelmCharts in all Project Registration namespaces
-	namespaces, err := h.namespaceCache.List(labels.Everything())
+	namespaceObj, err := h.namespaceCache.Get(namespace)
+	if err != nil {
+		// the namespace was probably deleted, in which case the ProjectHelmCharts will be re-enqueued on the namespace change
+		return nil, nil
+	}
+	if h.projectGetter.IsProjectRegistrationNamespace(namespaceObj) || h.projectGetter.IsSystemNamespace(namespaceObj) {
+		return nil, nil
+	}
+	// re-enqueue all HelmCharts aggregating bindings to this ClusterRole that target this namespace
+	projectHelmCharts, err := h.projectHelmChartCache.GetByIndex(ProjectHelmChartByRBACAggregation, rb.RoleRef.Name)
 	if err != nil {
+		logrus.Debugf("Error in resolveProjectNamespaceRoleBinding while re-enqueuing HelmCharts targeting %s", namespace)
 		return nil, err
 	}
 	var keys []relatedresource.Key
-	for _, namespace := range namespaces {
-		if namespace == nil {
-			continue
-		}
-		isProjectRegistrationNamespace := h.projectGetter.IsProjectRegistrationNamespace(namespace)
-		if !isProjectRegistrationNamespace {
+	for _, projectHelmChart := range projectHelmCharts {
+		if !h.shouldManage(projectHelmChart) {
 			continue
 		}
-		projectHelmCharts, err := h.projectHelmChartCache.List(namespace.Name, labels.Everything())
-		if err != nil {
-			logrus.Debugf("Error in resolveClusterRoleBinding while re-enqueuing HelmCharts in %s", namespace)
-			return nil, err
-		}
-		for _, projectHelmChart := range projectHelmCharts {
-			if projectHelmChart == nil {
+		if h.opts.ProjectCRD {
+			// the target namespaces are determined by the Project tied to the project registration namespace
+			targetProjectNamespaces, err := h.projectGetter.GetTargetProjectNamespaces(projectHelmChart)
+			if err != nil || !slices.Contains(targetProjectNamespaces, namespace) {
+				continue
+			}
+		} else if len(h.opts.ProjectLabel) > 0 {
+			registrationNamespace, err := h.namespaceCache.Get(projectHelmChart.Namespace)
+			if err != nil {
+				continue
+			}
+			// the project registration namespace may belong to any level of the project hierarchy
+			projectLabel, projectID, ok := h.opts.GetProjectLabelAndID(registrationNamespace.Labels)
+			if !ok || namespaceObj.Labels[projectLabel] != projectID {
+				continue
+			}
+		} else {
+			// without a project label, the target namespaces are determined by spec.projectNamespaceSelector
+			selector, err := metav1.LabelSelectorAsSelector(projectHelmChart.Spec.ProjectNamespaceSelector)
+			if err != nil || !selector.Matches(labels.Set(namespaceObj.Labels)) {
 				continue
 			}
-			keys = append(keys, relatedresource.Key{
-				Namespace: projectHelmChart.Namespace,
-				Name:      projectHelmChart.Name,
-			})
 		}
+		keys = append(keys, relatedresource.Key{
+			Namespace: projectHelmChart.Namespace,
+			Name:      projectHelmChart.Name,
+		})
 	}
-	return keys, nil
+	return h.coalesceRBACResync(keys), nil
+}
+
+func (h *handler) resolveClusterRoleBinding(_, _ string, crb *rbacv1.ClusterRoleBinding) ([]relatedresource.Key, error) {
+	// we want to re-enqueue the ProjectHelmChart if the rolebinding's ref points to one of the operator default roles
+	_, isDefaultRoleRef := common.IsDefaultClusterRoleRef(h.opts, crb.RoleRef.Name)
+	if !isDefaultRoleRef {
+		return nil, nil
+	}
+	// re-enqueue all HelmCharts aggregating bindings to this ClusterRole
+	projectHelmCharts, err := h.projectHelmChartCache.GetByIndex(ProjectHelmChartByRBACAggregation, crb.RoleRef.Name)
+	if err != nil {
+		logrus.Debugf("Error in resolveClusterRoleBinding while re-enqueuing HelmCharts participating in RBAC aggregation")
+		return nil, err
+	}
+	var keys []relatedresource.Key
+	for _, projectHelmChart := range projectHelmCharts {
+		if !h.shouldManage(projectHelmChart) {
+			continue
+		}
+		keys = append(keys, relatedresource.Key{
+			Namespace: projectHelmChart.Namespace,
+			Name:      projectHelmChart.Name,
+		})
+	}
+	return h.coalesceRBACResync(keys), nil
+}
+
+// coalesceRBACResync enqueues the provided ProjectHelmCharts after the ClusterRoleBinding resync delay and returns no keys, unless the
+// delay is 0, in which case the keys are returned to be enqueued immediately
+//
+// Note: a single change to a ClusterRoleBinding or ClusterRole can affect many ProjectHelmCharts, so changes are coalesced by delaying the
+// enqueue; the workqueue only keeps the earliest enqueue for a key, so each ProjectHelmChart is only enqueued once per window
+func (h *handler) coalesceRBACResync(keys []relatedresource.Key) []relatedresource.Key {
+	if h.clusterRoleBindingResyncDelay == 0 {
+		return keys
+	}
+	for _, key := range keys {
+		h.projectHelmCharts.EnqueueAfter(key.Namespace, key.Name, h.clusterRoleBindingResyncDelay)
+	}
+	return nil
 }
 
 // Project Release Namespace Data
@@ -178,10 +379,21 @@ func (h *handler) resolveProjectReleaseNamespaceData(_, _ string, obj runtime.Ob
 	if obj == nil {
 		return nil, nil
 	}
-	if rb, ok := obj.(*rbacv1.RoleBinding); ok {
-		// since the rolebinding will be created and owned by the ProjectHelmChart,
-		// we can simply leverage is annotations to identify what we should resolve to.
-		return h.resolveProjectHelmChartOwned(rb.Annotations)
+	var isOwnedByProjectHelmChart bool
+	switch o := obj.(type) {
+	case *rbacv1.RoleBinding:
+		isOwnedByProjectHelmChart = true
+	case *corev1.ConfigMap:
+		_, isOwnedByProjectHelmChart = o.Labels[common.HelmProjectOperatorRBACReportLabel]
+	case *rbacv1.Role:
+		_, isReleaseRole := o.Labels[common.HelmProjectOperatorProjectHelmChartRoleLabel]
+		isOwnedByProjectHelmChart = !isReleaseRole
+	}
+	if isOwnedByProjectHelmChart {
+		// since rolebindings, the RBAC report in the registration namespace, default roles in the release namespace,
+		// and fan-out roles in the target project namespaces will be created and owned by the ProjectHelmChart,
+		// we can simply leverage its annotations to identify what we should resolve to.
+		return h.resolveProjectHelmChartOwnedReleaseData(obj)
 	}
 	if configmap, ok := obj.(*corev1.ConfigMap); ok {
 		return h.resolveByProjectReleaseLabelValue(configmap.Labels, common.HelmProjectOperatorDashboardValuesConfigMapLabel)
@@ -189,9 +401,56 @@ func (h *handler) resolveProjectReleaseNamespaceData(_, _ string, obj runtime.Ob
 	if role, ok := obj.(*rbacv1.Role); ok {
 		return h.resolveByProjectReleaseLabelValue(role.Labels, common.HelmProjectOperatorProjectHelmChartRoleLabel)
 	}
+	if clusterRole, ok := obj.(*rbacv1.ClusterRole); ok {
+		if !common.HasFanOutLabel(clusterRole.Labels) {
+			return nil, nil
+		}
+		keys, err := h.resolveByProjectReleaseLabelValue(clusterRole.Labels, common.HelmProjectOperatorProjectHelmChartRoleLabel)
+		if err != nil {
+			return nil, err
+		}
+		return h.coalesceRBACResync(keys), nil
+	}
 	return nil, nil
 }
 
+// resolveProjectHelmChartOwnedReleaseData resolves an object to the ProjectHelmChart identified by its owner annotations, provided that
+// the owner is a ProjectHelmChart and the object lives in a namespace that the ProjectHelmChart creates RBAC in (its registration namespace,
+// its release namespace, or one of its target project namespaces); this ensures that objects outside of those namespaces that happen to be
+// applied with the same owner annotations by another set cannot trigger a ProjectHelmChart to be re-enqueued
+func (h *handler) resolveProjectHelmChartOwnedReleaseData(obj runtime.Object) ([]relatedresource.Key, error) {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	annotations := objMeta.GetAnnotations()
+	if annotations[apply.LabelGVK] != v1alpha1.SchemeGroupVersion.WithKind("ProjectHelmChart").String() {
+		return nil, nil
+	}
+	keys, err := h.resolveProjectHelmChartOwned(annotations)
+	if err != nil {
+		return nil, err
+	}
+	var ownerKeys []relatedresource.Key
+	for _, key := range keys {
+		projectHelmChart, err := h.projectHelmChartCache.Get(key.Namespace, key.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// the ProjectHelmChart was deleted; the generated handler will remove the objects it owns
+				continue
+			}
+			return nil, err
+		}
+		releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+		namespace := objMeta.GetNamespace()
+		if namespace != projectHelmChart.Namespace && namespace != releaseNamespace && !slices.Contains(projectHelmChart.Status.TargetNamespaces, namespace) {
+			continue
+		}
+		ownerKeys = append(ownerKeys, key)
+	}
+	return ownerKeys, nil
+}
+
 // Common
 
 func (h *handler) resolveProjectHelmChartOwned(annotations map[string]string) ([]relatedresource.Key, error) {
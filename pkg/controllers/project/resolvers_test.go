@@ -0,0 +1,161 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveProjectHelmChartOwnedReleaseData(t *testing.T) {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+		Spec: v1alpha1.ProjectHelmChartSpec{
+			ReleaseNamespace: "p-example-release",
+		},
+		Status: v1alpha1.ProjectHelmChartStatus{
+			TargetNamespaces: []string{"p-example-target"},
+		},
+	}
+	projectHelmChartGVK := v1alpha1.SchemeGroupVersion.WithKind("ProjectHelmChart").String()
+	newRole := func(namespace, ownerGVK string) *rbacv1.Role {
+		return &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "project-monitoring-admin",
+				Namespace: namespace,
+				Annotations: map[string]string{
+					apply.LabelGVK:       ownerGVK,
+					apply.LabelNamespace: projectHelmChart.Namespace,
+					apply.LabelName:      projectHelmChart.Name,
+				},
+			},
+		}
+	}
+	expectedKeys := []relatedresource.Key{{Namespace: projectHelmChart.Namespace, Name: projectHelmChart.Name}}
+
+	testCases := []struct {
+		name     string
+		role     *rbacv1.Role
+		expected []relatedresource.Key
+	}{
+		{
+			name:     "role in release namespace",
+			role:     newRole("p-example-release", projectHelmChartGVK),
+			expected: expectedKeys,
+		},
+		{
+			name:     "role in target project namespace",
+			role:     newRole("p-example-target", projectHelmChartGVK),
+			expected: expectedKeys,
+		},
+		{
+			name:     "role in registration namespace",
+			role:     newRole(projectHelmChart.Namespace, projectHelmChartGVK),
+			expected: expectedKeys,
+		},
+		{
+			name: "role in unrelated namespace",
+			role: newRole("p-other", projectHelmChartGVK),
+		},
+		{
+			name: "role owned by another kind",
+			role: newRole("p-example-release", "/v1, Kind=ConfigMap"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						DeploymentBackend: "helm-sdk",
+					},
+				},
+				projectHelmChartCache: &fakeProjectHelmChartCache{projectHelmCharts: []*v1alpha1.ProjectHelmChart{projectHelmChart}},
+			}
+			keys, err := h.resolveProjectReleaseNamespaceData("", "", tc.role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keys, tc.expected) {
+				t.Errorf("expected keys %v, found %v", tc.expected, keys)
+			}
+		})
+	}
+}
+
+func TestClusterRoleToReleaseNamespaceName(t *testing.T) {
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "project-monitoring-admin",
+			Labels: map[string]string{
+				common.HelmProjectOperatorProjectHelmChartRoleLabel:       "project-monitoring",
+				common.HelmProjectOperatorProjectHelmChartRoleFanOutLabel: "true",
+			},
+			Annotations: map[string]string{
+				common.HelmReleaseNamespaceAnnotation: "p-example-release",
+			},
+		},
+	}
+	h := &handler{}
+	indices, err := h.clusterRoleToReleaseNamespaceName(clusterRole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"p-example-release/project-monitoring"}; !reflect.DeepEqual(indices, expected) {
+		t.Errorf("expected indices %v, found %v", expected, indices)
+	}
+	delete(clusterRole.Annotations, common.HelmReleaseNamespaceAnnotation)
+	indices, err = h.clusterRoleToReleaseNamespaceName(clusterRole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indices) != 0 {
+		t.Errorf("expected ClusterRole without a release namespace to not be indexed, found %v", indices)
+	}
+}
+
+func TestProjectHelmChartToRBACAggregation(t *testing.T) {
+	h := &handler{
+		opts: common.Options{
+			OperatorOptions: common.OperatorOptions{
+				HelmAPIVersion: "dummy.cattle.io/v1alpha1",
+			},
+			RuntimeOptions: common.RuntimeOptions{
+				AdminClusterRole: "admin",
+				EditClusterRole:  "edit",
+				ViewClusterRole:  "view",
+				ClusterRoleTiers: map[string]string{
+					"monitoring-admin": "admin",
+				},
+			},
+		},
+	}
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		Spec: v1alpha1.ProjectHelmChartSpec{
+			HelmAPIVersion: "dummy.cattle.io/v1alpha1",
+		},
+	}
+	indices, err := h.projectHelmChartToRBACAggregation(projectHelmChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"admin", "edit", "view"}; !reflect.DeepEqual(indices, expected) {
+		t.Errorf("expected indices %v, found %v", expected, indices)
+	}
+	projectHelmChart.Spec.HelmAPIVersion = "other.cattle.io/v1alpha1"
+	indices, err = h.projectHelmChartToRBACAggregation(projectHelmChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indices) != 0 {
+		t.Errorf("expected ProjectHelmChart for another operator to not be indexed, found %v", indices)
+	}
+}
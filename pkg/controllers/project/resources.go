@@ -1,14 +1,19 @@
 package project
 
 import (
-	helmcontrollerv1 "github.com/k3s-io/helm-controller/pkg/apis/helm.cattle.io/v1"
-	"github.com/k3s-io/helm-controller/pkg/controllers/chart"
+	"encoding/json"
+	"fmt"
+	"sort"
+
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
-	helmlockerv1alpha1 "github.com/rancher/helm-project-operator/pkg/helm-locker/apis/helm.cattle.io/v1alpha1"
-	"github.com/rancher/helm-project-operator/pkg/helm-locker/controllers/release"
+	"github.com/rancher/helm-project-operator/pkg/naming"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -16,67 +21,52 @@ import (
 // Note: each resource created here should have a resolver set in resolvers.go
 // The only exception is ProjectHelmCharts since those are handled by the main generating controller
 
-// getHelmChart returns the HelmChart created on behalf of this ProjectHelmChart
-func (h *handler) getHelmChart(projectID string, valuesContent string, projectHelmChart *v1alpha1.ProjectHelmChart) *helmcontrollerv1.HelmChart {
-	// must be in system namespace since helm controllers are configured to only watch one namespace
-	jobImage := DefaultJobImage
-	if len(h.opts.HelmJobImage) > 0 {
-		jobImage = h.opts.HelmJobImage
-	}
-	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
-	helmChart := helmcontrollerv1.NewHelmChart(h.systemNamespace, releaseName, helmcontrollerv1.HelmChart{
-		Spec: helmcontrollerv1.HelmChartSpec{
-			TargetNamespace: releaseNamespace,
-			Chart:           releaseName,
-			JobImage:        jobImage,
-			ChartContent:    h.opts.ChartContent,
-			ValuesContent:   valuesContent,
-		},
-	})
-	helmChart.SetLabels(common.GetHelmResourceLabels(projectID, projectHelmChart.Spec.HelmAPIVersion))
-	helmChart.SetAnnotations(map[string]string{
-		chart.ManagedBy: h.opts.ControllerName,
-	})
-	return helmChart
-}
-
-// getHelmRelease returns the HelmRelease created on behalf of this ProjectHelmChart
-func (h *handler) getHelmRelease(projectID string, projectHelmChart *v1alpha1.ProjectHelmChart) *helmlockerv1alpha1.HelmRelease {
-	// must be in system namespace since helmlocker controllers are configured to only watch one namespace
+// getRelease returns the Helm release that needs to be deployed by the DeploymentBackend on behalf of this ProjectHelmChart
+func (h *handler) getRelease(projectID string, valuesContent string, projectHelmChart *v1alpha1.ProjectHelmChart) backend.Release {
 	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
-	helmRelease := helmlockerv1alpha1.NewHelmRelease(h.systemNamespace, releaseName, helmlockerv1alpha1.HelmRelease{
-		Spec: helmlockerv1alpha1.HelmReleaseSpec{
-			Release: helmlockerv1alpha1.ReleaseKey{
-				Namespace: releaseNamespace,
-				Name:      releaseName,
-			},
-		},
-	})
-	helmRelease.SetLabels(common.GetHelmResourceLabels(projectID, projectHelmChart.Spec.HelmAPIVersion))
-	helmRelease.SetAnnotations(map[string]string{
-		release.ManagedBy: h.opts.ControllerName,
-	})
-	return helmRelease
+	release := backend.Release{
+		Namespace:     releaseNamespace,
+		Name:          releaseName,
+		ValuesContent: valuesContent,
+		Labels:        common.GetHelmResourceLabels(projectID, projectHelmChart.Spec.HelmAPIVersion),
+		HelmOptions:   projectHelmChart.Spec.HelmOptions,
+	}
+	if h.opts.ScopeReleasePermissions {
+		release.ServiceAccountName = getReleaseServiceAccountName(releaseName)
+	}
+	return release
 }
 
 // getProjectReleaseNamespace returns the Project Release Namespace created on behalf of this ProjectHelmChart, if required
 func (h *handler) getProjectReleaseNamespace(projectID string, isOrphaned bool, projectHelmChart *v1alpha1.ProjectHelmChart) *v1.Namespace {
-	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
-	if releaseNamespace == h.systemNamespace || releaseNamespace == projectHelmChart.Namespace {
+	if !h.isOperatorManagedReleaseNamespace(projectHelmChart) {
 		return nil
 	}
+	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+	originalReleaseNamespace, _ := h.getOriginalReleaseNamespaceAndName(projectHelmChart)
+	annotations := common.GetProjectNamespaceAnnotations(h.opts.ProjectReleaseLabelValue, h.opts.ProjectLabel, h.opts.ClusterID)
+	labels := common.GetProjectNamespaceLabels(projectID, h.opts.ProjectLabel, h.opts.ProjectReleaseLabelValue, isOrphaned)
+	if projectHelmChart.Spec.ReleaseNamespaceMetadata != nil && h.validateReleaseNamespaceMetadata(projectHelmChart) == nil {
+		// labels and annotations managed by the operator cannot be overridden
+		labels = addMissing(labels, projectHelmChart.Spec.ReleaseNamespaceMetadata.Labels)
+		annotations = addMissing(annotations, projectHelmChart.Spec.ReleaseNamespaceMetadata.Annotations)
+	}
+	// a shared release namespace is applied by every ProjectHelmChart in the project, so it only receives metadata propagated from the project
+	propagatedLabels, propagatedAnnotations := h.getPropagatedLabelsAndAnnotations(projectHelmChart, !h.opts.SharedProjectReleaseNamespace)
+	labels = addMissing(labels, propagatedLabels)
+	annotations = addMissing(annotations, propagatedAnnotations)
 	projectReleaseNamespace := &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        releaseNamespace,
-			Annotations: common.GetProjectNamespaceAnnotations(h.opts.ProjectReleaseLabelValue, h.opts.ProjectLabel, h.opts.ClusterID),
-			Labels:      common.GetProjectNamespaceLabels(projectID, h.opts.ProjectLabel, h.opts.ProjectReleaseLabelValue, isOrphaned),
+			Annotations: common.AddOriginalNameAnnotation(annotations, originalReleaseNamespace, releaseNamespace),
+			Labels:      labels,
 		},
 	}
 	return projectReleaseNamespace
 }
 
 // getRoleBindings returns the RoleBindings created on behalf of this ProjectHelmChart in the Project Release Namespace based on Roles created in the
-// Project Release Namespace and RoleBindings attached to the default operator roles (configured as AdminClusterRole, EditClusterRole, and ViewClusterRole
+// Project Release Namespace and RoleBindings attached to the default operator roles (configured as AdminClusterRole, EditClusterRole, ViewClusterRole, and ClusterRoleTiers
 //
 //	in the providedRuntimeOptions) in the Project Registration Namespace only. To update these RoleBindings in the release namespace, you will need to assign
 //
@@ -109,3 +99,366 @@ func (h *handler) getRoleBindings(projectID string, k8sRoleToRoleRefs map[string
 
 	return objs
 }
+
+// getDefaultReleaseRoles returns the default Roles created in the Project Release Namespace for each tier that the deployed chart does not
+// provide any Roles for. The RoleRef of each default Role is added to the provided k8sRoleToRoleRefs so that getRoleBindings binds it.
+//
+// Note: default Roles are intentionally not marked with the Project Helm Chart Role labels, since they would otherwise be considered
+// as Roles provided by the chart on the next sync
+func (h *handler) getDefaultReleaseRoles(projectID string, k8sRoleToRoleRefs map[string][]rbacv1.RoleRef, projectHelmChart *v1alpha1.ProjectHelmChart) []runtime.Object {
+	if k8sRoleToRoleRefs == nil {
+		// the release namespace does not exist yet
+		return nil
+	}
+	var objs []runtime.Object
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	for subjectRole, rules := range h.defaultReleaseRoles.Roles {
+		roleRefs, ok := k8sRoleToRoleRefs[subjectRole]
+		if !ok || len(roleRefs) > 0 {
+			// the chart provides its own roles for this tier
+			continue
+		}
+		name := naming.SafeName(fmt.Sprintf("%s-%s", releaseName, subjectRole), naming.MaxNamespaceNameLength)
+		objs = append(objs, &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: releaseNamespace,
+				Labels:    common.GetCommonLabels(projectID),
+			},
+			Rules: rules,
+		})
+		k8sRoleToRoleRefs[subjectRole] = append(roleRefs, rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     name,
+		})
+	}
+	return objs
+}
+
+// rbacReport is the report of the RBAC computed for a ProjectHelmChart
+type rbacReport struct {
+	// ReleaseNamespace is the namespace where the Roles of each tier are bound
+	ReleaseNamespace string `json:"releaseNamespace"`
+	// Tiers are the reports for each tier (e.g. admin, edit, view, or any tier in ClusterRoleTiers)
+	Tiers map[string]rbacReportTier `json:"tiers"`
+}
+
+// rbacReportTier is the report of the RBAC computed for a single tier of a ProjectHelmChart
+type rbacReportTier struct {
+	// ClusterRole is the ClusterRole whose bindings determine the subjects of this tier
+	ClusterRole string `json:"clusterRole"`
+	// Roles are the names of the Roles bound to the subjects of this tier in the release namespace
+	Roles []string `json:"roles"`
+	// Subjects are the subjects of this tier
+	Subjects []rbacReportSubject `json:"subjects"`
+}
+
+// rbacReportSubject is a subject of a tier along with the bindings that it was collected from
+type rbacReportSubject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Sources are the RoleBindings (in the format RoleBinding <namespace>/<name>) and ClusterRoleBindings (in the format
+	// ClusterRoleBinding <name>) that bind this subject to the ClusterRole of this tier
+	Sources []string `json:"sources"`
+}
+
+// getRBACReport returns the ConfigMap in the Project Registration Namespace that reports the RBAC computed for this ProjectHelmChart
+//
+// Note: if a ConfigMap with the same name that is not owned by this ProjectHelmChart already exists in the Project Registration Namespace,
+// no report is returned to avoid overwriting a ConfigMap created by a project owner
+func (h *handler) getRBACReport(projectID string, k8sRoleToRoleRefs map[string][]rbacv1.RoleRef, k8sRoleToSubjects map[string][]rbacv1.Subject, k8sRoleToSubjectSources subjectSources, projectHelmChart *v1alpha1.ProjectHelmChart) (*v1.ConfigMap, error) {
+	name := getRBACReportName(projectHelmChart)
+	existing, err := h.configmapCache.Get(projectHelmChart.Namespace, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && (existing.Annotations[apply.LabelNamespace] != projectHelmChart.Namespace || existing.Annotations[apply.LabelName] != projectHelmChart.Name) {
+		logrus.Warnf("Unable to record rbac report for %s/%s: configmap %s/%s already exists and is not owned by it", projectHelmChart.Namespace, projectHelmChart.Name, projectHelmChart.Namespace, name)
+		return nil, nil
+	}
+	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+	report := rbacReport{
+		ReleaseNamespace: releaseNamespace,
+		Tiers:            make(map[string]rbacReportTier),
+	}
+	for subjectRole, clusterRole := range common.GetDefaultClusterRoles(h.opts) {
+		tier := rbacReportTier{
+			ClusterRole: clusterRole,
+			Roles:       []string{},
+			Subjects:    []rbacReportSubject{},
+		}
+		if len(k8sRoleToSubjects[subjectRole]) > 0 {
+			// RoleBindings are only created if the tier has subjects
+			for _, roleRef := range k8sRoleToRoleRefs[subjectRole] {
+				tier.Roles = append(tier.Roles, roleRef.Name)
+			}
+		}
+		for _, subject := range k8sRoleToSubjects[subjectRole] {
+			sources := append([]string{}, k8sRoleToSubjectSources[subjectRole][getSubjectKey(subject)]...)
+			sort.Strings(sources)
+			tier.Subjects = append(tier.Subjects, rbacReportSubject{
+				Kind:      subject.Kind,
+				Name:      subject.Name,
+				Namespace: subject.Namespace,
+				Sources:   sources,
+			})
+		}
+		// sort to ensure that the report only changes when the computed RBAC changes
+		sort.Strings(tier.Roles)
+		sort.Slice(tier.Subjects, func(i, j int) bool {
+			a, b := tier.Subjects[i], tier.Subjects[j]
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			return a.Name < b.Name
+		})
+		report.Tiers[subjectRole] = tier
+	}
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	labels := common.GetCommonLabels(projectID)
+	labels[common.HelmProjectOperatorRBACReportLabel] = projectHelmChart.Name
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: projectHelmChart.Namespace,
+			Labels:    labels,
+		},
+		Data: map[string]string{
+			"report.json": string(reportBytes),
+		},
+	}, nil
+}
+
+// getFanOutRoleBindings returns the Roles and RoleBindings that grant the permissions of fan-out Roles and ClusterRoles to the subjects of the
+// corresponding subject role in every target project namespace of this ProjectHelmChart, excluding the Project Release Namespace. Since RoleBindings
+// cannot reference a Role in a different namespace, each fan-out Role in the Project Release Namespace is copied into each target project namespace.
+//
+// As the target project namespaces change, these Roles and RoleBindings are added or removed on the next apply
+func (h *handler) getFanOutRoleBindings(projectID string, targetNamespaces []string, roles []*rbacv1.Role, clusterRoles []*rbacv1.ClusterRole, k8sRoleToSubjects map[string][]rbacv1.Subject, projectHelmChart *v1alpha1.ProjectHelmChart) []runtime.Object {
+	if len(roles) == 0 && len(clusterRoles) == 0 {
+		return nil
+	}
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	var objs []runtime.Object
+	for _, namespace := range targetNamespaces {
+		if namespace == releaseNamespace {
+			// the Roles in the Project Release Namespace are already bound by getRoleBindings
+			continue
+		}
+		for _, role := range roles {
+			subjects := k8sRoleToSubjects[role.Labels[common.HelmProjectOperatorProjectHelmChartRoleAggregateFromLabel]]
+			if len(subjects) == 0 {
+				// no need to create empty RoleBindings
+				continue
+			}
+			// prefix with the release name to avoid conflicts with Roles and RoleBindings that already exist in the project namespace
+			name := naming.SafeName(fmt.Sprintf("%s-%s", releaseName, role.Name), naming.MaxNamespaceNameLength)
+			objs = append(objs,
+				&rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: namespace,
+						Labels:    common.GetCommonLabels(projectID),
+					},
+					Rules: role.Rules,
+				},
+				&rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: namespace,
+						Labels:    common.GetCommonLabels(projectID),
+					},
+					RoleRef: rbacv1.RoleRef{
+						APIGroup: rbacv1.GroupName,
+						Kind:     "Role",
+						Name:     name,
+					},
+					Subjects: subjects,
+				},
+			)
+		}
+		for _, clusterRole := range clusterRoles {
+			subjects := k8sRoleToSubjects[clusterRole.Labels[common.HelmProjectOperatorProjectHelmChartRoleAggregateFromLabel]]
+			if len(subjects) == 0 {
+				// no need to create empty RoleBindings
+				continue
+			}
+			objs = append(objs, &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      naming.SafeName(fmt.Sprintf("%s-%s", releaseName, clusterRole.Name), naming.MaxNamespaceNameLength),
+					Namespace: namespace,
+					Labels:    common.GetCommonLabels(projectID),
+				},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "ClusterRole",
+					Name:     clusterRole.Name,
+				},
+				Subjects: subjects,
+			})
+		}
+	}
+	return objs
+}
+
+// getReleasePermissions returns the ServiceAccount that deploys the release on behalf of this ProjectHelmChart along with the RBAC resources
+// that scope its permissions to the Project Release Namespace and the target namespaces of the ProjectHelmChart
+func (h *handler) getReleasePermissions(projectID string, targetNamespaces []string, projectHelmChart *v1alpha1.ProjectHelmChart) []runtime.Object {
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	serviceAccountName := getReleaseServiceAccountName(releaseName)
+	subjects := []rbacv1.Subject{
+		{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      serviceAccountName,
+			Namespace: h.systemNamespace,
+		},
+	}
+
+	objs := []runtime.Object{
+		&v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				// must be in system namespace since the ServiceAccount should not be usable by workloads deployed by the release
+				Name:      serviceAccountName,
+				Namespace: h.systemNamespace,
+				Labels:    common.GetCommonLabels(projectID),
+			},
+		},
+	}
+
+	defaultRules := common.DefaultReleasePermissionRules
+	if h.releasePermissions.AllowAllResources {
+		defaultRules = common.AllReleasePermissionRules
+	}
+	rules := append([]rbacv1.PolicyRule{}, defaultRules...)
+	rules = append(rules, h.releasePermissions.Rules...)
+	namespaces := append([]string{releaseNamespace}, targetNamespaces...)
+	seen := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		if seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		objs = append(objs,
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceAccountName,
+					Namespace: namespace,
+					Labels:    common.GetCommonLabels(projectID),
+				},
+				Rules: rules,
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceAccountName,
+					Namespace: namespace,
+					Labels:    common.GetCommonLabels(projectID),
+				},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "Role",
+					Name:     serviceAccountName,
+				},
+				Subjects: subjects,
+			},
+		)
+	}
+
+	if len(h.releasePermissions.ClusterRules) == 0 {
+		return objs
+	}
+	// cluster-scoped names must be unique across all operators deployed onto the cluster
+	clusterRoleName := fmt.Sprintf("%s-%s", h.systemNamespace, serviceAccountName)
+	objs = append(objs,
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   clusterRoleName,
+				Labels: common.GetCommonLabels(projectID),
+			},
+			Rules: h.releasePermissions.ClusterRules,
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   clusterRoleName,
+				Labels: common.GetCommonLabels(projectID),
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     clusterRoleName,
+			},
+			Subjects: subjects,
+		},
+	)
+	return objs
+}
+
+// getReleaseNamespaceResources returns the ResourceQuota and LimitRange created in the Project Release Namespace based on the
+// defaults configured by the operator and the overrides provided in the spec.releaseNamespaceResources of this ProjectHelmChart
+func (h *handler) getReleaseNamespaceResources(projectID string, projectHelmChart *v1alpha1.ProjectHelmChart) []runtime.Object {
+	var objs []runtime.Object
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	overrides := projectHelmChart.Spec.ReleaseNamespaceResources
+	if overrides == nil {
+		overrides = &v1alpha1.ReleaseNamespaceResources{}
+	}
+
+	if h.releaseNamespaceResources.ResourceQuota != nil || len(overrides.ResourceQuota) > 0 {
+		resourceQuotaSpec := v1.ResourceQuotaSpec{}
+		if h.releaseNamespaceResources.ResourceQuota != nil {
+			h.releaseNamespaceResources.ResourceQuota.DeepCopyInto(&resourceQuotaSpec)
+		}
+		resourceQuotaSpec.Hard = mergeResourceLists(resourceQuotaSpec.Hard, overrides.ResourceQuota)
+		objs = append(objs, &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      releaseName,
+				Namespace: releaseNamespace,
+				Labels:    common.GetCommonLabels(projectID),
+			},
+			Spec: resourceQuotaSpec,
+		})
+	}
+
+	if h.releaseNamespaceResources.LimitRange != nil || overrides.LimitRange != nil {
+		limitRangeSpec := v1.LimitRangeSpec{}
+		if h.releaseNamespaceResources.LimitRange != nil {
+			h.releaseNamespaceResources.LimitRange.DeepCopyInto(&limitRangeSpec)
+		}
+		if overrides.LimitRange != nil {
+			containerIndex := -1
+			for i, limit := range limitRangeSpec.Limits {
+				if limit.Type == v1.LimitTypeContainer {
+					containerIndex = i
+					break
+				}
+			}
+			if containerIndex < 0 {
+				limitRangeSpec.Limits = append(limitRangeSpec.Limits, v1.LimitRangeItem{
+					Type: v1.LimitTypeContainer,
+				})
+				containerIndex = len(limitRangeSpec.Limits) - 1
+			}
+			containerLimits := &limitRangeSpec.Limits[containerIndex]
+			containerLimits.Default = mergeResourceLists(containerLimits.Default, overrides.LimitRange.Default)
+			containerLimits.DefaultRequest = mergeResourceLists(containerLimits.DefaultRequest, overrides.LimitRange.DefaultRequest)
+			containerLimits.Max = mergeResourceLists(containerLimits.Max, overrides.LimitRange.Max)
+		}
+		objs = append(objs, &v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      releaseName,
+				Namespace: releaseNamespace,
+				Labels:    common.GetCommonLabels(projectID),
+			},
+			Spec: limitRangeSpec,
+		})
+	}
+
+	return objs
+}
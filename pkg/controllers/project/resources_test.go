@@ -0,0 +1,375 @@
+package project
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/wrangler/pkg/apply"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestGetRBACReport(t *testing.T) {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+	}
+	newConfigMap := func(ownerNamespace, ownerName string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getRBACReportName(projectHelmChart),
+				Namespace: projectHelmChart.Namespace,
+				Annotations: map[string]string{
+					apply.LabelNamespace: ownerNamespace,
+					apply.LabelName:      ownerName,
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		configMaps []*corev1.ConfigMap
+		expected   bool
+	}{
+		{
+			name:     "no existing report",
+			expected: true,
+		},
+		{
+			name:       "existing report owned by this ProjectHelmChart",
+			configMaps: []*corev1.ConfigMap{newConfigMap(projectHelmChart.Namespace, projectHelmChart.Name)},
+			expected:   true,
+		},
+		{
+			name:       "existing configmap owned by another ProjectHelmChart",
+			configMaps: []*corev1.ConfigMap{newConfigMap(projectHelmChart.Namespace, "project-logging")},
+		},
+		{
+			name:       "existing configmap without owner",
+			configMaps: []*corev1.ConfigMap{newConfigMap("", "")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						DeploymentBackend: "helm-sdk",
+					},
+				},
+				configmapCache: &fakeConfigMapCache{configMaps: tc.configMaps},
+			}
+			report, err := h.getRBACReport("p-example", nil, nil, nil, projectHelmChart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (report != nil) != tc.expected {
+				t.Errorf("expected report to be returned: %t, found %v", tc.expected, report)
+			}
+		})
+	}
+}
+
+func TestGetRBACReportName(t *testing.T) {
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: strings.Repeat("a", 60),
+		},
+	}
+	name := getRBACReportName(projectHelmChart)
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		t.Errorf("expected %s to be a valid DNS-1123 label: %s", name, strings.Join(errs, ", "))
+	}
+}
+
+func TestGetReleaseNamespaceResources(t *testing.T) {
+	defaultResourceQuota := &corev1.ResourceQuotaSpec{
+		Hard: corev1.ResourceList{
+			corev1.ResourcePods:         resource.MustParse("20"),
+			corev1.ResourceLimitsMemory: resource.MustParse("4Gi"),
+		},
+	}
+	defaultLimitRange := &corev1.LimitRangeSpec{
+		Limits: []corev1.LimitRangeItem{
+			{
+				Type: corev1.LimitTypePod,
+				Max:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+			},
+			{
+				Type:           corev1.LimitTypeContainer,
+				Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+				DefaultRequest: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+		},
+	}
+	originalDefaultLimitRange := defaultLimitRange.DeepCopy()
+	testCases := []struct {
+		name                  string
+		resourceQuota         *corev1.ResourceQuotaSpec
+		limitRange            *corev1.LimitRangeSpec
+		overrides             *v1alpha1.ReleaseNamespaceResources
+		expectedResourceQuota *corev1.ResourceQuotaSpec
+		expectedLimitRange    *corev1.LimitRangeSpec
+	}{
+		{
+			name: "no defaults or overrides",
+		},
+		{
+			name:                  "defaults only",
+			resourceQuota:         defaultResourceQuota,
+			limitRange:            defaultLimitRange,
+			expectedResourceQuota: defaultResourceQuota,
+			expectedLimitRange:    defaultLimitRange,
+		},
+		{
+			name:          "overrides are merged into the defaults",
+			resourceQuota: defaultResourceQuota,
+			limitRange:    defaultLimitRange,
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				ResourceQuota: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("30")},
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+					Max:     corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+			},
+			expectedResourceQuota: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{
+					corev1.ResourcePods:         resource.MustParse("30"),
+					corev1.ResourceLimitsMemory: resource.MustParse("4Gi"),
+				},
+			},
+			expectedLimitRange: &corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{
+					defaultLimitRange.Limits[0],
+					{
+						Type:           corev1.LimitTypeContainer,
+						Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+						DefaultRequest: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+						Max:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+					},
+				},
+			},
+		},
+		{
+			name: "overrides without defaults",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				ResourceQuota: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+			},
+			expectedResourceQuota: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			},
+			expectedLimitRange: &corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{
+					{
+						Type:           corev1.LimitTypeContainer,
+						DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					},
+				},
+			},
+		},
+		{
+			name:       "container limits are added to LimitRange defaults without container limits",
+			limitRange: &corev1.LimitRangeSpec{Limits: defaultLimitRange.Limits[:1]},
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			},
+			expectedLimitRange: &corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{
+					defaultLimitRange.Limits[0],
+					{
+						Type:    corev1.LimitTypeContainer,
+						Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						DeploymentBackend: "helm-sdk",
+					},
+				},
+				releaseNamespaceResources: common.ReleaseNamespaceResourcesOptions{
+					ResourceQuota: tc.resourceQuota,
+					LimitRange:    tc.limitRange,
+				},
+			}
+			projectHelmChart := &v1alpha1.ProjectHelmChart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project-monitoring",
+					Namespace: "cattle-project-p-example",
+				},
+				Spec: v1alpha1.ProjectHelmChartSpec{
+					ReleaseNamespaceResources: tc.overrides,
+				},
+			}
+			var resourceQuota *corev1.ResourceQuotaSpec
+			var limitRange *corev1.LimitRangeSpec
+			for _, obj := range h.getReleaseNamespaceResources("p-example", projectHelmChart) {
+				switch o := obj.(type) {
+				case *corev1.ResourceQuota:
+					resourceQuota = &o.Spec
+				case *corev1.LimitRange:
+					limitRange = &o.Spec
+				default:
+					t.Fatalf("unexpected object %T", obj)
+				}
+			}
+			if !reflect.DeepEqual(resourceQuota, tc.expectedResourceQuota) {
+				t.Errorf("expected ResourceQuota spec %v, found %v", tc.expectedResourceQuota, resourceQuota)
+			}
+			if !reflect.DeepEqual(limitRange, tc.expectedLimitRange) {
+				t.Errorf("expected LimitRange spec %v, found %v", tc.expectedLimitRange, limitRange)
+			}
+			if !reflect.DeepEqual(defaultLimitRange, originalDefaultLimitRange) {
+				t.Errorf("expected the LimitRange defaults configured on the operator to not be modified, found %v", defaultLimitRange)
+			}
+		})
+	}
+}
+
+func TestValidateReleaseNamespaceResources(t *testing.T) {
+	releaseNamespaceResources := common.ReleaseNamespaceResourcesOptions{
+		MaxResourceQuota: corev1.ResourceList{
+			corev1.ResourcePods: resource.MustParse("50"),
+		},
+		MaxContainerLimits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+	}
+	testCases := []struct {
+		name      string
+		overrides *v1alpha1.ReleaseNamespaceResources
+		expectErr bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "overrides within bounds",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				ResourceQuota: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("50")},
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+					Max:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				},
+			},
+		},
+		{
+			name: "resource quota above the maximum",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				ResourceQuota: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("51")},
+			},
+			expectErr: true,
+		},
+		{
+			name: "resource quota on a resource without a maximum",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				ResourceQuota: corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1")},
+			},
+			expectErr: true,
+		},
+		{
+			name: "container default above the maximum",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3Gi")},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "container default request above the maximum",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2500m")},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "container max above the maximum",
+			overrides: &v1alpha1.ReleaseNamespaceResources{
+				LimitRange: &v1alpha1.ContainerLimitRange{
+					Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")},
+				},
+			},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				releaseNamespaceResources: releaseNamespaceResources,
+			}
+			projectHelmChart := &v1alpha1.ProjectHelmChart{
+				Spec: v1alpha1.ProjectHelmChartSpec{
+					ReleaseNamespaceResources: tc.overrides,
+				},
+			}
+			err := h.validateReleaseNamespaceResources(projectHelmChart)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateResourceListBounds(t *testing.T) {
+	maxResources := corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("2"),
+	}
+	testCases := []struct {
+		name      string
+		resources corev1.ResourceList
+		expectErr bool
+	}{
+		{
+			name: "no resources",
+		},
+		{
+			name:      "below the maximum",
+			resources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m")},
+		},
+		{
+			name:      "equal to the maximum",
+			resources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2000m")},
+		},
+		{
+			name:      "above the maximum",
+			resources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2001m")},
+			expectErr: true,
+		},
+		{
+			name:      "resource without a maximum",
+			resources: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Mi")},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateResourceListBounds("spec.releaseNamespaceResources.resourceQuota", tc.resources, maxResources)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+		})
+	}
+}
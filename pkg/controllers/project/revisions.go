@@ -0,0 +1,196 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// valuesRevisionKey is the key in a values revision Secret that contains the spec.values of that revision
+	valuesRevisionKey = "values.yaml"
+)
+
+// getValuesRevisionSecretName returns the name of the Secret that stores a revision of the spec.values of a release
+func getValuesRevisionSecretName(releaseName string, revision int) string {
+	return fmt.Sprintf("%s-values-v%d", releaseName, revision)
+}
+
+// getValuesRevisionSecrets returns the Secrets that store revisions of the spec.values of a release, sorted from oldest to newest
+func (h *handler) getValuesRevisionSecrets(releaseName string) ([]*corev1.Secret, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		common.HelmProjectOperatorValuesRevisionLabel: releaseName,
+	})
+	secrets, err := h.secretCache.List(h.systemNamespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	var revisionSecrets []*corev1.Secret
+	for _, secret := range secrets {
+		if secret == nil {
+			continue
+		}
+		if _, ok := getValuesRevision(secret); !ok {
+			continue
+		}
+		revisionSecrets = append(revisionSecrets, secret)
+	}
+	sort.Slice(revisionSecrets, func(i, j int) bool {
+		iRevision, _ := getValuesRevision(revisionSecrets[i])
+		jRevision, _ := getValuesRevision(revisionSecrets[j])
+		return iRevision < jRevision
+	})
+	return revisionSecrets, nil
+}
+
+// getValuesRevision returns the revision number stored on a values revision Secret
+func getValuesRevision(secret *corev1.Secret) (int, bool) {
+	if secret.Annotations == nil {
+		return 0, false
+	}
+	revision, err := strconv.Atoi(secret.Annotations[common.HelmProjectOperatorValuesRevisionAnnotation])
+	if err != nil {
+		return 0, false
+	}
+	return revision, true
+}
+
+// getValuesRevisionStatus converts a values revision Secret into the representation displayed in the status of a ProjectHelmChart
+func getValuesRevisionStatus(secret *corev1.Secret) v1alpha1.ValuesRevision {
+	revision, _ := getValuesRevision(secret)
+	return v1alpha1.ValuesRevision{
+		Revision:     revision,
+		ValuesDigest: secret.Annotations[common.HelmProjectOperatorValuesDigestAnnotation],
+		AppliedAt:    secret.CreationTimestamp,
+	}
+}
+
+// getValuesRevisions returns the Secrets that store the revisions of the spec.values of a release that should be retained, adding a
+// new revision if the spec.values of the LastDeployedRelease differ from the latest revision, and updates the list of revisions in
+// the ProjectHelmChartStatus
+//
+// Note: revisions are stored in immutable Secrets in the system namespace since only Cluster Admins should have access to it. Since
+// these Secrets are applied alongside the other resources generated for the ProjectHelmChart, revisions beyond the configured limit
+// are pruned by the apply and all revisions are removed once the ProjectHelmChart is removed.
+func (h *handler) getValuesRevisions(projectHelmChart *v1alpha1.ProjectHelmChart, projectHelmChartStatus *v1alpha1.ProjectHelmChartStatus) ([]runtime.Object, error) {
+	if h.opts.ValuesHistoryLimit <= 0 {
+		projectHelmChartStatus.ValuesRevisions = nil
+		return nil, nil
+	}
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	existingRevisionSecrets, err := h.getValuesRevisionSecrets(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list values revisions for release %s: %s", releaseName, err)
+	}
+	var revisionSecrets []*corev1.Secret
+	for _, existingRevisionSecret := range existingRevisionSecrets {
+		revision, _ := getValuesRevision(existingRevisionSecret)
+		revisionSecret := getValuesRevisionSecret(h.systemNamespace, releaseName, revision, existingRevisionSecret.Annotations[common.HelmProjectOperatorValuesDigestAnnotation], existingRevisionSecret.Data[valuesRevisionKey])
+		// retain the creation timestamp to report when the revision was applied
+		revisionSecret.CreationTimestamp = existingRevisionSecret.CreationTimestamp
+		revisionSecrets = append(revisionSecrets, revisionSecret)
+	}
+
+	lastDeployedRelease := projectHelmChartStatus.LastDeployedRelease
+	latestRevision := 0
+	latestValuesDigest := ""
+	if len(revisionSecrets) > 0 {
+		latestRevisionStatus := getValuesRevisionStatus(revisionSecrets[len(revisionSecrets)-1])
+		latestRevision = latestRevisionStatus.Revision
+		latestValuesDigest = latestRevisionStatus.ValuesDigest
+	}
+	if lastDeployedRelease != nil && lastDeployedRelease.ValuesDigest != latestValuesDigest {
+		valuesContent, err := yaml.Marshal(lastDeployedRelease.Values)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshall values of revision %d: %s", latestRevision+1, err)
+		}
+		logrus.Infof("Recording values revision %d for ProjectHelmChart %s/%s", latestRevision+1, projectHelmChart.Namespace, projectHelmChart.Name)
+		revisionSecret := getValuesRevisionSecret(h.systemNamespace, releaseName, latestRevision+1, lastDeployedRelease.ValuesDigest, valuesContent)
+		// the revision is re-enqueued once it is created, which will replace this with its creation timestamp
+		revisionSecret.CreationTimestamp = metav1.Now()
+		revisionSecrets = append(revisionSecrets, revisionSecret)
+	}
+
+	// only retain the newest revisions
+	if len(revisionSecrets) > h.opts.ValuesHistoryLimit {
+		revisionSecrets = revisionSecrets[len(revisionSecrets)-h.opts.ValuesHistoryLimit:]
+	}
+
+	var objs []runtime.Object
+	projectHelmChartStatus.ValuesRevisions = nil
+	for _, revisionSecret := range revisionSecrets {
+		projectHelmChartStatus.ValuesRevisions = append(projectHelmChartStatus.ValuesRevisions, getValuesRevisionStatus(revisionSecret))
+		// the creation timestamp is set by the API server
+		revisionSecret.CreationTimestamp = metav1.Time{}
+		objs = append(objs, revisionSecret)
+	}
+	return objs, nil
+}
+
+// getValuesRevisionSecret returns the immutable Secret that stores a revision of the spec.values of a release
+func getValuesRevisionSecret(systemNamespace, releaseName string, revision int, valuesDigest string, valuesContent []byte) *corev1.Secret {
+	immutable := true
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getValuesRevisionSecretName(releaseName, revision),
+			Namespace: systemNamespace,
+			Labels: map[string]string{
+				common.HelmProjectOperatorValuesRevisionLabel: releaseName,
+			},
+			Annotations: map[string]string{
+				common.HelmProjectOperatorValuesRevisionAnnotation: strconv.Itoa(revision),
+				common.HelmProjectOperatorValuesDigestAnnotation:   valuesDigest,
+			},
+		},
+		Immutable: &immutable,
+		Type:      corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			valuesRevisionKey: valuesContent,
+		},
+	}
+}
+
+// OnRollbackToRevision restores the spec.values of the revision requested via the rollback-to-revision annotation
+func (h *handler) OnRollbackToRevision(_ string, projectHelmChart *v1alpha1.ProjectHelmChart) (*v1alpha1.ProjectHelmChart, error) {
+	if !h.shouldManage(projectHelmChart) || projectHelmChart.DeletionTimestamp != nil {
+		return projectHelmChart, nil
+	}
+	revision, ok, err := common.GetRollbackToRevision(projectHelmChart)
+	if !ok {
+		return projectHelmChart, nil
+	}
+	projectHelmChartCopy := projectHelmChart.DeepCopy()
+	delete(projectHelmChartCopy.Annotations, common.HelmProjectOperatorRollbackToRevisionAnnotation)
+	if err != nil {
+		logrus.Errorf("Unable to roll back ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+		return h.projectHelmCharts.Update(projectHelmChartCopy)
+	}
+
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	revisionSecret, err := h.secretCache.Get(h.systemNamespace, getValuesRevisionSecretName(releaseName, revision))
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return projectHelmChart, err
+		}
+		logrus.Errorf("Unable to roll back ProjectHelmChart %s/%s: values revision %d does not exist", projectHelmChart.Namespace, projectHelmChart.Name, revision)
+		return h.projectHelmCharts.Update(projectHelmChartCopy)
+	}
+	var values v1alpha1.GenericMap
+	if err := yaml.Unmarshal(revisionSecret.Data[valuesRevisionKey], &values); err != nil {
+		logrus.Errorf("Unable to roll back ProjectHelmChart %s/%s: unable to parse values of revision %d: %s", projectHelmChart.Namespace, projectHelmChart.Name, revision, err)
+		return h.projectHelmCharts.Update(projectHelmChartCopy)
+	}
+	logrus.Infof("Restoring spec.values of ProjectHelmChart %s/%s to values revision %d", projectHelmChart.Namespace, projectHelmChart.Name, revision)
+	projectHelmChartCopy.Spec.Values = values
+	return h.projectHelmCharts.Update(projectHelmChartCopy)
+}
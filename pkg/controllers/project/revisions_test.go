@@ -0,0 +1,300 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/helm-project-operator/pkg/controllers/namespace"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	corecontroller "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeNamespaceCache is a NamespaceCache backed by the provided namespaces
+type fakeNamespaceCache struct {
+	corecontroller.NamespaceCache
+
+	namespaces []*corev1.Namespace
+}
+
+func (c *fakeNamespaceCache) Get(name string) (*corev1.Namespace, error) {
+	for _, namespace := range c.namespaces {
+		if namespace.Name == name {
+			return namespace, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, name)
+}
+
+// fakeProjectGetter is a ProjectGetter that treats the provided namespaces as registration and system namespaces
+type fakeProjectGetter struct {
+	namespace.ProjectGetter
+
+	registrationNamespaces []string
+	systemNamespaces       []string
+	targetNamespaces       []string
+}
+
+func (g *fakeProjectGetter) IsProjectRegistrationNamespace(namespace *corev1.Namespace) bool {
+	return namespace != nil && containsString(g.registrationNamespaces, namespace.Name)
+}
+
+func (g *fakeProjectGetter) IsSystemNamespace(namespace *corev1.Namespace) bool {
+	return namespace != nil && containsString(g.systemNamespaces, namespace.Name)
+}
+
+func (g *fakeProjectGetter) GetTargetProjectNamespaces(_ *v1alpha1.ProjectHelmChart) ([]string, error) {
+	return g.targetNamespaces, nil
+}
+
+// fakeProjectHelmChartController is a ProjectHelmChartController that records the ProjectHelmCharts that were updated or enqueued
+type fakeProjectHelmChartController struct {
+	helmprojectcontroller.ProjectHelmChartController
+
+	updated  []*v1alpha1.ProjectHelmChart
+	enqueued []string
+}
+
+func (c *fakeProjectHelmChartController) Enqueue(namespace, name string) {
+	c.enqueued = append(c.enqueued, namespace+"/"+name)
+}
+
+func (c *fakeProjectHelmChartController) Update(projectHelmChart *v1alpha1.ProjectHelmChart) (*v1alpha1.ProjectHelmChart, error) {
+	c.updated = append(c.updated, projectHelmChart)
+	return projectHelmChart, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetValuesRevisions(t *testing.T) {
+	systemNamespace := "cattle-helm-system"
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+	}
+	newHandler := func(valuesHistoryLimit int, secrets []*corev1.Secret) *handler {
+		return &handler{
+			systemNamespace: systemNamespace,
+			opts: common.Options{
+				RuntimeOptions: common.RuntimeOptions{
+					DeploymentBackend:  "helm-sdk",
+					ValuesHistoryLimit: valuesHistoryLimit,
+				},
+			},
+			secretCache: &fakeSecretCache{secrets: secrets},
+		}
+	}
+	_, releaseName := newHandler(0, nil).getReleaseNamespaceAndName(projectHelmChart)
+	newRevisionSecret := func(revision int, values v1alpha1.GenericMap) *corev1.Secret {
+		return getValuesRevisionSecret(systemNamespace, releaseName, revision, getValuesDigest(values), []byte("{}\n"))
+	}
+	deployedValues := v1alpha1.GenericMap{"replicas": float64(1)}
+	newValues := v1alpha1.GenericMap{"replicas": float64(2)}
+	existingRevisionSecrets := []*corev1.Secret{
+		newRevisionSecret(2, v1alpha1.GenericMap{"replicas": float64(3)}),
+		newRevisionSecret(3, deployedValues),
+		newRevisionSecret(1, v1alpha1.GenericMap{}),
+	}
+
+	testCases := []struct {
+		name                string
+		valuesHistoryLimit  int
+		secrets             []*corev1.Secret
+		lastDeployedRelease *v1alpha1.DeployedRelease
+		expectedRevisions   []int
+	}{
+		{
+			name:                "values history disabled",
+			valuesHistoryLimit:  0,
+			secrets:             existingRevisionSecrets,
+			lastDeployedRelease: &v1alpha1.DeployedRelease{Revision: 1, ValuesDigest: getValuesDigest(newValues), Values: newValues},
+		},
+		{
+			name:               "no release deployed",
+			valuesHistoryLimit: 10,
+		},
+		{
+			name:                "first revision is recorded",
+			valuesHistoryLimit:  10,
+			lastDeployedRelease: &v1alpha1.DeployedRelease{Revision: 1, ValuesDigest: getValuesDigest(deployedValues), Values: deployedValues},
+			expectedRevisions:   []int{1},
+		},
+		{
+			name:                "existing revisions are retained in order",
+			valuesHistoryLimit:  10,
+			secrets:             existingRevisionSecrets,
+			lastDeployedRelease: &v1alpha1.DeployedRelease{Revision: 4, ValuesDigest: getValuesDigest(deployedValues), Values: deployedValues},
+			expectedRevisions:   []int{1, 2, 3},
+		},
+		{
+			name:                "new revision is appended",
+			valuesHistoryLimit:  10,
+			secrets:             existingRevisionSecrets,
+			lastDeployedRelease: &v1alpha1.DeployedRelease{Revision: 5, ValuesDigest: getValuesDigest(newValues), Values: newValues},
+			expectedRevisions:   []int{1, 2, 3, 4},
+		},
+		{
+			name:                "revisions beyond the limit are trimmed",
+			valuesHistoryLimit:  2,
+			secrets:             existingRevisionSecrets,
+			lastDeployedRelease: &v1alpha1.DeployedRelease{Revision: 5, ValuesDigest: getValuesDigest(newValues), Values: newValues},
+			expectedRevisions:   []int{3, 4},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHandler(tc.valuesHistoryLimit, tc.secrets)
+			status := v1alpha1.ProjectHelmChartStatus{
+				LastDeployedRelease: tc.lastDeployedRelease,
+				ValuesRevisions:     []v1alpha1.ValuesRevision{{Revision: 100}},
+			}
+			objs, err := h.getValuesRevisions(projectHelmChart, &status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var revisions, statusRevisions []int
+			for _, obj := range objs {
+				secret, ok := obj.(*corev1.Secret)
+				if !ok {
+					t.Fatalf("expected values revision to be a Secret, found %T", obj)
+				}
+				revision, _ := getValuesRevision(secret)
+				if expectedName := getValuesRevisionSecretName(releaseName, revision); secret.Name != expectedName || secret.Namespace != systemNamespace {
+					t.Errorf("expected values revision %d to be stored in Secret %s/%s, found %s/%s", revision, systemNamespace, expectedName, secret.Namespace, secret.Name)
+				}
+				revisions = append(revisions, revision)
+			}
+			for _, valuesRevision := range status.ValuesRevisions {
+				statusRevisions = append(statusRevisions, valuesRevision.Revision)
+			}
+			if !reflect.DeepEqual(revisions, tc.expectedRevisions) {
+				t.Errorf("expected values revisions %v, found %v", tc.expectedRevisions, revisions)
+			}
+			if !reflect.DeepEqual(statusRevisions, tc.expectedRevisions) {
+				t.Errorf("expected values revisions %v in status, found %v", tc.expectedRevisions, statusRevisions)
+			}
+		})
+	}
+}
+
+func TestOnRollbackToRevision(t *testing.T) {
+	systemNamespace := "cattle-helm-system"
+	registrationNamespace := "cattle-project-p-example"
+	h := &handler{
+		systemNamespace: systemNamespace,
+		opts: common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				DeploymentBackend: "helm-sdk",
+			},
+		},
+		namespaceCache: &fakeNamespaceCache{
+			namespaces: []*corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: registrationNamespace}}},
+		},
+		projectGetter: &fakeProjectGetter{registrationNamespaces: []string{registrationNamespace}},
+	}
+	newProjectHelmChart := func(rollbackToRevision string) *v1alpha1.ProjectHelmChart {
+		projectHelmChart := &v1alpha1.ProjectHelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "project-monitoring",
+				Namespace:   registrationNamespace,
+				Annotations: map[string]string{"example.com/unrelated": "true"},
+			},
+			Spec: v1alpha1.ProjectHelmChartSpec{
+				Values: v1alpha1.GenericMap{"replicas": float64(2)},
+			},
+		}
+		if len(rollbackToRevision) > 0 {
+			projectHelmChart.Annotations[common.HelmProjectOperatorRollbackToRevisionAnnotation] = rollbackToRevision
+		}
+		return projectHelmChart
+	}
+	_, releaseName := h.getReleaseNamespaceAndName(newProjectHelmChart(""))
+	revisionValues := v1alpha1.GenericMap{"replicas": float64(1)}
+	h.secretCache = &fakeSecretCache{
+		secrets: []*corev1.Secret{
+			getValuesRevisionSecret(systemNamespace, releaseName, 1, getValuesDigest(revisionValues), []byte("replicas: 1\n")),
+			getValuesRevisionSecret(systemNamespace, releaseName, 2, "", []byte("replicas: [\n")),
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		projectHelmChart *v1alpha1.ProjectHelmChart
+		expectUpdate     bool
+		expectedValues   v1alpha1.GenericMap
+	}{
+		{
+			name:             "no annotation",
+			projectHelmChart: newProjectHelmChart(""),
+		},
+		{
+			name:             "revision is restored",
+			projectHelmChart: newProjectHelmChart("1"),
+			expectUpdate:     true,
+			expectedValues:   revisionValues,
+		},
+		{
+			name:             "missing revision only removes the annotation",
+			projectHelmChart: newProjectHelmChart("3"),
+			expectUpdate:     true,
+			expectedValues:   v1alpha1.GenericMap{"replicas": float64(2)},
+		},
+		{
+			name:             "unparseable revision only removes the annotation",
+			projectHelmChart: newProjectHelmChart("2"),
+			expectUpdate:     true,
+			expectedValues:   v1alpha1.GenericMap{"replicas": float64(2)},
+		},
+		{
+			name:             "invalid annotation is removed",
+			projectHelmChart: newProjectHelmChart("latest"),
+			expectUpdate:     true,
+			expectedValues:   v1alpha1.GenericMap{"replicas": float64(2)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectHelmCharts := &fakeProjectHelmChartController{}
+			h.projectHelmCharts = projectHelmCharts
+			if _, err := h.OnRollbackToRevision("", tc.projectHelmChart); err != nil {
+				t.Fatal(err)
+			}
+			if !tc.expectUpdate {
+				if len(projectHelmCharts.updated) != 0 {
+					t.Errorf("expected ProjectHelmChart to not be updated, found %v", projectHelmCharts.updated)
+				}
+				return
+			}
+			if len(projectHelmCharts.updated) != 1 {
+				t.Fatalf("expected ProjectHelmChart to be updated once, found %d updates", len(projectHelmCharts.updated))
+			}
+			updated := projectHelmCharts.updated[0]
+			if _, ok := updated.Annotations[common.HelmProjectOperatorRollbackToRevisionAnnotation]; ok {
+				t.Errorf("expected annotation %s to be removed", common.HelmProjectOperatorRollbackToRevisionAnnotation)
+			}
+			if updated.Annotations["example.com/unrelated"] != "true" {
+				t.Errorf("expected unrelated annotations to be retained, found %v", updated.Annotations)
+			}
+			if !reflect.DeepEqual(updated.Spec.Values, tc.expectedValues) {
+				t.Errorf("expected values %v, found %v", tc.expectedValues, updated.Spec.Values)
+			}
+			if _, ok := tc.projectHelmChart.Annotations[common.HelmProjectOperatorRollbackToRevisionAnnotation]; !ok {
+				t.Errorf("expected the ProjectHelmChart from the cache to not be modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/sirupsen/logrus"
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
+)
+
+// getLatestRelease returns the latest revision of the Helm release deployed on behalf of this ProjectHelmChart, if it exists
+func (h *handler) getLatestRelease(projectHelmChart *v1alpha1.ProjectHelmChart) (*rspb.Release, error) {
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	latestRelease, err := h.releaseGetter.Last(releaseNamespace, releaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return latestRelease, nil
+}
+
+// releaseMatchesValuesContent returns whether the provided revision of a Helm release was deployed with the provided values.yaml
+func releaseMatchesValuesContent(release *rspb.Release, valuesContent []byte) (bool, error) {
+	if release == nil || release.Info == nil {
+		return false, nil
+	}
+	values := map[string]interface{}{}
+	valuesJSON, err := yaml.YAMLToJSON(valuesContent)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(valuesJSON, &values); err != nil {
+		return false, err
+	}
+	return getValuesDigest(release.Config) == getValuesDigest(values), nil
+}
+
+// getValuesDigest returns a digest that identifies a set of values
+func getValuesDigest(values map[string]interface{}) string {
+	if len(values) == 0 {
+		values = map[string]interface{}{}
+	}
+	// note: encoding/json sorts map keys, so identical values always result in the same digest
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		// values are always parsed from YAML or JSON, so this should never happen
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(valuesJSON))
+}
+
+// getValuesContentToDeploy returns the values.yaml that should be deployed for this ProjectHelmChart, which will correspond to the
+// values of the LastDeployedRelease instead of the provided values.yaml if spec.rollbackOnFailure is enabled and spec.values failed to deploy.
+// It also returns whether the values are being rolled back.
+//
+// Note: this function also updates the LastDeployedRelease and FailedValuesDigest in the ProjectHelmChartStatus based on the latest revision of the release
+func (h *handler) getValuesContentToDeploy(projectHelmChart *v1alpha1.ProjectHelmChart, projectHelmChartStatus *v1alpha1.ProjectHelmChartStatus, projectID string, targetProjectNamespaces []string, valuesContent []byte) ([]byte, bool, error) {
+	specValuesDigest := getValuesDigest(projectHelmChart.Spec.Values)
+	if !projectHelmChart.Spec.RollbackOnFailure || projectHelmChartStatus.FailedValuesDigest != specValuesDigest {
+		// spec.values has been modified since the last failure, so the new values should be tried
+		projectHelmChartStatus.FailedValuesDigest = ""
+	}
+	lastDeployedRelease := projectHelmChartStatus.LastDeployedRelease
+	rollingBack := len(projectHelmChartStatus.FailedValuesDigest) > 0 && lastDeployedRelease != nil
+
+	var err error
+	if rollingBack {
+		valuesContent, err = h.getLastDeployedValuesContent(projectHelmChart, lastDeployedRelease, projectID, targetProjectNamespaces)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	latestRelease, err := h.getLatestRelease(projectHelmChart)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to get latest revision of release: %s", err)
+	}
+	matches, err := releaseMatchesValuesContent(latestRelease, valuesContent)
+	if err != nil {
+		return nil, false, err
+	}
+	if !matches {
+		// the latest revision of the release does not correspond to the values that are being deployed yet
+		return valuesContent, rollingBack, nil
+	}
+
+	switch latestRelease.Info.Status {
+	case rspb.StatusDeployed:
+		if rollingBack {
+			// the last deployed values were successfully re-applied
+			lastDeployedRelease = lastDeployedRelease.DeepCopy()
+			lastDeployedRelease.Revision = latestRelease.Version
+			projectHelmChartStatus.LastDeployedRelease = lastDeployedRelease
+			return valuesContent, rollingBack, nil
+		}
+		var values v1alpha1.GenericMap
+		projectHelmChart.Spec.Values.DeepCopyInto(&values)
+		projectHelmChartStatus.LastDeployedRelease = &v1alpha1.DeployedRelease{
+			Revision:     latestRelease.Version,
+			ValuesDigest: specValuesDigest,
+			Values:       values,
+		}
+	case rspb.StatusFailed:
+		if rollingBack || !projectHelmChart.Spec.RollbackOnFailure {
+			return valuesContent, rollingBack, nil
+		}
+		if lastDeployedRelease == nil || lastDeployedRelease.ValuesDigest == specValuesDigest {
+			// there are no other values to roll back to
+			return valuesContent, rollingBack, nil
+		}
+		logrus.Infof("Rolling back values of ProjectHelmChart %s/%s to revision %d since spec.values failed to deploy", projectHelmChart.Namespace, projectHelmChart.Name, lastDeployedRelease.Revision)
+		projectHelmChartStatus.FailedValuesDigest = specValuesDigest
+		valuesContent, err = h.getLastDeployedValuesContent(projectHelmChart, lastDeployedRelease, projectID, targetProjectNamespaces)
+		if err != nil {
+			return nil, false, err
+		}
+		return valuesContent, true, nil
+	}
+	return valuesContent, rollingBack, nil
+}
+
+// getLastDeployedValuesContent returns the values.yaml that should be deployed to roll back to the values of the LastDeployedRelease
+func (h *handler) getLastDeployedValuesContent(projectHelmChart *v1alpha1.ProjectHelmChart, lastDeployedRelease *v1alpha1.DeployedRelease, projectID string, targetProjectNamespaces []string) ([]byte, error) {
+	// note: default and required overrides are re-applied since the project's namespaces may have changed since the values were deployed
+	rollbackProjectHelmChart := projectHelmChart.DeepCopy()
+	rollbackProjectHelmChart.Spec.Values = lastDeployedRelease.Values
+	values := h.getValues(rollbackProjectHelmChart, projectID, targetProjectNamespaces)
+	valuesContent, err := values.ToYAML()
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshall values of last deployed revision %d: %s", lastDeployedRelease.Revision, err)
+	}
+	return valuesContent, nil
+}
+
+// getValuesDiff returns the paths of all values that differ between the two sets of values
+func getValuesDiff(oldValues, newValues map[string]interface{}) []string {
+	var diff []string
+	appendValuesDiff(&diff, "", oldValues, newValues)
+	sort.Strings(diff)
+	return diff
+}
+
+func appendValuesDiff(diff *[]string, prefix string, oldValues, newValues map[string]interface{}) {
+	keys := map[string]bool{}
+	for k := range oldValues {
+		keys[k] = true
+	}
+	for k := range newValues {
+		keys[k] = true
+	}
+	for k := range keys {
+		path := k
+		if len(prefix) > 0 {
+			path = fmt.Sprintf("%s.%s", prefix, k)
+		}
+		if oldMap, newMap, bothMaps := bothMaps(oldValues[k], newValues[k]); bothMaps {
+			appendValuesDiff(diff, path, oldMap, newMap)
+			continue
+		}
+		if !reflect.DeepEqual(oldValues[k], newValues[k]) {
+			*diff = append(*diff, path)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeHelmReleaseGetter is a HelmReleaseGetter that returns the provided release as the latest revision of every release
+type fakeHelmReleaseGetter struct {
+	latestRelease *rspb.Release
+}
+
+func (g *fakeHelmReleaseGetter) Last(_, _ string) (*rspb.Release, error) {
+	if g.latestRelease == nil {
+		return nil, driver.ErrReleaseNotFound
+	}
+	return g.latestRelease, nil
+}
+
+func TestGetValuesContentToDeploy(t *testing.T) {
+	projectID := "p-example"
+	targetProjectNamespaces := []string{"p-example-target"}
+	deployedValues := v1alpha1.GenericMap{"replicas": float64(1)}
+	failingValues := v1alpha1.GenericMap{"replicas": float64(2)}
+	newValues := v1alpha1.GenericMap{"replicas": float64(3)}
+
+	h := &handler{
+		opts: common.Options{
+			RuntimeOptions: common.RuntimeOptions{
+				DeploymentBackend: "helm-sdk",
+			},
+		},
+	}
+	newProjectHelmChart := func(values v1alpha1.GenericMap, rollbackOnFailure bool) *v1alpha1.ProjectHelmChart {
+		return &v1alpha1.ProjectHelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "project-monitoring",
+				Namespace: "cattle-project-p-example",
+			},
+			Spec: v1alpha1.ProjectHelmChartSpec{
+				Values:            values,
+				RollbackOnFailure: rollbackOnFailure,
+			},
+		}
+	}
+	// getValuesContent returns the values.yaml that the handler would deploy for the provided spec.values
+	getValuesContent := func(values v1alpha1.GenericMap) []byte {
+		fullValues := h.getValues(newProjectHelmChart(values, true), projectID, targetProjectNamespaces)
+		valuesContent, err := fullValues.ToYAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return valuesContent
+	}
+	newRelease := func(version int, status rspb.Status, values v1alpha1.GenericMap) *rspb.Release {
+		return &rspb.Release{
+			Version: version,
+			Info:    &rspb.Info{Status: status},
+			Config:  h.getValues(newProjectHelmChart(values, true), projectID, targetProjectNamespaces),
+		}
+	}
+	lastDeployedRelease := &v1alpha1.DeployedRelease{
+		Revision:     1,
+		ValuesDigest: getValuesDigest(deployedValues),
+		Values:       deployedValues,
+	}
+
+	testCases := []struct {
+		name                        string
+		projectHelmChart            *v1alpha1.ProjectHelmChart
+		status                      v1alpha1.ProjectHelmChartStatus
+		latestRelease               *rspb.Release
+		expectedValues              v1alpha1.GenericMap
+		expectedRolledBack          bool
+		expectedLastDeployedRelease *v1alpha1.DeployedRelease
+		expectedFailedValuesDigest  string
+	}{
+		{
+			name:             "first install has no last deployed state",
+			projectHelmChart: newProjectHelmChart(deployedValues, true),
+			expectedValues:   deployedValues,
+		},
+		{
+			name:             "first install fails without any values to roll back to",
+			projectHelmChart: newProjectHelmChart(failingValues, true),
+			latestRelease:    newRelease(1, rspb.StatusFailed, failingValues),
+			expectedValues:   failingValues,
+		},
+		{
+			name:                        "deployed values are recorded",
+			projectHelmChart:            newProjectHelmChart(deployedValues, true),
+			latestRelease:               newRelease(1, rspb.StatusDeployed, deployedValues),
+			expectedValues:              deployedValues,
+			expectedLastDeployedRelease: lastDeployedRelease,
+		},
+		{
+			name:                        "failed values fall back to the last deployed values",
+			projectHelmChart:            newProjectHelmChart(failingValues, true),
+			status:                      v1alpha1.ProjectHelmChartStatus{LastDeployedRelease: lastDeployedRelease},
+			latestRelease:               newRelease(2, rspb.StatusFailed, failingValues),
+			expectedValues:              deployedValues,
+			expectedRolledBack:          true,
+			expectedLastDeployedRelease: lastDeployedRelease,
+			expectedFailedValuesDigest:  getValuesDigest(failingValues),
+		},
+		{
+			name:             "failed values are not rolled back without rollbackOnFailure",
+			projectHelmChart: newProjectHelmChart(failingValues, false),
+			status: v1alpha1.ProjectHelmChartStatus{
+				LastDeployedRelease: lastDeployedRelease,
+				FailedValuesDigest:  getValuesDigest(failingValues),
+			},
+			latestRelease:               newRelease(2, rspb.StatusFailed, failingValues),
+			expectedValues:              failingValues,
+			expectedLastDeployedRelease: lastDeployedRelease,
+		},
+		{
+			name:             "rolled back values are recorded once deployed",
+			projectHelmChart: newProjectHelmChart(failingValues, true),
+			status: v1alpha1.ProjectHelmChartStatus{
+				LastDeployedRelease: lastDeployedRelease,
+				FailedValuesDigest:  getValuesDigest(failingValues),
+			},
+			latestRelease:      newRelease(3, rspb.StatusDeployed, deployedValues),
+			expectedValues:     deployedValues,
+			expectedRolledBack: true,
+			expectedLastDeployedRelease: &v1alpha1.DeployedRelease{
+				Revision:     3,
+				ValuesDigest: lastDeployedRelease.ValuesDigest,
+				Values:       deployedValues,
+			},
+			expectedFailedValuesDigest: getValuesDigest(failingValues),
+		},
+		{
+			name:             "failure is cleared once spec.values changes",
+			projectHelmChart: newProjectHelmChart(newValues, true),
+			status: v1alpha1.ProjectHelmChartStatus{
+				LastDeployedRelease: lastDeployedRelease,
+				FailedValuesDigest:  getValuesDigest(failingValues),
+			},
+			latestRelease:               newRelease(3, rspb.StatusDeployed, deployedValues),
+			expectedValues:              newValues,
+			expectedLastDeployedRelease: lastDeployedRelease,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h.releaseGetter = &fakeHelmReleaseGetter{latestRelease: tc.latestRelease}
+			status := tc.status
+			valuesContent, rolledBack, err := h.getValuesContentToDeploy(tc.projectHelmChart, &status, projectID, targetProjectNamespaces, getValuesContent(tc.projectHelmChart.Spec.Values))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expectedValuesContent := getValuesContent(tc.expectedValues); string(valuesContent) != string(expectedValuesContent) {
+				t.Errorf("expected values content:\n%s\nfound:\n%s", expectedValuesContent, valuesContent)
+			}
+			if rolledBack != tc.expectedRolledBack {
+				t.Errorf("expected rolled back to be %t, found %t", tc.expectedRolledBack, rolledBack)
+			}
+			if !reflect.DeepEqual(status.LastDeployedRelease, tc.expectedLastDeployedRelease) {
+				t.Errorf("expected last deployed release %v, found %v", tc.expectedLastDeployedRelease, status.LastDeployedRelease)
+			}
+			if status.FailedValuesDigest != tc.expectedFailedValuesDigest {
+				t.Errorf("expected failed values digest %q, found %q", tc.expectedFailedValuesDigest, status.FailedValuesDigest)
+			}
+		})
+	}
+}
+
+func TestReleaseMatchesValuesContent(t *testing.T) {
+	testCases := []struct {
+		name          string
+		release       *rspb.Release
+		valuesContent string
+		matches       bool
+		expectErr     bool
+	}{
+		{
+			name:          "no release",
+			valuesContent: "replicas: 1\n",
+		},
+		{
+			name:          "release without info",
+			release:       &rspb.Release{Config: map[string]interface{}{"replicas": 1}},
+			valuesContent: "replicas: 1\n",
+		},
+		{
+			name:          "matching values",
+			release:       &rspb.Release{Info: &rspb.Info{}, Config: map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "v1"}}},
+			valuesContent: "image:\n  tag: v1\nreplicas: 1\n",
+			matches:       true,
+		},
+		{
+			name:          "empty values",
+			release:       &rspb.Release{Info: &rspb.Info{}},
+			valuesContent: "{}\n",
+			matches:       true,
+		},
+		{
+			name:          "different values",
+			release:       &rspb.Release{Info: &rspb.Info{}, Config: map[string]interface{}{"replicas": 1}},
+			valuesContent: "replicas: 2\n",
+		},
+		{
+			name:          "invalid values",
+			release:       &rspb.Release{Info: &rspb.Info{}},
+			valuesContent: "replicas: [\n",
+			expectErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := releaseMatchesValuesContent(tc.release, []byte(tc.valuesContent))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+			if matches != tc.matches {
+				t.Errorf("expected values to match: %t, found %t", tc.matches, matches)
+			}
+		})
+	}
+}
+
+func TestGetValuesDiff(t *testing.T) {
+	testCases := []struct {
+		name      string
+		oldValues map[string]interface{}
+		newValues map[string]interface{}
+		expected  []string
+	}{
+		{
+			name:      "identical values",
+			oldValues: map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "v1"}},
+			newValues: map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "v1"}},
+		},
+		{
+			name:      "modified nested value",
+			oldValues: map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"repository": "example", "tag": "v1"}},
+			newValues: map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"repository": "example", "tag": "v2"}},
+			expected:  []string{"image.tag"},
+		},
+		{
+			name:      "added and removed values",
+			oldValues: map[string]interface{}{"replicas": 1},
+			newValues: map[string]interface{}{"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}}},
+			expected:  []string{"replicas", "resources"},
+		},
+		{
+			name:      "value replaced by a map",
+			oldValues: map[string]interface{}{"image": "example:v1"},
+			newValues: map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}},
+			expected:  []string{"image"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := getValuesDiff(tc.oldValues, tc.newValues)
+			if !reflect.DeepEqual(diff, tc.expected) {
+				t.Errorf("expected diff %v, found %v", tc.expected, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"fmt"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Note: if SharedProjectReleaseNamespace is provided, the Project Release Namespace is shared by all ProjectHelmCharts in a project
+// across all Project Operators. To ensure that ProjectHelmCharts do not fight over the ownership of the namespace, it is never applied
+// as part of the apply set of a ProjectHelmChart; instead, it is applied under a fixed set ID without an owner and is only orphaned
+// once no ProjectHelmChart in the Project Registration Namespace uses it anymore.
+
+const (
+	// sharedProjectReleaseNamespaceSetID is the set ID that shared Project Release Namespaces are applied under by all Project Operators
+	sharedProjectReleaseNamespaceSetID = "shared-project-release-namespace-applier"
+)
+
+// isSharedProjectReleaseNamespace returns whether the release of this ProjectHelmChart is deployed into a shared Project Release Namespace
+func (h *handler) isSharedProjectReleaseNamespace(projectHelmChart *v1alpha1.ProjectHelmChart) bool {
+	return h.opts.SharedProjectReleaseNamespace && h.isOperatorManagedReleaseNamespace(projectHelmChart)
+}
+
+// applySharedProjectReleaseNamespace applies the shared Project Release Namespace used by this ProjectHelmChart
+func (h *handler) applySharedProjectReleaseNamespace(projectID string, isOrphaned bool, projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	projectReleaseNamespace := h.getProjectReleaseNamespace(projectID, isOrphaned, projectHelmChart)
+	if err := h.apply.WithSetID(sharedProjectReleaseNamespaceSetID).ApplyObjects(projectReleaseNamespace); err != nil {
+		return fmt.Errorf("unable to apply shared project release namespace %s: %s", projectReleaseNamespace.Name, err)
+	}
+	return nil
+}
+
+// isSharedProjectReleaseNamespaceInUse returns whether any ProjectHelmChart other than this one still uses the shared Project Release Namespace
+//
+// Note: the ProjectHelmChart cache contains ProjectHelmCharts from all Project Operators, all of which share the same Project Release Namespace
+// since it is only rendered from the Project Registration Namespace
+func (h *handler) isSharedProjectReleaseNamespaceInUse(projectHelmChart *v1alpha1.ProjectHelmChart) (bool, error) {
+	projectHelmCharts, err := h.projectHelmChartCache.List(projectHelmChart.Namespace, labels.Everything())
+	if err != nil {
+		return false, fmt.Errorf("unable to list ProjectHelmCharts in %s: %s", projectHelmChart.Namespace, err)
+	}
+	for _, otherProjectHelmChart := range projectHelmCharts {
+		if otherProjectHelmChart == nil || otherProjectHelmChart.Name == projectHelmChart.Name {
+			continue
+		}
+		if otherProjectHelmChart.DeletionTimestamp != nil || common.HasCleanupLabel(otherProjectHelmChart) {
+			continue
+		}
+		if otherProjectHelmChart.Status.Status == noTargetProjectNamespacesStatus {
+			// the ProjectHelmChart does not deploy a release
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// shouldOrphanProjectReleaseNamespace returns whether the Project Release Namespace of this ProjectHelmChart should be marked as orphaned once
+// this ProjectHelmChart no longer uses it, which is only the case once no other ProjectHelmChart is using a shared Project Release Namespace
+func (h *handler) shouldOrphanProjectReleaseNamespace(projectHelmChart *v1alpha1.ProjectHelmChart) (bool, error) {
+	if !h.isSharedProjectReleaseNamespace(projectHelmChart) {
+		return true, nil
+	}
+	inUse, err := h.isSharedProjectReleaseNamespaceInUse(projectHelmChart)
+	if err != nil {
+		return false, err
+	}
+	return !inUse, nil
+}
@@ -0,0 +1,110 @@
+package project
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	helmprojectcontroller "github.com/rancher/helm-project-operator/pkg/generated/controllers/helm.cattle.io/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// fakeProjectHelmChartCache is a ProjectHelmChartCache backed by the provided ProjectHelmCharts that supports a single indexer
+type fakeProjectHelmChartCache struct {
+	helmprojectcontroller.ProjectHelmChartCache
+
+	projectHelmCharts []*v1alpha1.ProjectHelmChart
+	indexer           func(projectHelmChart *v1alpha1.ProjectHelmChart) ([]string, error)
+}
+
+func (c *fakeProjectHelmChartCache) Get(namespace, name string) (*v1alpha1.ProjectHelmChart, error) {
+	for _, projectHelmChart := range c.projectHelmCharts {
+		if projectHelmChart.Namespace == namespace && projectHelmChart.Name == name {
+			return projectHelmChart, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("projecthelmcharts"), name)
+}
+
+func (c *fakeProjectHelmChartCache) List(namespace string, selector labels.Selector) ([]*v1alpha1.ProjectHelmChart, error) {
+	var projectHelmCharts []*v1alpha1.ProjectHelmChart
+	for _, projectHelmChart := range c.projectHelmCharts {
+		if projectHelmChart.Namespace == namespace && selector.Matches(labels.Set(projectHelmChart.Labels)) {
+			projectHelmCharts = append(projectHelmCharts, projectHelmChart)
+		}
+	}
+	return projectHelmCharts, nil
+}
+
+func (c *fakeProjectHelmChartCache) GetByIndex(_, key string) ([]*v1alpha1.ProjectHelmChart, error) {
+	var projectHelmCharts []*v1alpha1.ProjectHelmChart
+	for _, projectHelmChart := range c.projectHelmCharts {
+		indices, err := c.indexer(projectHelmChart)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index == key {
+				projectHelmCharts = append(projectHelmCharts, projectHelmChart)
+				break
+			}
+		}
+	}
+	return projectHelmCharts, nil
+}
+
+func TestIsSharedProjectReleaseNamespaceInUse(t *testing.T) {
+	newProjectHelmChart := func(name string) *v1alpha1.ProjectHelmChart {
+		return &v1alpha1.ProjectHelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "cattle-project-p-example",
+			},
+		}
+	}
+	projectHelmChart := newProjectHelmChart("project-monitoring")
+	deleting := newProjectHelmChart("deleting")
+	deleting.DeletionTimestamp = &metav1.Time{}
+	cleanup := newProjectHelmChart("cleanup")
+	cleanup.Labels = map[string]string{common.HelmProjectOperatedCleanupLabel: "true"}
+	noTargets := newProjectHelmChart("no-targets")
+	noTargets.Status.Status = noTargetProjectNamespacesStatus
+	otherNamespace := newProjectHelmChart("other-namespace")
+	otherNamespace.Namespace = "cattle-project-p-other"
+
+	testCases := []struct {
+		name              string
+		projectHelmCharts []*v1alpha1.ProjectHelmChart
+		inUse             bool
+	}{
+		{
+			name:              "only this ProjectHelmChart",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{projectHelmChart},
+		},
+		{
+			name:              "other ProjectHelmCharts that do not deploy a release",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{projectHelmChart, deleting, cleanup, noTargets, otherNamespace},
+		},
+		{
+			name:              "another ProjectHelmChart that deploys a release",
+			projectHelmCharts: []*v1alpha1.ProjectHelmChart{projectHelmChart, newProjectHelmChart("project-logging")},
+			inUse:             true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				projectHelmChartCache: &fakeProjectHelmChartCache{projectHelmCharts: tc.projectHelmCharts},
+			}
+			inUse, err := h.isSharedProjectReleaseNamespaceInUse(projectHelmChart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inUse != tc.inUse {
+				t.Errorf("expected in use to be %t, found %t", tc.inUse, inUse)
+			}
+		})
+	}
+}
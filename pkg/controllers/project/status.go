@@ -2,11 +2,18 @@ package project
 
 import (
 	"fmt"
+	"strings"
 
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
 )
 
+const (
+	// noTargetProjectNamespacesStatus is the status of a ProjectHelmChart that does not deploy a release since it has no target namespaces
+	noTargetProjectNamespacesStatus = "NoTargetProjectNamespaces"
+)
+
 // getCleanupStatus returns the status on seeing the cleanup label on a ProjectHelmChart
 func (h *handler) getCleanupStatus(projectHelmChart *v1alpha1.ProjectHelmChart, _ v1alpha1.ProjectHelmChartStatus) v1alpha1.ProjectHelmChartStatus {
 	return v1alpha1.ProjectHelmChartStatus{
@@ -36,11 +43,19 @@ func (h *handler) getUnableToCreateHelmReleaseStatus(projectHelmChart *v1alpha1.
 // the Project Registration Namespace's namespaceSelector) targets no namespaces
 func (h *handler) getNoTargetNamespacesStatus(_ *v1alpha1.ProjectHelmChart, _ v1alpha1.ProjectHelmChartStatus) v1alpha1.ProjectHelmChartStatus {
 	return v1alpha1.ProjectHelmChartStatus{
-		Status:        "NoTargetProjectNamespaces",
+		Status:        noTargetProjectNamespacesStatus,
 		StatusMessage: "There are no project namespaces to deploy a ProjectHelmChart.",
 	}
 }
 
+// getInvalidReleaseNamespaceStatus returns the status on seeing a spec.releaseNamespace on the ProjectHelmChart that is not within its project
+func (h *handler) getInvalidReleaseNamespaceStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, err error) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status if possible
+	projectHelmChartStatus.Status = "InvalidReleaseNamespace"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Unable to deploy release into provided spec.releaseNamespace: %s", err)
+	return projectHelmChartStatus
+}
+
 // getValuesParseErrorStatus returns the status on encountering an error with parsing the provided contents of spec.values on the ProjectHelmChart
 func (h *handler) getValuesParseErrorStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, err error) v1alpha1.ProjectHelmChartStatus {
 	// retain existing status if possible
@@ -49,6 +64,48 @@ func (h *handler) getValuesParseErrorStatus(_ *v1alpha1.ProjectHelmChart, projec
 	return projectHelmChartStatus
 }
 
+// getHelmOptionsErrorStatus returns the status on encountering spec.helmOptions on the ProjectHelmChart that are invalid or not allowed by the operator
+func (h *handler) getHelmOptionsErrorStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, err error) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status if possible
+	projectHelmChartStatus.Status = "UnableToApplyHelmOptions"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Unable to apply provided spec.helmOptions to ProjectHelmChart: %s", err)
+	return projectHelmChartStatus
+}
+
+// getReleaseNamespaceResourcesErrorStatus returns the status on encountering spec.releaseNamespaceResources on the ProjectHelmChart that are
+// outside of the bounds configured by the operator
+func (h *handler) getReleaseNamespaceResourcesErrorStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, err error) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status if possible
+	projectHelmChartStatus.Status = "UnableToApplyReleaseNamespaceResources"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Unable to apply provided spec.releaseNamespaceResources to ProjectHelmChart: %s", err)
+	return projectHelmChartStatus
+}
+
+// getReleaseNamespaceMetadataErrorStatus returns the status on encountering spec.releaseNamespaceMetadata on the ProjectHelmChart that cannot be applied
+func (h *handler) getReleaseNamespaceMetadataErrorStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, err error) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status if possible
+	projectHelmChartStatus.Status = "UnableToApplyReleaseNamespaceMetadata"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Unable to apply provided spec.releaseNamespaceMetadata to ProjectHelmChart: %s", err)
+	return projectHelmChartStatus
+}
+
+// getDeployingReleaseStatus returns the transitionary status that occurs while the deployment backend is performing a Helm operation on the release
+func (h *handler) getDeployingReleaseStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, releaseStatus backend.ReleaseStatus) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status
+	projectHelmChartStatus.Status = "DeployingRelease"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Helm release is being deployed by the operator (%s): %s", releaseStatus.State, releaseStatus.Message)
+	return projectHelmChartStatus
+}
+
+// getUnableToDeployReleaseStatus returns the status on the deployment backend failing to perform a Helm operation on the release
+// The operation will not be retried until the ProjectHelmChart or the values provided to the release are modified
+func (h *handler) getUnableToDeployReleaseStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus, releaseStatus backend.ReleaseStatus) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status
+	projectHelmChartStatus.Status = "UnableToDeployRelease"
+	projectHelmChartStatus.StatusMessage = fmt.Sprintf("Unable to deploy Helm release: %s", releaseStatus.Message)
+	return projectHelmChartStatus
+}
+
 // getWaitingForDashboardValuesStatus returns the transitionary status that occurs after deploying a Helm chart but before a dashboard configmap is created
 // If a ProjectHelmChart is stuck in this status, it is likely either an error on the Operator for not creating this ConfigMap or there might be an issue
 // with the underlying Job ran by the child HelmChart resource created on this ProjectHelmChart's behalf
@@ -60,6 +117,22 @@ func (h *handler) getWaitingForDashboardValuesStatus(_ *v1alpha1.ProjectHelmChar
 	return projectHelmChartStatus
 }
 
+// getRolledBackStatus returns the status that indicates the values in spec.values failed to deploy, so the values of the last deployed release were re-applied
+// The values in spec.values will not be retried until they are modified
+func (h *handler) getRolledBackStatus(projectHelmChart *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus) v1alpha1.ProjectHelmChartStatus {
+	// retain existing status
+	var diff []string
+	if projectHelmChartStatus.LastDeployedRelease != nil {
+		diff = getValuesDiff(projectHelmChartStatus.LastDeployedRelease.Values, projectHelmChart.Spec.Values)
+		projectHelmChartStatus.StatusMessage = fmt.Sprintf(
+			"Provided spec.values failed to deploy, so the values of revision %d were re-applied. The provided values will not be retried until spec.values is modified. Failed values differ in: %s",
+			projectHelmChartStatus.LastDeployedRelease.Revision, strings.Join(diff, ", "),
+		)
+	}
+	projectHelmChartStatus.Status = "RolledBack"
+	return projectHelmChartStatus
+}
+
 // getDeployedStatus returns the status that indicates the ProjectHelmChart is successfully deployed
 func (h *handler) getDeployedStatus(_ *v1alpha1.ProjectHelmChart, projectHelmChartStatus v1alpha1.ProjectHelmChartStatus) v1alpha1.ProjectHelmChartStatus {
 	// retain existing status
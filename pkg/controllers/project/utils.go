@@ -2,13 +2,31 @@ package project
 
 import (
 	"fmt"
+	"slices"
 
 	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/backend"
 	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/helm-project-operator/pkg/naming"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // getProjectID returns the projectID tied to this ProjectHelmChart
 func (h *handler) getProjectID(projectHelmChart *v1alpha1.ProjectHelmChart) (string, error) {
+	if h.opts.ProjectCRD {
+		// use the name of the Project that the project registration namespace was created for as the projectID
+		projectRegistrationNamespace, err := h.namespaceCache.Get(projectHelmChart.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse projectID for projectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+		}
+		return projectRegistrationNamespace.Labels[common.HelmProjectOperatorProjectLabel], nil
+	}
 	if len(h.opts.ProjectLabel) == 0 {
 		// use the projectHelmChart's name as the projectID
 		return projectHelmChart.Name, nil
@@ -17,15 +35,34 @@ func (h *handler) getProjectID(projectHelmChart *v1alpha1.ProjectHelmChart) (str
 	if err != nil {
 		return "", fmt.Errorf("unable to parse projectID for projectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
 	}
-	projectID, ok := projectRegistrationNamespace.Labels[h.opts.ProjectLabel]
+	// the project registration namespace may belong to any level of the project hierarchy
+	_, projectID, ok := h.opts.GetProjectLabelAndID(projectRegistrationNamespace.Labels)
 	if !ok {
 		return "", nil
 	}
 	return projectID, nil
 }
 
+// getProjectLabel returns the label that identifies the level of the project hierarchy that this ProjectHelmChart belongs to
+// If the level cannot be identified, this returns the ProjectLabel
+func (h *handler) getProjectLabel(projectHelmChart *v1alpha1.ProjectHelmChart) string {
+	projectRegistrationNamespace, err := h.namespaceCache.Get(projectHelmChart.Namespace)
+	if err != nil {
+		return h.opts.ProjectLabel
+	}
+	projectLabel, _, ok := h.opts.GetProjectLabelAndID(projectRegistrationNamespace.Labels)
+	if !ok {
+		return h.opts.ProjectLabel
+	}
+	return projectLabel
+}
+
 // getProjectNamespaceSelector returns the projectNamespaceSelector tied to this ProjectHelmChart
-func (h *handler) getProjectNamespaceSelector(projectHelmChart *v1alpha1.ProjectHelmChart, projectID string) map[string]interface{} {
+func (h *handler) getProjectNamespaceSelector(projectHelmChart *v1alpha1.ProjectHelmChart, projectID string, targetProjectNamespaces []string) map[string]interface{} {
+	if h.opts.ProjectCRD {
+		// Project namespaces are not guaranteed to share any labels, so select the target namespaces by name
+		return getNamespaceNameSelector(targetProjectNamespaces)
+	}
 	if len(h.opts.ProjectLabel) == 0 {
 		// Use the projectHelmChart selector as the namespaceSelector
 		if projectHelmChart.Spec.ProjectNamespaceSelector == nil {
@@ -36,14 +73,20 @@ func (h *handler) getProjectNamespaceSelector(projectHelmChart *v1alpha1.Project
 			"matchExpressions": projectHelmChart.Spec.ProjectNamespaceSelector.MatchExpressions,
 		}
 	}
+	projectLabel := h.getProjectLabel(projectHelmChart)
 	if len(h.opts.ProjectReleaseLabelValue) == 0 {
 		// Release namespace is not created, so use namespaceSelector provided tied to projectID
 		return map[string]interface{}{
 			"matchLabels": map[string]string{
-				h.opts.ProjectLabel: projectID,
+				projectLabel: projectID,
 			},
 		}
 	}
+	if projectLabel != h.opts.ProjectLabel {
+		// project namespaces are only marked with the HelmProjectOperatorProjectLabel of their innermost project, so select
+		// the namespaces of a group of projects (along with the release namespace) by name
+		return getNamespaceNameSelector(targetProjectNamespaces)
+	}
 	// use the HelmProjectOperated label
 	return map[string]interface{}{
 		"matchLabels": map[string]string{
@@ -52,19 +95,289 @@ func (h *handler) getProjectNamespaceSelector(projectHelmChart *v1alpha1.Project
 	}
 }
 
+// getNamespaceNameSelector returns a namespaceSelector that selects the provided namespaces by name
+func getNamespaceNameSelector(namespaces []string) map[string]interface{} {
+	return map[string]interface{}{
+		"matchExpressions": []metav1.LabelSelectorRequirement{
+			{
+				Key:      corev1.LabelMetadataName,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   namespaces,
+			},
+		},
+	}
+}
+
 // getReleaseNamespaceAndName returns the name of the Project Release namespace and the name of the Helm Release
 // that will be deployed into the Project Release namespace on behalf of the ProjectHelmChart
+//
+// Note: generated names that are too long or not valid DNS-1123 labels are truncated with a stable hash suffix; the
+// original names are recorded in the annotations of the generated resources (see getOriginalReleaseNamespaceAndName)
 func (h *handler) getReleaseNamespaceAndName(projectHelmChart *v1alpha1.ProjectHelmChart) (string, string) {
-	projectReleaseName := fmt.Sprintf("%s-%s", projectHelmChart.Name, h.opts.ReleaseName)
-	if h.opts.Singleton {
-		// This changes the naming scheme of the deployed resources such that only one can every be created per namespace
-		projectReleaseName = fmt.Sprintf("%s-%s", projectHelmChart.Namespace, h.opts.ReleaseName)
+	releaseName := naming.SafeName(h.getOriginalReleaseName(projectHelmChart), h.getMaxReleaseNameLength())
+	if len(projectHelmChart.Spec.ReleaseNamespace) > 0 {
+		// Underlying Helm releases will be created in the existing namespace provided by the user
+		return projectHelmChart.Spec.ReleaseNamespace, releaseName
 	}
 	if len(h.opts.ProjectLabel) == 0 || len(h.opts.ProjectReleaseLabelValue) == 0 {
 		// Underlying Helm releases will be created in the namespace where the ProjectHelmChart is registered (project registration namespace)
 		// The project registration namespace will either be the system namespace or auto-generated namespaces depending on the user values provided
-		return projectHelmChart.Namespace, projectReleaseName
+		return projectHelmChart.Namespace, releaseName
+	}
+	// Underlying Helm releases will be created in project release namespaces, which are either dedicated to the release or shared by the project
+	_, releaseNamespace := h.getProjectReleaseNamespaceName(projectHelmChart, releaseName)
+	return releaseNamespace, releaseName
+}
+
+// getOriginalReleaseNamespaceAndName returns the name of the Project Release namespace and the name of the Helm Release
+// before any truncation is applied to ensure that they are valid
+func (h *handler) getOriginalReleaseNamespaceAndName(projectHelmChart *v1alpha1.ProjectHelmChart) (string, string) {
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	if len(projectHelmChart.Spec.ReleaseNamespace) == 0 && len(h.opts.ProjectLabel) > 0 && len(h.opts.ProjectReleaseLabelValue) > 0 {
+		releaseNamespace, _ = h.getProjectReleaseNamespaceName(projectHelmChart, releaseName)
+	}
+	return releaseNamespace, h.getOriginalReleaseName(projectHelmChart)
+}
+
+// getOriginalReleaseName returns the name of the Helm Release before any truncation is applied to ensure that it is valid
+func (h *handler) getOriginalReleaseName(projectHelmChart *v1alpha1.ProjectHelmChart) string {
+	if h.opts.Singleton {
+		// This changes the naming scheme of the deployed resources such that only one can every be created per namespace
+		return fmt.Sprintf("%s-%s", projectHelmChart.Namespace, h.opts.ReleaseName)
+	}
+	return fmt.Sprintf("%s-%s", projectHelmChart.Name, h.opts.ReleaseName)
+}
+
+// getProjectReleaseNamespaceName returns the original name rendered by the naming template for the Project Release Namespace of this
+// ProjectHelmChart along with the name that is actually used for the namespace, which is truncated if the original name is too long or invalid
+//
+// Note: if a namespace created by the operator for this ProjectHelmChart based on a legacy template already exists, it will continue to be used
+func (h *handler) getProjectReleaseNamespaceName(projectHelmChart *v1alpha1.ProjectHelmChart, releaseName string) (string, string) {
+	data := naming.ProjectReleaseNamespaceData{
+		ProjectRegistrationNamespace: projectHelmChart.Namespace,
+	}
+	if !h.opts.SharedProjectReleaseNamespace {
+		data.ProjectHelmChart = projectHelmChart.Name
+		data.ReleaseName = releaseName
+	}
+	for _, legacyTemplate := range h.opts.LegacyProjectReleaseNamespaceTemplates {
+		originalName, err := naming.Render(legacyTemplate, data)
+		if err != nil {
+			logrus.Errorf("unable to render legacy project release namespace for ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+			continue
+		}
+		name := naming.SafeName(originalName, naming.MaxNamespaceNameLength)
+		legacyNamespace, err := h.namespaceCache.Get(name)
+		if err != nil || legacyNamespace.DeletionTimestamp != nil || !h.isLegacyProjectReleaseNamespace(legacyNamespace, projectHelmChart) {
+			continue
+		}
+		return originalName, name
+	}
+	originalName, err := naming.Render(h.opts.GetProjectReleaseNamespaceTemplate(), data)
+	if err != nil {
+		// this should never happen since templates are validated on startup
+		logrus.Errorf("unable to render project release namespace for ProjectHelmChart %s/%s: %s", projectHelmChart.Namespace, projectHelmChart.Name, err)
+		originalName = fmt.Sprintf("%s-%s", projectHelmChart.Namespace, releaseName)
+	}
+	return originalName, naming.SafeName(originalName, naming.MaxNamespaceNameLength)
+}
+
+// isLegacyProjectReleaseNamespace returns whether an existing namespace rendered by a legacy template was created by the operator
+// as the Project Release Namespace of this ProjectHelmChart, as opposed to a namespace that belongs to another project or ProjectHelmChart
+//
+// Note: a shared Project Release Namespace is not owned by any ProjectHelmChart, so only its project is checked
+func (h *handler) isLegacyProjectReleaseNamespace(namespace *corev1.Namespace, projectHelmChart *v1alpha1.ProjectHelmChart) bool {
+	if !common.HasHelmProjectOperatedLabel(namespace.Labels) {
+		return false
+	}
+	projectID, err := h.getProjectID(projectHelmChart)
+	if err != nil || len(projectID) == 0 || namespace.Labels[common.HelmProjectOperatorProjectLabel] != projectID {
+		return false
+	}
+	if h.opts.SharedProjectReleaseNamespace {
+		return true
+	}
+	return namespace.Annotations[apply.LabelNamespace] == projectHelmChart.Namespace && namespace.Annotations[apply.LabelName] == projectHelmChart.Name
+}
+
+// getMaxReleaseNameLength returns the maximum length of the name of a Helm release deployed by the configured DeploymentBackend
+func (h *handler) getMaxReleaseNameLength() int {
+	switch h.opts.DeploymentBackend {
+	case "", backend.HelmControllerBackend:
+		// the HelmChart created for the release is named after the release
+		return naming.MaxHelmChartNameLength
+	default:
+		return naming.MaxReleaseNameLength
+	}
+}
+
+// addOriginalReleaseName records the original name of the release on the objects returned by the DeploymentBackend if it had to be truncated
+func (h *handler) addOriginalReleaseName(projectHelmChart *v1alpha1.ProjectHelmChart, objs []runtime.Object) error {
+	_, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	_, originalReleaseName := h.getOriginalReleaseNamespaceAndName(projectHelmChart)
+	if releaseName == originalReleaseName {
+		return nil
+	}
+	for _, obj := range objs {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		objMeta.SetAnnotations(common.AddOriginalNameAnnotation(objMeta.GetAnnotations(), originalReleaseName, releaseName))
+	}
+	return nil
+}
+
+// isOperatorManagedReleaseNamespace returns whether the release namespace of this ProjectHelmChart is a dedicated Project Release Namespace
+// that is created and managed by the operator
+func (h *handler) isOperatorManagedReleaseNamespace(projectHelmChart *v1alpha1.ProjectHelmChart) bool {
+	if len(projectHelmChart.Spec.ReleaseNamespace) > 0 {
+		// the operator never manages namespaces provided by users
+		return false
+	}
+	releaseNamespace, _ := h.getReleaseNamespaceAndName(projectHelmChart)
+	return releaseNamespace != h.systemNamespace && releaseNamespace != projectHelmChart.Namespace
+}
+
+// validateReleaseNamespace ensures that the spec.releaseNamespace provided on the ProjectHelmChart, if any, is one of its target namespaces
+func (h *handler) validateReleaseNamespace(projectHelmChart *v1alpha1.ProjectHelmChart, targetProjectNamespaces []string) error {
+	releaseNamespace := projectHelmChart.Spec.ReleaseNamespace
+	if len(releaseNamespace) == 0 {
+		return nil
+	}
+	if !slices.Contains(targetProjectNamespaces, releaseNamespace) {
+		return fmt.Errorf("spec.releaseNamespace %s is not a namespace within the project targeted by this ProjectHelmChart", releaseNamespace)
+	}
+	return nil
+}
+
+// validateHelmOptions ensures that the spec.helmOptions provided on the ProjectHelmChart only configure options allowed by the operator
+func (h *handler) validateHelmOptions(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	helmOptions := projectHelmChart.Spec.HelmOptions
+	if helmOptions == nil {
+		return nil
+	}
+	var configuredOptions []string
+	if helmOptions.Timeout != nil {
+		configuredOptions = append(configuredOptions, backend.HelmOptionTimeout)
+	}
+	if helmOptions.Atomic {
+		configuredOptions = append(configuredOptions, backend.HelmOptionAtomic)
+	}
+	if helmOptions.Wait {
+		configuredOptions = append(configuredOptions, backend.HelmOptionWait)
+	}
+	if len(helmOptions.FailurePolicy) > 0 {
+		configuredOptions = append(configuredOptions, backend.HelmOptionFailurePolicy)
+	}
+	if helmOptions.BackoffLimit != nil {
+		configuredOptions = append(configuredOptions, backend.HelmOptionBackoffLimit)
+	}
+	if len(helmOptions.NodeSelector) > 0 {
+		configuredOptions = append(configuredOptions, backend.HelmOptionNodeSelector)
+	}
+	if len(helmOptions.Tolerations) > 0 {
+		configuredOptions = append(configuredOptions, backend.HelmOptionTolerations)
+	}
+	for _, configuredOption := range configuredOptions {
+		if !slices.Contains(h.opts.AllowedHelmOptions, configuredOption) {
+			return fmt.Errorf("spec.helmOptions.%s is not allowed by the operator", configuredOption)
+		}
+	}
+	if helmOptions.Timeout != nil && helmOptions.Timeout.Duration <= 0 {
+		return fmt.Errorf("spec.helmOptions.timeout must be greater than 0")
+	}
+	if helmOptions.BackoffLimit != nil && *helmOptions.BackoffLimit < 0 {
+		return fmt.Errorf("spec.helmOptions.backoffLimit must not be negative")
+	}
+	switch helmOptions.FailurePolicy {
+	case "", backend.FailurePolicyAbort, backend.FailurePolicyReinstall:
+	default:
+		return fmt.Errorf("invalid spec.helmOptions.failurePolicy %s: must be one of %s or %s", helmOptions.FailurePolicy, backend.FailurePolicyAbort, backend.FailurePolicyReinstall)
+	}
+	return nil
+}
+
+// validateReleaseNamespaceResources ensures that the spec.releaseNamespaceResources provided on the ProjectHelmChart are within the bounds
+// configured by the operator
+func (h *handler) validateReleaseNamespaceResources(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	releaseNamespaceResources := projectHelmChart.Spec.ReleaseNamespaceResources
+	if releaseNamespaceResources == nil {
+		return nil
+	}
+	if err := validateResourceListBounds("spec.releaseNamespaceResources.resourceQuota", releaseNamespaceResources.ResourceQuota, h.releaseNamespaceResources.MaxResourceQuota); err != nil {
+		return err
+	}
+	if releaseNamespaceResources.LimitRange == nil {
+		return nil
+	}
+	if err := validateResourceListBounds("spec.releaseNamespaceResources.limitRange.default", releaseNamespaceResources.LimitRange.Default, h.releaseNamespaceResources.MaxContainerLimits); err != nil {
+		return err
+	}
+	if err := validateResourceListBounds("spec.releaseNamespaceResources.limitRange.defaultRequest", releaseNamespaceResources.LimitRange.DefaultRequest, h.releaseNamespaceResources.MaxContainerLimits); err != nil {
+		return err
+	}
+	return validateResourceListBounds("spec.releaseNamespaceResources.limitRange.max", releaseNamespaceResources.LimitRange.Max, h.releaseNamespaceResources.MaxContainerLimits)
+}
+
+// validateResourceListBounds ensures that every resource in the list is allowed and does not exceed its maximum value
+func validateResourceListBounds(field string, resources, maxResources corev1.ResourceList) error {
+	for resourceName, quantity := range resources {
+		maxQuantity, ok := maxResources[resourceName]
+		if !ok {
+			return fmt.Errorf("%s.%s is not allowed by the operator", field, resourceName)
+		}
+		if quantity.Cmp(maxQuantity) > 0 {
+			return fmt.Errorf("%s.%s (%s) exceeds the maximum allowed by the operator (%s)", field, resourceName, quantity.String(), maxQuantity.String())
+		}
+	}
+	return nil
+}
+
+// mergeResourceLists returns a copy of the base list with the values of the overrides applied on top of it
+func mergeResourceLists(base, overrides corev1.ResourceList) corev1.ResourceList {
+	if len(base) == 0 && len(overrides) == 0 {
+		return base
+	}
+	merged := corev1.ResourceList{}
+	for resourceName, quantity := range base {
+		merged[resourceName] = quantity.DeepCopy()
+	}
+	for resourceName, quantity := range overrides {
+		merged[resourceName] = quantity.DeepCopy()
+	}
+	return merged
+}
+
+// getReleaseServiceAccountName returns the name of the ServiceAccount in the system namespace that deploys the Helm release
+// if the operator is configured to scope release permissions
+func getReleaseServiceAccountName(releaseName string) string {
+	return fmt.Sprintf("%s-deployer", releaseName)
+}
+
+// getRBACReportName returns the name of the ConfigMap in the Project Registration Namespace that reports the RBAC computed for this ProjectHelmChart
+// if the operator is configured to record RBAC reports
+func getRBACReportName(projectHelmChart *v1alpha1.ProjectHelmChart) string {
+	return naming.SafeName(fmt.Sprintf("%s-rbac-report", projectHelmChart.Name), validation.DNS1123LabelMaxLength)
+}
+
+// removeRelease ensures that the Helm release deployed on behalf of this ProjectHelmChart is removed by the DeploymentBackend
+func (h *handler) removeRelease(projectHelmChart *v1alpha1.ProjectHelmChart) error {
+	releaseNamespace, releaseName := h.getReleaseNamespaceAndName(projectHelmChart)
+	exists, err := h.verifyReleaseNamespaceExists(releaseNamespace)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// the release cannot exist if the release namespace does not exist
+		return nil
+	}
+	err = h.backend.Remove(backend.Release{
+		Namespace: releaseNamespace,
+		Name:      releaseName,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove release %s/%s for ProjectHelmChart %s/%s: %s", releaseNamespace, releaseName, projectHelmChart.Namespace, projectHelmChart.Name, err)
 	}
-	// Underlying Helm releases will be created in dedicated project release namespaces
-	return projectReleaseName, projectReleaseName
+	return nil
 }
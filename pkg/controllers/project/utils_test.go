@@ -0,0 +1,219 @@
+package project
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/rancher/helm-project-operator/pkg/apis/helm.cattle.io/v1alpha1"
+	"github.com/rancher/helm-project-operator/pkg/controllers/common"
+	"github.com/rancher/wrangler/pkg/apply"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsLegacyProjectReleaseNamespace(t *testing.T) {
+	// note: without a ProjectLabel, the projectID of a ProjectHelmChart is its name
+	projectHelmChart := &v1alpha1.ProjectHelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-monitoring",
+			Namespace: "cattle-project-p-example",
+		},
+	}
+	newNamespace := func(projectID, ownerNamespace, ownerName string) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "legacy-release",
+				Labels: common.GetCommonLabels(projectID),
+				Annotations: map[string]string{
+					apply.LabelNamespace: ownerNamespace,
+					apply.LabelName:      ownerName,
+				},
+			},
+		}
+	}
+	unmanaged := newNamespace("project-monitoring", "cattle-project-p-example", "project-monitoring")
+	delete(unmanaged.Labels, common.HelmProjectOperatedLabel)
+
+	testCases := []struct {
+		name      string
+		namespace *corev1.Namespace
+		shared    bool
+		expected  bool
+	}{
+		{
+			name:      "namespace owned by this ProjectHelmChart",
+			namespace: newNamespace("project-monitoring", "cattle-project-p-example", "project-monitoring"),
+			expected:  true,
+		},
+		{
+			name:      "namespace not created by the operator",
+			namespace: unmanaged,
+		},
+		{
+			name:      "namespace of another project",
+			namespace: newNamespace("p-other", "cattle-project-p-example", "project-monitoring"),
+		},
+		{
+			name:      "namespace owned by another ProjectHelmChart",
+			namespace: newNamespace("project-monitoring", "cattle-project-p-other", "project-monitoring"),
+		},
+		{
+			name:      "shared namespace of this project",
+			namespace: newNamespace("project-monitoring", "", ""),
+			shared:    true,
+			expected:  true,
+		},
+		{
+			name:      "shared namespace of another project",
+			namespace: newNamespace("p-other", "", ""),
+			shared:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						SharedProjectReleaseNamespace: tc.shared,
+					},
+				},
+			}
+			if isLegacy := h.isLegacyProjectReleaseNamespace(tc.namespace, projectHelmChart); isLegacy != tc.expected {
+				t.Errorf("expected %t, found %t", tc.expected, isLegacy)
+			}
+		})
+	}
+}
+
+func TestValidateReleaseNamespace(t *testing.T) {
+	systemNamespace := "cattle-helm-system"
+	registrationNamespace := "cattle-project-p-example"
+	// note: target project namespaces never include Project Registration Namespaces or system namespaces
+	projectGetter := &fakeProjectGetter{
+		registrationNamespaces: []string{registrationNamespace},
+		systemNamespaces:       []string{systemNamespace, "kube-system"},
+		targetNamespaces:       []string{"p-example-app", "p-example-data"},
+	}
+	h := &handler{
+		systemNamespace: systemNamespace,
+		projectGetter:   projectGetter,
+	}
+	testCases := []struct {
+		name             string
+		releaseNamespace string
+		expectErr        bool
+	}{
+		{
+			name: "no release namespace provided",
+		},
+		{
+			name:             "namespace inside the project",
+			releaseNamespace: "p-example-data",
+		},
+		{
+			name:             "namespace outside the project",
+			releaseNamespace: "p-other-app",
+			expectErr:        true,
+		},
+		{
+			name:             "project registration namespace",
+			releaseNamespace: registrationNamespace,
+			expectErr:        true,
+		},
+		{
+			name:             "operator system namespace",
+			releaseNamespace: systemNamespace,
+			expectErr:        true,
+		},
+		{
+			name:             "other system namespace",
+			releaseNamespace: "kube-system",
+			expectErr:        true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectHelmChart := &v1alpha1.ProjectHelmChart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project-monitoring",
+					Namespace: registrationNamespace,
+				},
+				Spec: v1alpha1.ProjectHelmChartSpec{
+					ReleaseNamespace: tc.releaseNamespace,
+				},
+			}
+			targetProjectNamespaces, err := h.projectGetter.GetTargetProjectNamespaces(projectHelmChart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.validateReleaseNamespace(projectHelmChart, targetProjectNamespaces)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error to be returned: %t, found %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestIsOperatorManagedReleaseNamespace(t *testing.T) {
+	systemNamespace := "cattle-helm-system"
+	testCases := []struct {
+		name                  string
+		projectLabel          string
+		registrationNamespace string
+		releaseNamespace      string
+		expected              bool
+	}{
+		{
+			name:                  "project release namespace",
+			projectLabel:          "field.cattle.io/projectId",
+			registrationNamespace: "cattle-project-p-example",
+			expected:              true,
+		},
+		{
+			name:                  "namespace inside the project provided by the user",
+			projectLabel:          "field.cattle.io/projectId",
+			registrationNamespace: "cattle-project-p-example",
+			releaseNamespace:      "p-example-data",
+		},
+		{
+			name:                  "namespace outside the project provided by the user",
+			projectLabel:          "field.cattle.io/projectId",
+			registrationNamespace: "cattle-project-p-example",
+			releaseNamespace:      "p-other-app",
+		},
+		{
+			name:                  "project registration namespace",
+			registrationNamespace: "cattle-project-p-example",
+		},
+		{
+			name:                  "system namespace",
+			registrationNamespace: systemNamespace,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &handler{
+				systemNamespace: systemNamespace,
+				opts: common.Options{
+					RuntimeOptions: common.RuntimeOptions{
+						DeploymentBackend:        "helm-sdk",
+						ProjectLabel:             tc.projectLabel,
+						ProjectReleaseLabelValue: "p-system",
+					},
+				},
+				namespaceCache: &fakeNamespaceCache{},
+			}
+			projectHelmChart := &v1alpha1.ProjectHelmChart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project-monitoring",
+					Namespace: tc.registrationNamespace,
+				},
+				Spec: v1alpha1.ProjectHelmChartSpec{
+					ReleaseNamespace: tc.releaseNamespace,
+				},
+			}
+			if managed := h.isOperatorManagedReleaseNamespace(projectHelmChart); managed != tc.expected {
+				t.Errorf("expected release namespace to be managed by the operator: %t, found %t", tc.expected, managed)
+			}
+		})
+	}
+}
@@ -30,7 +30,7 @@ func (h *handler) getValues(projectHelmChart *v1alpha1.ProjectHelmChart, project
 				"projectNamespaces":        targetProjectNamespaces,
 				"projectID":                projectID,
 				"releaseProjectID":         h.opts.ProjectReleaseLabelValue,
-				"projectNamespaceSelector": h.getProjectNamespaceSelector(projectHelmChart, projectID),
+				"projectNamespaceSelector": h.getProjectNamespaceSelector(projectHelmChart, projectID, targetProjectNamespaces),
 			},
 		},
 	}
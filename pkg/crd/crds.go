@@ -148,6 +148,12 @@ func List() ([]crd.CRD, []crd.CRD) {
 				WithColumn("Release Name", ".status.releaseName").
 				WithColumn("Target Namespaces", ".status.targetNamespaces")
 		}),
+		newCRD(&v1alpha1.Project{}, func(c crd.CRD) crd.CRD {
+			c.NonNamespace = true
+			return c.
+				WithColumn("Registration Namespace", ".status.registrationNamespace").
+				WithColumn("Namespaces", ".status.namespaces")
+		}),
 	}
 	crdDeps := append(helmcontrollercrd.List(), helmlockercrd.List()...)
 	return crds, crdDeps
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 Rancher Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by main. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/lasso/pkg/client"
+	"github.com/rancher/lasso/pkg/controller"
+	"github.com/rancher/wrangler/pkg/generic"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+type LimitRangeHandler func(string, *v1.LimitRange) (*v1.LimitRange, error)
+
+type LimitRangeController interface {
+	generic.ControllerMeta
+	LimitRangeClient
+
+	OnChange(ctx context.Context, name string, sync LimitRangeHandler)
+	OnRemove(ctx context.Context, name string, sync LimitRangeHandler)
+	Enqueue(namespace, name string)
+	EnqueueAfter(namespace, name string, duration time.Duration)
+
+	Cache() LimitRangeCache
+}
+
+type LimitRangeClient interface {
+	Create(*v1.LimitRange) (*v1.LimitRange, error)
+	Update(*v1.LimitRange) (*v1.LimitRange, error)
+
+	Delete(namespace, name string, options *metav1.DeleteOptions) error
+	Get(namespace, name string, options metav1.GetOptions) (*v1.LimitRange, error)
+	List(namespace string, opts metav1.ListOptions) (*v1.LimitRangeList, error)
+	Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.LimitRange, err error)
+}
+
+type LimitRangeCache interface {
+	Get(namespace, name string) (*v1.LimitRange, error)
+	List(namespace string, selector labels.Selector) ([]*v1.LimitRange, error)
+
+	AddIndexer(indexName string, indexer LimitRangeIndexer)
+	GetByIndex(indexName, key string) ([]*v1.LimitRange, error)
+}
+
+type LimitRangeIndexer func(obj *v1.LimitRange) ([]string, error)
+
+type limitRangeController struct {
+	controller    controller.SharedController
+	client        *client.Client
+	gvk           schema.GroupVersionKind
+	groupResource schema.GroupResource
+}
+
+func NewLimitRangeController(gvk schema.GroupVersionKind, resource string, namespaced bool, controller controller.SharedControllerFactory) LimitRangeController {
+	c := controller.ForResourceKind(gvk.GroupVersion().WithResource(resource), gvk.Kind, namespaced)
+	return &limitRangeController{
+		controller: c,
+		client:     c.Client(),
+		gvk:        gvk,
+		groupResource: schema.GroupResource{
+			Group:    gvk.Group,
+			Resource: resource,
+		},
+	}
+}
+
+func FromLimitRangeHandlerToHandler(sync LimitRangeHandler) generic.Handler {
+	return func(key string, obj runtime.Object) (ret runtime.Object, err error) {
+		var v *v1.LimitRange
+		if obj == nil {
+			v, err = sync(key, nil)
+		} else {
+			v, err = sync(key, obj.(*v1.LimitRange))
+		}
+		if v == nil {
+			return nil, err
+		}
+		return v, err
+	}
+}
+
+func (c *limitRangeController) Updater() generic.Updater {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		newObj, err := c.Update(obj.(*v1.LimitRange))
+		if newObj == nil {
+			return nil, err
+		}
+		return newObj, err
+	}
+}
+
+func UpdateLimitRangeDeepCopyOnChange(client LimitRangeClient, obj *v1.LimitRange, handler func(obj *v1.LimitRange) (*v1.LimitRange, error)) (*v1.LimitRange, error) {
+	if obj == nil {
+		return obj, nil
+	}
+
+	copyObj := obj.DeepCopy()
+	newObj, err := handler(copyObj)
+	if newObj != nil {
+		copyObj = newObj
+	}
+	if obj.ResourceVersion == copyObj.ResourceVersion && !equality.Semantic.DeepEqual(obj, copyObj) {
+		return client.Update(copyObj)
+	}
+
+	return copyObj, err
+}
+
+func (c *limitRangeController) AddGenericHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.controller.RegisterHandler(ctx, name, controller.SharedControllerHandlerFunc(handler))
+}
+
+func (c *limitRangeController) AddGenericRemoveHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), handler))
+}
+
+func (c *limitRangeController) OnChange(ctx context.Context, name string, sync LimitRangeHandler) {
+	c.AddGenericHandler(ctx, name, FromLimitRangeHandlerToHandler(sync))
+}
+
+func (c *limitRangeController) OnRemove(ctx context.Context, name string, sync LimitRangeHandler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), FromLimitRangeHandlerToHandler(sync)))
+}
+
+func (c *limitRangeController) Enqueue(namespace, name string) {
+	c.controller.Enqueue(namespace, name)
+}
+
+func (c *limitRangeController) EnqueueAfter(namespace, name string, duration time.Duration) {
+	c.controller.EnqueueAfter(namespace, name, duration)
+}
+
+func (c *limitRangeController) Informer() cache.SharedIndexInformer {
+	return c.controller.Informer()
+}
+
+func (c *limitRangeController) GroupVersionKind() schema.GroupVersionKind {
+	return c.gvk
+}
+
+func (c *limitRangeController) Cache() LimitRangeCache {
+	return &limitRangeCache{
+		indexer:  c.Informer().GetIndexer(),
+		resource: c.groupResource,
+	}
+}
+
+func (c *limitRangeController) Create(obj *v1.LimitRange) (*v1.LimitRange, error) {
+	result := &v1.LimitRange{}
+	return result, c.client.Create(context.TODO(), obj.Namespace, obj, result, metav1.CreateOptions{})
+}
+
+func (c *limitRangeController) Update(obj *v1.LimitRange) (*v1.LimitRange, error) {
+	result := &v1.LimitRange{}
+	return result, c.client.Update(context.TODO(), obj.Namespace, obj, result, metav1.UpdateOptions{})
+}
+
+func (c *limitRangeController) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	if options == nil {
+		options = &metav1.DeleteOptions{}
+	}
+	return c.client.Delete(context.TODO(), namespace, name, *options)
+}
+
+func (c *limitRangeController) Get(namespace, name string, options metav1.GetOptions) (*v1.LimitRange, error) {
+	result := &v1.LimitRange{}
+	return result, c.client.Get(context.TODO(), namespace, name, result, options)
+}
+
+func (c *limitRangeController) List(namespace string, opts metav1.ListOptions) (*v1.LimitRangeList, error) {
+	result := &v1.LimitRangeList{}
+	return result, c.client.List(context.TODO(), namespace, result, opts)
+}
+
+func (c *limitRangeController) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.client.Watch(context.TODO(), namespace, opts)
+}
+
+func (c *limitRangeController) Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (*v1.LimitRange, error) {
+	result := &v1.LimitRange{}
+	return result, c.client.Patch(context.TODO(), namespace, name, pt, data, result, metav1.PatchOptions{}, subresources...)
+}
+
+type limitRangeCache struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+func (c *limitRangeCache) Get(namespace, name string) (*v1.LimitRange, error) {
+	obj, exists, err := c.indexer.GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(c.resource, name)
+	}
+	return obj.(*v1.LimitRange), nil
+}
+
+func (c *limitRangeCache) List(namespace string, selector labels.Selector) (ret []*v1.LimitRange, err error) {
+
+	err = cache.ListAllByNamespace(c.indexer, namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.LimitRange))
+	})
+
+	return ret, err
+}
+
+func (c *limitRangeCache) AddIndexer(indexName string, indexer LimitRangeIndexer) {
+	utilruntime.Must(c.indexer.AddIndexers(map[string]cache.IndexFunc{
+		indexName: func(obj interface{}) (strings []string, e error) {
+			return indexer(obj.(*v1.LimitRange))
+		},
+	}))
+}
+
+func (c *limitRangeCache) GetByIndex(indexName, key string) (result []*v1.LimitRange, err error) {
+	objs, err := c.indexer.ByIndex(indexName, key)
+	if err != nil {
+		return nil, err
+	}
+	result = make([]*v1.LimitRange, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*v1.LimitRange))
+	}
+	return result, nil
+}
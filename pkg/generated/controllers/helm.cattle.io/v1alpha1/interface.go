@@ -30,6 +30,7 @@ func init() {
 }
 
 type Interface interface {
+	Project() ProjectController
 	ProjectHelmChart() ProjectHelmChartController
 }
 
@@ -43,6 +44,9 @@ type version struct {
 	controllerFactory controller.SharedControllerFactory
 }
 
+func (c *version) Project() ProjectController {
+	return NewProjectController(schema.GroupVersionKind{Group: "helm.cattle.io", Version: "v1alpha1", Kind: "Project"}, "projects", false, c.controllerFactory)
+}
 func (c *version) ProjectHelmChart() ProjectHelmChartController {
 	return NewProjectHelmChartController(schema.GroupVersionKind{Group: "helm.cattle.io", Version: "v1alpha1", Kind: "ProjectHelmChart"}, "projecthelmcharts", true, c.controllerFactory)
 }
@@ -0,0 +1,58 @@
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// MaxNamespaceNameLength is the maximum length of the name of a namespace, which must be a valid DNS-1123 label
+	MaxNamespaceNameLength = validation.DNS1123LabelMaxLength
+
+	// MaxReleaseNameLength is the maximum length of the name of a Helm release
+	MaxReleaseNameLength = 53
+
+	// MaxHelmChartNameLength is the maximum length of the name of a HelmChart deployed by a Helm Controller, since the
+	// Helm Controller runs Jobs named helm-install-<name> or helm-delete-<name> whose names must be valid DNS-1123 labels
+	MaxHelmChartNameLength = validation.DNS1123LabelMaxLength - len("helm-install-")
+
+	// hashLength is the number of characters of the hash of the original name that is suffixed onto truncated names
+	hashLength = 8
+)
+
+// SafeName returns the provided name if it is a valid DNS-1123 label that does not exceed maxLength characters.
+// Otherwise, it returns a name that is sanitized into a valid DNS-1123 label, truncated, and suffixed with a hash
+// of the original name. The returned name is stable for a given name and maxLength.
+func SafeName(name string, maxLength int) string {
+	if len(name) <= maxLength && len(validation.IsDNS1123Label(name)) == 0 {
+		return name
+	}
+	prefix := sanitize(name)
+	if maxPrefixLength := maxLength - hashLength - 1; len(prefix) > maxPrefixLength {
+		prefix = strings.TrimRight(prefix[:maxPrefixLength], "-")
+	}
+	if len(prefix) == 0 {
+		return hash(name)
+	}
+	return prefix + "-" + hash(name)
+}
+
+// sanitize lowercases the provided name and replaces all characters that are not allowed in a DNS-1123 label with a '-'
+func sanitize(name string) string {
+	sanitized := []byte(strings.ToLower(name))
+	for i, c := range sanitized {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			sanitized[i] = '-'
+		}
+	}
+	return strings.Trim(string(sanitized), "-")
+}
+
+// hash returns a short hash of the provided name that is a valid DNS-1123 label
+func hash(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
@@ -0,0 +1,95 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestSafeName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		maxLength int
+		expected  string
+	}{
+		{
+			name:      "valid name within namespace limit",
+			input:     "cattle-project-p-example",
+			maxLength: MaxNamespaceNameLength,
+			expected:  "cattle-project-p-example",
+		},
+		{
+			name:      "valid name at namespace limit",
+			input:     strings.Repeat("a", 63),
+			maxLength: MaxNamespaceNameLength,
+			expected:  strings.Repeat("a", 63),
+		},
+		{
+			name:      "valid name exceeding namespace limit",
+			input:     strings.Repeat("a", 64),
+			maxLength: MaxNamespaceNameLength,
+			expected:  strings.Repeat("a", 54) + "-" + hash(strings.Repeat("a", 64)),
+		},
+		{
+			name:      "valid name at release limit",
+			input:     strings.Repeat("a", 53),
+			maxLength: MaxReleaseNameLength,
+			expected:  strings.Repeat("a", 53),
+		},
+		{
+			name:      "valid name exceeding release limit",
+			input:     strings.Repeat("a", 54),
+			maxLength: MaxReleaseNameLength,
+			expected:  strings.Repeat("a", 44) + "-" + hash(strings.Repeat("a", 54)),
+		},
+		{
+			name:      "truncation does not end in a dash",
+			input:     strings.Repeat("a", 43) + "-" + strings.Repeat("b", 20),
+			maxLength: MaxReleaseNameLength,
+			expected:  strings.Repeat("a", 43) + "-" + hash(strings.Repeat("a", 43)+"-"+strings.Repeat("b", 20)),
+		},
+		{
+			name:      "uppercase characters",
+			input:     "Project-Monitoring",
+			maxLength: MaxNamespaceNameLength,
+			expected:  "project-monitoring-" + hash("Project-Monitoring"),
+		},
+		{
+			name:      "dots and underscores",
+			input:     "project.monitoring_v2",
+			maxLength: MaxNamespaceNameLength,
+			expected:  "project-monitoring-v2-" + hash("project.monitoring_v2"),
+		},
+		{
+			name:      "leading and trailing invalid characters",
+			input:     "-project-monitoring.",
+			maxLength: MaxNamespaceNameLength,
+			expected:  "project-monitoring-" + hash("-project-monitoring."),
+		},
+		{
+			name:      "only invalid characters",
+			input:     "___",
+			maxLength: MaxNamespaceNameLength,
+			expected:  hash("___"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			safeName := SafeName(tc.input, tc.maxLength)
+			if safeName != tc.expected {
+				t.Errorf("expected %s, found %s", tc.expected, safeName)
+			}
+			if len(safeName) > tc.maxLength {
+				t.Errorf("expected %s to not exceed %d characters", safeName, tc.maxLength)
+			}
+			if errs := validation.IsDNS1123Label(safeName); len(errs) > 0 {
+				t.Errorf("expected %s to be a valid DNS-1123 label: %s", safeName, strings.Join(errs, ", "))
+			}
+			if SafeName(tc.input, tc.maxLength) != safeName {
+				t.Errorf("expected safe name of %s to be stable", tc.input)
+			}
+		})
+	}
+}
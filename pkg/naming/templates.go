@@ -0,0 +1,86 @@
+package naming
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ProjectRegistrationNamespaceData is the data provided to templates that render the name of a Project Registration Namespace
+type ProjectRegistrationNamespaceData struct {
+	// ProjectID is the value of the project label on the namespaces in the project
+	ProjectID string
+}
+
+// ProjectReleaseNamespaceData is the data provided to templates that render the name of a Project Release Namespace
+type ProjectReleaseNamespaceData struct {
+	// ProjectRegistrationNamespace is the name of the Project Registration Namespace that contains the ProjectHelmChart
+	ProjectRegistrationNamespace string
+
+	// ProjectHelmChart is the name of the ProjectHelmChart
+	//
+	// Note: this is empty if the Project Release Namespace is shared by all ProjectHelmCharts in a project
+	ProjectHelmChart string
+
+	// ReleaseName is the name of the Helm release deployed on behalf of the ProjectHelmChart
+	//
+	// Note: this is empty if the Project Release Namespace is shared by all ProjectHelmCharts in a project
+	ReleaseName string
+}
+
+var (
+	templates     = map[string]*template.Template{}
+	templatesLock sync.Mutex
+)
+
+// Render renders the provided naming template with the provided data
+// Parsed templates are cached, so rendering the same template multiple times only parses it once
+func Render(text string, data interface{}) (string, error) {
+	tmpl, err := parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("unable to render naming template %q: %s", text, err)
+	}
+	return rendered.String(), nil
+}
+
+// ValidateTemplate validates that the provided naming template renders a valid DNS-1123 label for each of the provided
+// samples and that no two samples render the same name
+func ValidateTemplate(text string, samples ...interface{}) error {
+	rendered := map[string]bool{}
+	for _, sample := range samples {
+		name, err := Render(text, sample)
+		if err != nil {
+			return err
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return fmt.Errorf("naming template %q renders invalid name %q for %+v: %s", text, name, sample, strings.Join(errs, ", "))
+		}
+		if rendered[name] {
+			return fmt.Errorf("naming template %q renders the same name %q for different inputs", text, name)
+		}
+		rendered[name] = true
+	}
+	return nil
+}
+
+// parse returns the parsed naming template, using the cache if possible
+func parse(text string) (*template.Template, error) {
+	templatesLock.Lock()
+	defer templatesLock.Unlock()
+	if tmpl, ok := templates[text]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse naming template %q: %s", text, err)
+	}
+	templates[text] = tmpl
+	return tmpl, nil
+}